@@ -0,0 +1,122 @@
+// Package auth provides token based authentication for client sessions.
+//
+// An Authenticator verifies the token presented by a client and returns the
+// Identity it represents. When a node is configured with an Authenticator,
+// only the whitelisted routes can be called before the session is bound to
+// a UID, and the UID of a successfully verified token is bound to the session
+// automatically. The identity authenticated by the gate is carried to the
+// backends with the forwarded messages.
+package auth
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/nano-kit/go-nano/session"
+)
+
+// Errors that could be occurred during authentication
+var (
+	ErrMissingToken     = errors.New("auth: missing token")
+	ErrMalformedToken   = errors.New("auth: malformed token")
+	ErrUnsupportedAlg   = errors.New("auth: unsupported signing algorithm")
+	ErrUnknownKey       = errors.New("auth: unknown signing key")
+	ErrInvalidSignature = errors.New("auth: invalid signature")
+	ErrTokenExpired     = errors.New("auth: token is expired")
+	ErrTokenNotValidYet = errors.New("auth: token is not valid yet")
+	ErrInvalidIssuer    = errors.New("auth: invalid issuer")
+	ErrInvalidAudience  = errors.New("auth: invalid audience")
+	ErrMissingUID       = errors.New("auth: missing uid claim")
+)
+
+// DefaultResponse is the response of the requests rejected before
+// authentication if no response is specified, it is passed to the client
+// without serialization
+var DefaultResponse = []byte(`{"code":401,"error":"unauthenticated"}`)
+
+// identityKey is the session data key which stores the authenticated identity
+const identityKey = "nano.auth.identity"
+
+// Identity represents an authenticated principal
+type Identity struct {
+	UID       string                 // user id which will be bound to the session
+	Claims    map[string]interface{} // all claims carried by the token
+	ExpiresAt time.Time              // zero if the token never expires
+}
+
+// Authenticator verifies a token and returns the identity it represents
+type Authenticator interface {
+	Authenticate(token string) (*Identity, error)
+}
+
+// AuthenticatorFunc is an adapter to allow the use of ordinary functions as
+// Authenticator
+type AuthenticatorFunc func(token string) (*Identity, error)
+
+// Authenticate implements the Authenticator interface
+func (f AuthenticatorFunc) Authenticate(token string) (*Identity, error) {
+	return f(token)
+}
+
+// Login verifies the token with authenticator a, binds the UID of the identity
+// to session s and stores the identity in the session data.
+func Login(s *session.Session, a Authenticator, token string) (*Identity, error) {
+	if token == "" {
+		return nil, ErrMissingToken
+	}
+	id, err := a.Authenticate(token)
+	if err != nil {
+		return nil, err
+	}
+	if id.UID == "" {
+		return nil, ErrMissingUID
+	}
+	if err := s.Bind(id.UID); err != nil {
+		return nil, err
+	}
+	s.Set(identityKey, id)
+	return id, nil
+}
+
+// SetIdentity stores the identity authenticated by another node in session s
+// without binding the UID, e.g. the identity authenticated by the gate is
+// restored to the backend sessions. A nil identity removes the stored one.
+func SetIdentity(s *session.Session, id *Identity) {
+	if id == nil {
+		s.Remove(identityKey)
+		return
+	}
+	s.Set(identityKey, id)
+}
+
+// IdentityOf returns the identity stored by Login or SetIdentity, nil if the
+// session has not been authenticated. The identity authenticated by the gate
+// is also available in the backend sessions.
+func IdentityOf(s *session.Session) *Identity {
+	id, _ := s.Value(identityKey).(*Identity)
+	return id
+}
+
+// IsAuthenticated reports whether a UID has been bound to the session
+func IsAuthenticated(s *session.Session) bool {
+	return s.UID() != ""
+}
+
+// Whitelist is a list of route patterns that can be called before
+// authentication. A pattern is either a full route such as "Auth.Login",
+// or a service wildcard such as "Auth.*".
+type Whitelist []string
+
+// Allow reports whether the route matches any pattern of the whitelist
+func (w Whitelist) Allow(route string) bool {
+	for _, pattern := range w {
+		if pattern == route {
+			return true
+		}
+		if strings.HasSuffix(pattern, ".*") && strings.HasPrefix(route, pattern[:len(pattern)-1]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"hash"
+	"strings"
+	"time"
+)
+
+type (
+	// JWT is an Authenticator that verifies HMAC signed JSON Web Tokens
+	// (HS256, HS384 and HS512) against locally configured keys.
+	JWT struct {
+		keys       map[string][]byte // key id map to secret
+		defaultKey []byte            // used when the token header has no `kid`
+		uidClaim   string            // claim name of the user id
+		issuer     string            // expected `iss`, empty to skip check
+		audience   string            // expected `aud`, empty to skip check
+		leeway     time.Duration     // clock skew tolerance
+		now        func() time.Time
+	}
+
+	// JWTOption used to customize the JWT authenticator
+	JWTOption func(*JWT)
+
+	jwtHeader struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+)
+
+var algorithms = map[string]func() hash.Hash{
+	"HS256": sha256.New,
+	"HS384": sha512.New384,
+	"HS512": sha512.New,
+}
+
+// NewJWT returns a JWT authenticator, key is used to verify tokens
+// whose header does not specify a `kid`, it can be nil if all keys
+// are registered by WithKey.
+func NewJWT(key []byte, opts ...JWTOption) *JWT {
+	j := &JWT{
+		keys:       map[string][]byte{},
+		defaultKey: key,
+		uidClaim:   "sub",
+		now:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(j)
+	}
+	return j
+}
+
+// WithKey registers a secret which is selected by the `kid` header
+func WithKey(kid string, key []byte) JWTOption {
+	return func(j *JWT) {
+		j.keys[kid] = key
+	}
+}
+
+// WithUIDClaim sets the claim which carries the user id, "sub" by default
+func WithUIDClaim(claim string) JWTOption {
+	return func(j *JWT) {
+		j.uidClaim = claim
+	}
+}
+
+// WithIssuer sets the expected issuer
+func WithIssuer(iss string) JWTOption {
+	return func(j *JWT) {
+		j.issuer = iss
+	}
+}
+
+// WithAudience sets the expected audience
+func WithAudience(aud string) JWTOption {
+	return func(j *JWT) {
+		j.audience = aud
+	}
+}
+
+// WithLeeway sets the tolerance of clock skew when validating time claims
+func WithLeeway(d time.Duration) JWTOption {
+	return func(j *JWT) {
+		j.leeway = d
+	}
+}
+
+// Authenticate implements the Authenticator interface
+func (j *JWT) Authenticate(token string) (*Identity, error) {
+	if token == "" {
+		return nil, ErrMissingToken
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	newHash, ok := algorithms[header.Alg]
+	if !ok {
+		return nil, ErrUnsupportedAlg
+	}
+	key := j.defaultKey
+	if header.Kid != "" {
+		key, ok = j.keys[header.Kid]
+		if !ok {
+			return nil, ErrUnknownKey
+		}
+	}
+	if len(key) == 0 {
+		return nil, ErrUnknownKey
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	mac := hmac.New(newHash, key)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, ErrInvalidSignature
+	}
+
+	claims := map[string]interface{}{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	return j.validate(claims)
+}
+
+func (j *JWT) validate(claims map[string]interface{}) (*Identity, error) {
+	now := j.now()
+	id := &Identity{Claims: claims}
+
+	if exp, ok := numericClaim(claims, "exp"); ok {
+		id.ExpiresAt = time.Unix(exp, 0)
+		if now.After(id.ExpiresAt.Add(j.leeway)) {
+			return nil, ErrTokenExpired
+		}
+	}
+	if nbf, ok := numericClaim(claims, "nbf"); ok {
+		if now.Add(j.leeway).Before(time.Unix(nbf, 0)) {
+			return nil, ErrTokenNotValidYet
+		}
+	}
+	if j.issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != j.issuer {
+			return nil, ErrInvalidIssuer
+		}
+	}
+	if j.audience != "" && !containsAudience(claims["aud"], j.audience) {
+		return nil, ErrInvalidAudience
+	}
+
+	switch uid := claims[j.uidClaim].(type) {
+	case string:
+		id.UID = uid
+	case json.Number:
+		id.UID = uid.String()
+	}
+	if id.UID == "" {
+		return nil, ErrMissingUID
+	}
+	return id, nil
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return ErrMalformedToken
+	}
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return ErrMalformedToken
+	}
+	return nil
+}
+
+func numericClaim(claims map[string]interface{}, name string) (int64, bool) {
+	n, ok := claims[name].(json.Number)
+	if !ok {
+		return 0, false
+	}
+	if v, err := n.Int64(); err == nil {
+		return v, true
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return 0, false
+	}
+	return int64(f), true
+}
+
+func containsAudience(aud interface{}, expected string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == expected
+	case []interface{}:
+		for _, a := range v {
+			if s, ok := a.(string); ok && s == expected {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/nano-kit/go-nano/session"
+)
+
+func sign(t *testing.T, header, claims map[string]interface{}, key []byte) string {
+	h, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestJWT_Authenticate(t *testing.T) {
+	key := []byte("secret")
+	exp := time.Now().Add(time.Hour).Unix()
+	token := sign(t, map[string]interface{}{"alg": "HS256", "typ": "JWT"},
+		map[string]interface{}{"sub": "10086", "exp": exp, "role": "admin"}, key)
+
+	id, err := NewJWT(key).Authenticate(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.UID != "10086" || id.ExpiresAt.Unix() != exp || id.Claims["role"] != "admin" {
+		t.Fatalf("unexpected identity: %+v", id)
+	}
+
+	if _, err := NewJWT([]byte("other")).Authenticate(token); err != ErrInvalidSignature {
+		t.Fatalf("expect %v, got %v", ErrInvalidSignature, err)
+	}
+}
+
+func TestJWT_KeyID(t *testing.T) {
+	key := []byte("key-2")
+	token := sign(t, map[string]interface{}{"alg": "HS256", "kid": "2"},
+		map[string]interface{}{"uid": "42"}, key)
+
+	j := NewJWT(nil, WithKey("1", []byte("key-1")), WithKey("2", key), WithUIDClaim("uid"))
+	id, err := j.Authenticate(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.UID != "42" {
+		t.Fatalf("expect uid 42, got %s", id.UID)
+	}
+
+	token = sign(t, map[string]interface{}{"alg": "HS256", "kid": "3"},
+		map[string]interface{}{"uid": "42"}, key)
+	if _, err := j.Authenticate(token); err != ErrUnknownKey {
+		t.Fatalf("expect %v, got %v", ErrUnknownKey, err)
+	}
+}
+
+func TestJWT_Claims(t *testing.T) {
+	key := []byte("secret")
+	header := map[string]interface{}{"alg": "HS256"}
+	now := time.Now()
+
+	cases := []struct {
+		claims map[string]interface{}
+		err    error
+	}{
+		{map[string]interface{}{"sub": "1", "exp": now.Add(-time.Minute).Unix()}, ErrTokenExpired},
+		{map[string]interface{}{"sub": "1", "nbf": now.Add(time.Minute).Unix()}, ErrTokenNotValidYet},
+		{map[string]interface{}{"sub": "1", "iss": "evil"}, ErrInvalidIssuer},
+		{map[string]interface{}{"sub": "1", "iss": "nano", "aud": []string{"web"}}, ErrInvalidAudience},
+		{map[string]interface{}{"iss": "nano", "aud": "game"}, ErrMissingUID},
+		{map[string]interface{}{"sub": "1", "iss": "nano", "aud": []string{"web", "game"}}, nil},
+	}
+
+	j := NewJWT(key, WithIssuer("nano"), WithAudience("game"))
+	for i, c := range cases {
+		if c.claims["iss"] == nil {
+			c.claims["iss"] = "nano"
+		}
+		if c.claims["aud"] == nil {
+			c.claims["aud"] = "game"
+		}
+		_, err := j.Authenticate(sign(t, header, c.claims, key))
+		if err != c.err {
+			t.Fatalf("case %d: expect %v, got %v", i, c.err, err)
+		}
+	}
+
+	if _, err := j.Authenticate("a.b"); err != ErrMalformedToken {
+		t.Fatalf("expect %v, got %v", ErrMalformedToken, err)
+	}
+	token := sign(t, map[string]interface{}{"alg": "none"}, map[string]interface{}{"sub": "1"}, key)
+	if _, err := j.Authenticate(token); err != ErrUnsupportedAlg {
+		t.Fatalf("expect %v, got %v", ErrUnsupportedAlg, err)
+	}
+}
+
+func TestLogin(t *testing.T) {
+	key := []byte("secret")
+	token := sign(t, map[string]interface{}{"alg": "HS256"}, map[string]interface{}{"sub": "7"}, key)
+
+	s := session.New(nil)
+	if IsAuthenticated(s) {
+		t.Fatal("session should not be authenticated")
+	}
+	if _, err := Login(s, NewJWT(key), token); err != nil {
+		t.Fatal(err)
+	}
+	if !IsAuthenticated(s) || s.UID() != "7" || IdentityOf(s).UID != "7" {
+		t.Fatalf("session is not bound, UID=%s", s.UID())
+	}
+}
+
+func TestWhitelist_Allow(t *testing.T) {
+	w := Whitelist{"Auth.*", "Gate.Ping"}
+	allowed := []string{"Auth.Login", "Auth.Register", "Gate.Ping"}
+	denied := []string{"Gate.Pong", "Authx.Login", "Room.Join"}
+	for _, r := range allowed {
+		if !w.Allow(r) {
+			t.Fatalf("%s should be allowed", r)
+		}
+	}
+	for _, r := range denied {
+		if w.Allow(r) {
+			t.Fatalf("%s should be denied", r)
+		}
+	}
+}
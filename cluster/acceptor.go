@@ -3,9 +3,12 @@ package cluster
 import (
 	"context"
 	"net"
+	"sync"
+	"sync/atomic"
 
 	"github.com/nano-kit/go-nano/cluster/clusterpb"
 	"github.com/nano-kit/go-nano/internal/message"
+	"github.com/nano-kit/go-nano/pipeline"
 	"github.com/nano-kit/go-nano/service"
 	"github.com/nano-kit/go-nano/session"
 )
@@ -17,37 +20,68 @@ type acceptor struct {
 	lastMid    uint64
 	rpcHandler rpcHandler
 	gateAddr   string
+	node       *Node
+	reason     int32 // session.CloseReason notified by the gate
+
+	trace traceContext // trace context of the handler being executed
+
+	syncMu     sync.Mutex
+	syncedUID  string            // the UID known by the gate
+	syncedData map[string][]byte // the synchronized session data known by the gate
+
+	claims    []byte // the claims of the identity restored from the gate, see receiveIdentity
+	expiresAt int64  // the expiry of the identity restored from the gate
+}
+
+// egress passes the message sent to the gate through the backend egress
+// stage of the pipeline, send is called by the last middleware
+func (a *acceptor) egress(msg *message.Message, v interface{}, send func() error) error {
+	egress := pipeline.StageOf(a.node.Pipeline, pipeline.BackendEgress)
+	if egress == nil {
+		return send()
+	}
+	if err := egress.Process(a.session, msg); err != nil {
+		return err
+	}
+	ctx := pipeline.NewContext(a.session, msg)
+	ctx.Data = v
+	return pipeline.Execute(egress, ctx, send)
 }
 
 // Push implements the session.NetworkEntity interface
 func (a *acceptor) Push(route string, v interface{}) error {
 	// TODO: buffer
-	data, err := message.Serialize(v)
+	data, err := message.SerializeWith(a.node.serializer(), v)
 	if err != nil {
 		return err
 	}
-	request := &clusterpb.PushMessage{
-		SessionId: int64(a.sid),
-		Route:     route,
-		Data:      data,
-	}
-	_, err = a.gateClient.HandlePush(context.Background(), request)
-	return err
+	msg := &message.Message{Type: message.Push, Route: route, Data: data}
+	return a.egress(msg, v, func() error {
+		a.flushSession()
+		request := &clusterpb.PushMessage{
+			SessionId: int64(a.sid),
+			Route:     msg.Route,
+			Data:      msg.Data,
+		}
+		_, err := a.gateClient.HandlePush(withTraceparent(context.Background(), a.trace.get()), request)
+		return err
+	})
 }
 
 // Notify implements the session.NetworkEntity interface
 func (a *acceptor) Notify(route string, v interface{}) error {
 	// TODO: buffer
-	data, err := message.Serialize(v)
+	data, err := message.SerializeWith(a.node.serializer(), v)
 	if err != nil {
 		return err
 	}
 	msg := &message.Message{
-		Type:  message.Notify,
-		Route: route,
-		Data:  data,
+		Type:        message.Notify,
+		Route:       route,
+		Data:        data,
+		Traceparent: a.trace.get(),
 	}
-	a.rpcHandler(a.session, msg, true)
+	a.rpcHandler(a.session, msg)
 	return nil
 }
 
@@ -64,17 +98,21 @@ func (a *acceptor) Response(v interface{}) error {
 // ResponseMid implements the session.NetworkEntity interface
 func (a *acceptor) ResponseMid(mid uint64, v interface{}) error {
 	// TODO: buffer
-	data, err := message.Serialize(v)
+	data, err := message.SerializeWith(a.node.serializer(), v)
 	if err != nil {
 		return err
 	}
-	request := &clusterpb.ResponseMessage{
-		SessionId: int64(a.sid),
-		Id:        mid,
-		Data:      data,
-	}
-	_, err = a.gateClient.HandleResponse(context.Background(), request)
-	return err
+	msg := &message.Message{Type: message.Response, ID: mid, Data: data}
+	return a.egress(msg, v, func() error {
+		a.flushSession()
+		request := &clusterpb.ResponseMessage{
+			SessionId: int64(a.sid),
+			Id:        msg.ID,
+			Data:      msg.Data,
+		}
+		_, err := a.gateClient.HandleResponse(withTraceparent(context.Background(), a.trace.get()), request)
+		return err
+	})
 }
 
 // Close implements the session.NetworkEntity interface
@@ -87,6 +125,74 @@ func (a *acceptor) Close() error {
 	return err
 }
 
+// BindUID records the UID binding of the gate session to the session registry.
+// The gate records the binding itself when the UID is synchronized, so the
+// registry is consulted here only to enforce the bind policy on a UID which
+// is not known by the gate yet.
+func (a *acceptor) BindUID(uid string) error {
+	if a.node.BindPolicy == nil {
+		return nil
+	}
+	a.syncMu.Lock()
+	known := a.syncedUID == uid
+	a.syncMu.Unlock()
+	if known {
+		return nil
+	}
+	return a.node.bindSession(a.session, a.gateAddr, a.sid, uid)
+}
+
+// CloseReason returns the reason of closing the gate session
+func (a *acceptor) CloseReason() session.CloseReason {
+	return session.CloseReason(atomic.LoadInt32(&a.reason))
+}
+
+// Lifecycle implements the lifecycle provider of session
+func (a *acceptor) Lifecycle() *session.Lifecycle {
+	return a.node.Lifecycle
+}
+
+// mirrorUID records the UID bound by the gate, binding the session to it
+// does not consult the registry again
+func (a *acceptor) mirrorUID(uid string) {
+	a.syncMu.Lock()
+	a.syncedUID = uid
+	a.syncMu.Unlock()
+}
+
+// syncSession sends the UID and synchronized session data to the gate if they
+// are changed since last sync, it returns whether anything is sent
+func (a *acceptor) syncSession() (bool, error) {
+	a.syncMu.Lock()
+	defer a.syncMu.Unlock()
+
+	uid := a.session.UID()
+	data := encodeSessionData(a.session, a.node.SyncKeys)
+	if uid == a.syncedUID && sameSessionData(a.syncedData, data) {
+		return false, nil
+	}
+	request := &clusterpb.SyncSessionRequest{
+		SessionId: int64(a.sid),
+		Uid:       uid,
+		Metadata:  data,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sessionRPCTimeout)
+	defer cancel()
+	if _, err := a.gateClient.SyncSession(ctx, request); err != nil {
+		return true, err
+	}
+	a.syncedUID, a.syncedData = uid, data
+	return true, nil
+}
+
+// flushSession synchronizes the changes of session to the gate before the
+// messages depending on them are sent
+func (a *acceptor) flushSession() {
+	if _, err := a.syncSession(); err != nil {
+		logger.Error("sync session to gate failed", "sid", a.session.ID(), "member", a.gateAddr, "err", err)
+	}
+}
+
 // RemoteAddr implements the session.NetworkEntity interface
 func (a *acceptor) RemoteAddr() net.Addr {
 	return acceptorRemoteAddr{network: "grpc", address: a.gateAddr}
@@ -0,0 +1,277 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/nano-kit/go-nano/cluster/clusterpb"
+	"github.com/nano-kit/go-nano/logging"
+	"github.com/nano-kit/go-nano/session"
+)
+
+// GateSessions is the sessions connected to a gate, used by the cluster-wide
+// session view of the node monitor
+type GateSessions struct {
+	Gate     string                   `json:"gate"`
+	Sessions []*clusterpb.SessionInfo `json:"sessions"`
+	Error    string                   `json:"error,omitempty"`
+}
+
+// KickReasonAdmin is the reason of the kick packet sent to the client kicked
+// by the session admin API
+const KickReasonAdmin = "kicked by administrator"
+
+// registerAdminHandlers registers the session admin API, the endpoints which
+// kick, push to and close the sessions, change the log levels or reload the
+// configuration are registered only if the monitor is protected by MonitorAuth
+// or MonitorAdmin is set explicitly, otherwise the log levels can only be viewed
+func (n *Node) registerAdminHandlers(mux *http.ServeMux) {
+	mux.HandleFunc("/debug/nano/sessions", n.adminSessions)
+	if n.MonitorAuth != nil || n.MonitorAdmin {
+		mux.HandleFunc("/debug/nano/sessions/kick", n.adminKick)
+		mux.HandleFunc("/debug/nano/sessions/push", n.adminPush)
+		mux.HandleFunc("/debug/nano/sessions/close", n.adminCloseUID)
+		mux.HandleFunc("/debug/nano/log/levels", n.adminLogLevels)
+		mux.HandleFunc("/debug/nano/config/reload", n.adminReload)
+	} else {
+		mux.HandleFunc("/debug/nano/log/levels", viewLogLevels)
+	}
+	if n.IsMaster {
+		mux.HandleFunc("/debug/nano/cluster/sessions", n.adminClusterSessions)
+	}
+}
+
+func sessionInfo(s *session.Session) *clusterpb.SessionInfo {
+	info := &clusterpb.SessionInfo{
+		Id:           int64(s.ID()),
+		Uid:          s.UID(),
+		LastActivity: s.LastTime().Unix(),
+		Routes:       s.Router().Bindings(),
+	}
+	if addr := s.RemoteAddr(); addr != nil {
+		info.RemoteAddr = addr.String()
+	}
+	if a, ok := s.NetworkEntity().(*agent); ok {
+		info.QueueDepth = int32(len(a.chSend))
+	}
+	return info
+}
+
+// gateSessions returns the sessions connected to current node directly
+func (n *Node) gateSessions() []*clusterpb.SessionInfo {
+	var result []*clusterpb.SessionInfo
+	for _, s := range n.Sessions() {
+		if _, ok := s.NetworkEntity().(*agent); ok {
+			result = append(result, sessionInfo(s))
+		}
+	}
+	return result
+}
+
+// ListSessions implements the MemberServer interface
+func (n *Node) ListSessions(_ context.Context, _ *clusterpb.ListSessionsRequest) (*clusterpb.ListSessionsResponse, error) {
+	return &clusterpb.ListSessionsResponse{Sessions: n.gateSessions()}, nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Error("write admin response failed", "err", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+func requirePost(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return false
+	}
+	return true
+}
+
+// sessionRecord parses the `gate` and `sid` query parameters, the gate
+// defaults to current node
+func (n *Node) sessionRecord(r *http.Request) (*clusterpb.SessionRecord, error) {
+	sid, err := strconv.ParseInt(r.FormValue("sid"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	gate := r.FormValue("gate")
+	if gate == "" {
+		gate = n.ServiceAddr
+	}
+	return &clusterpb.SessionRecord{GateAddr: gate, SessionId: sid}, nil
+}
+
+// adminSessions lists all sessions of current node
+func (n *Node) adminSessions(w http.ResponseWriter, _ *http.Request) {
+	sessions := n.Sessions()
+	result := make([]*clusterpb.SessionInfo, 0, len(sessions))
+	for _, s := range sessions {
+		result = append(result, sessionInfo(s))
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// adminClusterSessions lists sessions of all gates in the cluster
+func (n *Node) adminClusterSessions(w http.ResponseWriter, _ *http.Request) {
+	var result []GateSessions
+	for _, addr := range n.cluster.gateAddrs() {
+		if addr == n.ServiceAddr {
+			result = append(result, GateSessions{Gate: addr, Sessions: n.gateSessions()})
+			continue
+		}
+		gs := GateSessions{Gate: addr}
+		pool, err := n.rpcClient.getConnPool(addr)
+		if err == nil {
+			var resp *clusterpb.ListSessionsResponse
+			client := clusterpb.NewMemberClient(pool.Get())
+			ctx, cancel := context.WithTimeout(context.Background(), sessionRPCTimeout)
+			resp, err = client.ListSessions(ctx, &clusterpb.ListSessionsRequest{})
+			cancel()
+			if err == nil {
+				gs.Sessions = resp.Sessions
+			}
+		}
+		if err != nil {
+			gs.Error = err.Error()
+		}
+		result = append(result, gs)
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// adminKick sends the kick packet to the session specified by `gate` and
+// `sid`, and closes it
+func (n *Node) adminKick(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) {
+		return
+	}
+	record, err := n.sessionRecord(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := n.kickSession(record, KickReasonAdmin); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"kicked": 1})
+}
+
+// adminPush pushes the request body to the session specified by `gate` and
+// `sid` with the `route`, the body should be encoded by the serializer of
+// the application
+func (n *Node) adminPush(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) {
+		return
+	}
+	record, err := n.sessionRecord(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	route := r.FormValue("route")
+	if route == "" {
+		writeError(w, http.StatusBadRequest, ErrInvalidRoute)
+		return
+	}
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := n.pushToSession(record, route, data); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"pushed": 1})
+}
+
+// adminCloseUID closes all sessions bound to the `uid` across the cluster
+func (n *Node) adminCloseUID(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) {
+		return
+	}
+	uid := r.FormValue("uid")
+	if uid == "" {
+		writeError(w, http.StatusBadRequest, session.ErrIllegalUID)
+		return
+	}
+	records, err := n.registry.Find(uid)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	closed := 0
+	for _, record := range records {
+		if err := n.kickSession(record, ""); err != nil {
+			logger.Error("close session failed", "sid", record.SessionId, "member", record.GateAddr, "err", err)
+			continue
+		}
+		closed++
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"closed": closed})
+}
+
+// adminLogLevels lists the log levels of the packages on GET, and sets the
+// `level` of the package `pkg` on POST, the default level is set if pkg is
+// empty and the package level is removed if level is "reset"
+func (n *Node) adminLogLevels(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		viewLogLevels(w, r)
+		return
+	}
+	if !requirePost(w, r) {
+		return
+	}
+	pkg, name := r.FormValue("pkg"), r.FormValue("level")
+	if name == "reset" && pkg != "" {
+		logging.ResetLevel(pkg)
+	} else {
+		level, err := logging.ParseLevel(name)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		logging.SetLevel(pkg, level)
+	}
+	logger.Info("log level changed", "pkg", pkg, "level", name)
+	writeJSON(w, http.StatusOK, logging.Levels())
+}
+
+// viewLogLevels lists the log levels of the packages, the levels can not be
+// changed by it
+func viewLogLevels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	writeJSON(w, http.StatusOK, logging.Levels())
+}
+
+// adminReload reloads the configuration and reports the changed settings
+func (n *Node) adminReload(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) {
+		return
+	}
+	if n.Reloader == nil {
+		writeError(w, http.StatusNotImplemented, ErrReloadDisabled)
+		return
+	}
+	report, err := n.Reloader()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,235 @@
+package cluster
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nano-kit/go-nano/cluster/clusterpb"
+	"github.com/nano-kit/go-nano/internal/codec"
+	"github.com/nano-kit/go-nano/internal/packet"
+	"github.com/nano-kit/go-nano/logging"
+	"github.com/nano-kit/go-nano/mock"
+	"github.com/nano-kit/go-nano/ratelimit"
+	"github.com/nano-kit/go-nano/service"
+	"github.com/nano-kit/go-nano/session"
+)
+
+func TestAdminSessions(t *testing.T) {
+	n := &Node{
+		Options:  Options{ServiceAddr: "127.0.0.1:3250"},
+		sessions: map[service.SID]*session.Session{},
+		registry: NewMemoryRegistry(),
+	}
+	entity := mock.NewNetworkEntity()
+	s := session.New(entity)
+	s.Bind("u1")
+	s.Router().Bind("Room", "127.0.0.1:3260")
+	n.storeSession(s)
+
+	// the sessions can not be changed without authentication by default
+	mux := http.NewServeMux()
+	n.registerAdminHandlers(mux)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/debug/nano/sessions/kick?sid=1", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expect %d, got %d", http.StatusNotFound, w.Code)
+	}
+
+	n.MonitorAdmin = true
+	mux = http.NewServeMux()
+	n.registerAdminHandlers(mux)
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/nano/sessions", nil))
+	var infos []*clusterpb.SessionInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &infos); err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 || infos[0].Uid != "u1" || infos[0].RemoteAddr != "mock-addr" ||
+		infos[0].Routes["Room"] != "127.0.0.1:3260" {
+		t.Fatalf("unexpected sessions: %s", w.Body.String())
+	}
+
+	sid := strconv.FormatInt(int64(s.ID()), 10)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/nano/sessions/push?sid="+sid, nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expect %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	body := strings.NewReader(`{"content":"hello"}`)
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/debug/nano/sessions/push?route=notice&sid="+sid, body))
+	if w.Code != http.StatusOK {
+		t.Fatalf("push failed: %s", w.Body.String())
+	}
+	if string(entity.FindResponseByRoute("notice").([]byte)) != `{"content":"hello"}` {
+		t.Fatalf("unexpected pushed message: %v", entity.FindResponseByRoute("notice"))
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/debug/nano/sessions/kick?sid=abc", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expect %d, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	// only the gates are asked for their sessions
+	n.IsMaster = true
+	n.cluster = newCluster(n)
+	mux = http.NewServeMux()
+	n.registerAdminHandlers(mux)
+	n.cluster.members = []*Member{
+		{MemberInfo: &clusterpb.MemberInfo{ServiceAddr: n.ServiceAddr, Gate: true}},
+		{MemberInfo: &clusterpb.MemberInfo{ServiceAddr: "127.0.0.1:3260"}},
+	}
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/nano/cluster/sessions", nil))
+	var gates []GateSessions
+	if err := json.Unmarshal(w.Body.Bytes(), &gates); err != nil {
+		t.Fatal(err)
+	}
+	if len(gates) != 1 || gates[0].Gate != n.ServiceAddr || gates[0].Error != "" {
+		t.Fatalf("unexpected cluster sessions: %s", w.Body.String())
+	}
+}
+
+func TestAdminLogLevels(t *testing.T) {
+	defer logging.ResetLevel("cluster")
+
+	// the log levels can only be viewed without authentication by default
+	mux := http.NewServeMux()
+	(&Node{}).registerAdminHandlers(mux)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/debug/nano/log/levels?pkg=cluster&level=debug", nil))
+	if w.Code != http.StatusMethodNotAllowed || logger.Enabled(logging.LevelDebug) {
+		t.Fatalf("expect %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/nano/log/levels", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expect %d, got %d", http.StatusOK, w.Code)
+	}
+
+	mux = http.NewServeMux()
+	(&Node{Options: Options{MonitorAdmin: true}}).registerAdminHandlers(mux)
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/debug/nano/log/levels?pkg=cluster&level=verbose", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expect %d, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/debug/nano/log/levels?pkg=cluster&level=debug", nil))
+	if w.Code != http.StatusOK || !logger.Enabled(logging.LevelDebug) {
+		t.Fatalf("set level failed: %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/nano/log/levels", nil))
+	var levels map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &levels); err != nil {
+		t.Fatal(err)
+	}
+	if levels["cluster"] != "debug" {
+		t.Fatalf("unexpected levels: %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/debug/nano/log/levels?pkg=cluster&level=reset", nil))
+	if w.Code != http.StatusOK || logging.LevelOf("cluster") != logging.LevelOf("") {
+		t.Fatalf("reset level failed: %s", w.Body.String())
+	}
+}
+
+func TestAdminReload(t *testing.T) {
+	// the configuration can not be reloaded without authentication by default
+	n := &Node{}
+	mux := http.NewServeMux()
+	n.registerAdminHandlers(mux)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/debug/nano/config/reload", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expect %d, got %d", http.StatusNotFound, w.Code)
+	}
+
+	n.MonitorAdmin = true
+	mux = http.NewServeMux()
+	n.registerAdminHandlers(mux)
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/debug/nano/config/reload", nil))
+	if w.Code != http.StatusNotImplemented {
+		t.Fatalf("expect %d, got %d", http.StatusNotImplemented, w.Code)
+	}
+
+	n.Reloader = func() (*ReloadReport, error) {
+		n.Reload(Reloadable{Heartbeat: 9 * time.Second, RateLimit: &ratelimit.Config{}})
+		return &ReloadReport{Applied: []string{"heartbeat", "rateLimit"}}, nil
+	}
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/debug/nano/config/reload", nil))
+	var report ReloadReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Applied) != 2 || n.heartbeat() != 9*time.Second || n.rateLimiter() == nil {
+		t.Fatalf("unexpected reload: %s", w.Body.String())
+	}
+	if !strings.Contains(string(n.handshakeData(negotiation{})), `"heartbeat":9`) {
+		t.Fatalf("unexpected handshake data %s", n.handshakeData(negotiation{}))
+	}
+}
+
+func TestReloadOptionsUnchanged(t *testing.T) {
+	n := &Node{Options: Options{Heartbeat: 5 * time.Second, ReadIdleTimeout: time.Minute}}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		n.Reload(Reloadable{Heartbeat: 9 * time.Second, WriteTimeout: time.Second})
+	}()
+	// the options are read without the lock while reloading
+	_ = n.Heartbeat + n.ReadIdleTimeout + n.WriteTimeout
+	<-done
+
+	if n.heartbeat() != 9*time.Second || n.readIdleTimeout() != 0 || n.writeTimeout() != time.Second {
+		t.Fatalf("unexpected settings %v %v %v", n.heartbeat(), n.readIdleTimeout(), n.writeTimeout())
+	}
+	if n.Heartbeat != 5*time.Second || n.ReadIdleTimeout != time.Minute {
+		t.Fatal("the options of the node should not be changed")
+	}
+}
+
+func TestAdminKick(t *testing.T) {
+	n := newTestNode(t, Options{MonitorAdmin: true}, nil)
+	n.ServiceAddr = "127.0.0.1:3250"
+	a, client := newTestAgent(n)
+	n.storeSession(a.session)
+	go a.write()
+
+	mux := http.NewServeMux()
+	n.registerAdminHandlers(mux)
+	sid := strconv.FormatInt(int64(a.session.ID()), 10)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/debug/nano/sessions/kick?sid="+sid, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("kick failed: %s", w.Body.String())
+	}
+
+	// the kick packet is written before the connection is closed
+	data, _ := ioutil.ReadAll(client)
+	packets, err := codec.NewDecoder().Decode(data)
+	if err != nil || len(packets) != 1 || packets[0].Type != packet.Kick {
+		t.Fatalf("expect kick packet, got %v, %v", packets, err)
+	}
+	var k Kick
+	if err := json.Unmarshal(packets[0].Data, &k); err != nil || k.Reason != KickReasonAdmin {
+		t.Fatalf("unexpected kick %s, %v", packets[0].Data, err)
+	}
+}
@@ -0,0 +1,185 @@
+package cluster
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nano-kit/go-nano/ratelimit"
+)
+
+// defaultMaxConnections is the number of client connections the open files
+// limit is sized for if AdmissionOptions.MaxConnections is not specified,
+// the connections are not limited by it
+const defaultMaxConnections = 10000
+
+// AdmissionOptions controls which client connections are accepted by the gate
+type AdmissionOptions struct {
+	MaxConnections   int             // maximum concurrent client connections, zero means unlimited
+	MaxConnsPerIP    int             // maximum concurrent client connections from an IP, zero means unlimited
+	AcceptRate       ratelimit.Limit // the rate of accepting new connections, zero means unlimited
+	AllowCIDRs       []string        // only the addresses in the CIDRs are accepted if specified, e.g. "10.0.0.0/8"
+	DenyCIDRs        []string        // the addresses in the CIDRs are rejected, it takes precedence over AllowCIDRs
+	HandshakeTimeout time.Duration   // close the connection without handshake in time, zero to disable
+}
+
+// admission enforces the AdmissionOptions, a nil admission accepts everything
+type admission struct {
+	opts   AdmissionOptions
+	allow  []*net.IPNet
+	deny   []*net.IPNet
+	accept *ratelimit.Bucket
+	conns  int64
+
+	mu    sync.Mutex
+	perIP map[string]int
+}
+
+func newAdmission(opts AdmissionOptions) (*admission, error) {
+	allow, err := parseCIDRs(opts.AllowCIDRs)
+	if err != nil {
+		return nil, err
+	}
+	deny, err := parseCIDRs(opts.DenyCIDRs)
+	if err != nil {
+		return nil, err
+	}
+	return &admission{
+		opts:   opts,
+		allow:  allow,
+		deny:   deny,
+		accept: ratelimit.NewBucket(opts.AcceptRate),
+		perIP:  map[string]int{},
+	}, nil
+}
+
+// parseCIDRs parses the CIDRs, see ParseCIDR
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	var result []*net.IPNet
+	for _, cidr := range cidrs {
+		ipnet, err := ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, ipnet)
+	}
+	return result, nil
+}
+
+// ParseCIDR parses a CIDR of AdmissionOptions, a single IP is treated as a
+// host network
+func ParseCIDR(cidr string) (*net.IPNet, error) {
+	if !strings.Contains(cidr, "/") {
+		ip := net.ParseIP(cidr)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid ip address: %s", cidr)
+		}
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 8*net.IPv4len
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	_, ipnet, err := net.ParseCIDR(cidr)
+	return ipnet, err
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func hostOf(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// admit decides whether the connection from the remote address is accepted,
+// the accepted connection should be released after it is closed
+func (a *admission) admit(addr string) error {
+	if a == nil {
+		return nil
+	}
+
+	host := hostOf(addr)
+	if ip := net.ParseIP(host); ip != nil {
+		if containsIP(a.deny, ip) || (len(a.allow) > 0 && !containsIP(a.allow, ip)) {
+			return ErrAddressDenied
+		}
+	}
+	if !a.accept.Allow() {
+		return ErrAcceptRateExceeded
+	}
+	if conns := atomic.AddInt64(&a.conns, 1); a.opts.MaxConnections > 0 && conns > int64(a.opts.MaxConnections) {
+		atomic.AddInt64(&a.conns, -1)
+		return ErrTooManyConnections
+	}
+
+	a.mu.Lock()
+	if a.opts.MaxConnsPerIP > 0 && a.perIP[host] >= a.opts.MaxConnsPerIP {
+		a.mu.Unlock()
+		atomic.AddInt64(&a.conns, -1)
+		return ErrTooManyConnsFromIP
+	}
+	a.perIP[host]++
+	a.mu.Unlock()
+
+	return nil
+}
+
+// admit admits the connection from addr by the admission of the node, the
+// result is counted in the metrics of the node
+func (n *Node) admit(addr string) error {
+	err := n.admission.admit(addr)
+	if err != nil {
+		n.metrics.admissions.With(err.Error()).Inc()
+	} else {
+		n.metrics.admissions.With("accepted").Inc()
+	}
+	return err
+}
+
+// release releases the connection accepted by admit
+func (a *admission) release(addr string) {
+	if a == nil {
+		return
+	}
+
+	host := hostOf(addr)
+	atomic.AddInt64(&a.conns, -1)
+	a.mu.Lock()
+	if a.perIP[host]--; a.perIP[host] <= 0 {
+		delete(a.perIP, host)
+	}
+	a.mu.Unlock()
+}
+
+// connections returns the number of current client connections
+func (a *admission) connections() int64 {
+	if a == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&a.conns)
+}
+
+// rejectHTTP writes the rejection of the WebSocket upgrade
+func rejectHTTP(w http.ResponseWriter, err error) {
+	code := http.StatusServiceUnavailable
+	switch err {
+	case ErrAddressDenied:
+		code = http.StatusForbidden
+	case ErrTooManyConnsFromIP:
+		code = http.StatusTooManyRequests
+	}
+	http.Error(w, err.Error(), code)
+}
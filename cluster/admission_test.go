@@ -0,0 +1,82 @@
+package cluster
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nano-kit/go-nano/ratelimit"
+)
+
+func TestAdmission(t *testing.T) {
+	a, err := newAdmission(AdmissionOptions{
+		MaxConnections: 3,
+		MaxConnsPerIP:  2,
+		AllowCIDRs:     []string{"10.0.0.0/8", "192.168.1.1"},
+		DenyCIDRs:      []string{"10.0.0.13"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		addr string
+		err  error
+	}{
+		{"10.0.0.1:1000", nil},
+		{"10.0.0.1:1001", nil},
+		{"10.0.0.1:1002", ErrTooManyConnsFromIP},
+		{"10.0.0.13:1000", ErrAddressDenied},
+		{"172.16.0.1:1000", ErrAddressDenied},
+		{"192.168.1.1:1000", nil},
+		{"10.0.0.2:1000", ErrTooManyConnections},
+	}
+	for _, c := range cases {
+		if err := a.admit(c.addr); err != c.err {
+			t.Fatalf("admit %s: expect %v, got %v", c.addr, c.err, err)
+		}
+	}
+
+	a.release("10.0.0.1:1000")
+	if err := a.admit("10.0.0.2:1000"); err != nil {
+		t.Fatalf("connection should be accepted after release, got %v", err)
+	}
+	if a.connections() != 3 {
+		t.Fatalf("expect 3 connections, got %d", a.connections())
+	}
+
+	// zero MaxConnections means unlimited
+	unlimited, _ := newAdmission(AdmissionOptions{})
+	for i := 0; i < defaultMaxConnections+1; i++ {
+		if err := unlimited.admit("10.0.0.1:1000"); err != nil {
+			t.Fatalf("connection %d should be accepted, got %v", i, err)
+		}
+	}
+
+	if _, err := newAdmission(AdmissionOptions{DenyCIDRs: []string{"bad"}}); err == nil {
+		t.Fatal("invalid CIDR should be rejected")
+	}
+
+	w := httptest.NewRecorder()
+	rejectHTTP(w, ErrAddressDenied)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expect %d, got %d", http.StatusForbidden, w.Code)
+	}
+}
+
+func TestAcceptRate(t *testing.T) {
+	a, err := newAdmission(AdmissionOptions{AcceptRate: ratelimit.Limit{Rate: 1, Burst: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.admit("127.0.0.1:1000"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.admit("127.0.0.1:1001"); err != ErrAcceptRateExceeded {
+		t.Fatalf("expect %v, got %v", ErrAcceptRateExceeded, err)
+	}
+	var nilAdmission *admission
+	if err := nilAdmission.admit("127.0.0.1:1000"); err != nil {
+		t.Fatal("nil admission should accept everything")
+	}
+}
@@ -21,19 +21,20 @@
 package cluster
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"net"
+	"runtime/debug"
 	"sync/atomic"
 	"time"
 
 	"github.com/nano-kit/go-nano/cluster/clusterpb"
 	"github.com/nano-kit/go-nano/internal/codec"
-	"github.com/nano-kit/go-nano/internal/env"
-	"github.com/nano-kit/go-nano/internal/log"
 	"github.com/nano-kit/go-nano/internal/message"
 	"github.com/nano-kit/go-nano/internal/packet"
+	"github.com/nano-kit/go-nano/logging"
 	"github.com/nano-kit/go-nano/pipeline"
 	"github.com/nano-kit/go-nano/scheduler"
 	"github.com/nano-kit/go-nano/session"
@@ -41,6 +42,10 @@ import (
 
 const (
 	agentWriteBacklog = 16
+
+	// agentClosingTimeout is how long the write goroutine waits for the
+	// OnClosing callbacks before the connection is closed
+	agentClosingTimeout = 3 * time.Second
 )
 
 var (
@@ -55,15 +60,26 @@ type (
 	// Agent corresponding a user, used for store raw conn information
 	agent struct {
 		// regular agent member
-		session  *session.Session    // session
-		conn     net.Conn            // low-level conn fd
-		lastMid  uint64              // last message id
-		state    int32               // current agent state
-		chDie    chan struct{}       // wait for close
-		chSend   chan pendingMessage // push message queue
-		lastAt   int64               // last heartbeat unix time stamp
-		decoder  *codec.Decoder      // binary decoder
-		pipeline pipeline.Pipeline
+		session   *session.Session    // session
+		conn      net.Conn            // low-level conn fd
+		lastMid   uint64              // last message id
+		state     int32               // current agent state
+		chDie     chan struct{}       // wait for close
+		chClosing chan struct{}       // closed after the OnClosing callbacks are called
+		chSend    chan pendingMessage // push message queue
+		lastAt    int64               // last heartbeat unix time stamp
+		reader    *codec.Reader       // packet reader of the connection
+		pipeline  pipeline.Pipeline
+		node      *Node // the node which accepted the connection
+		reason    int32 // session.CloseReason, the first one wins
+		compress  int32 // set if the client negotiated compression in handshake
+		fragment  int32 // the maximum packet size if the client negotiated fragments in handshake
+
+		trace traceContext // trace context of the handler being executed
+
+		lastDataAt  int64 // last data packet unix nano time stamp
+		idleCheckAt int64 // lastDataAt of the last idle check, accessed by scheduler
+		idleChecked time.Duration
 
 		rpcHandler rpcHandler
 	}
@@ -73,24 +89,28 @@ type (
 		route   string       // message route(push)
 		mid     uint64       // response message id(response)
 		payload interface{}  // payload
+		raw     []byte       // encoded packet written as is, e.g. kick
 	}
 )
 
 // Create new agent instance
-func newAgent(conn net.Conn, pipeline pipeline.Pipeline, rpcHandler rpcHandler) *agent {
+func newAgent(conn net.Conn, node *Node, pipeline pipeline.Pipeline, rpcHandler rpcHandler) *agent {
 	a := &agent{
 		conn:       conn,
+		node:       node,
 		state:      statusStart,
 		chDie:      make(chan struct{}),
+		chClosing:  make(chan struct{}),
 		lastAt:     time.Now().Unix(),
+		lastDataAt: time.Now().UnixNano(),
 		chSend:     make(chan pendingMessage, agentWriteBacklog),
-		decoder:    codec.NewDecoder(),
+		reader:     codec.NewReader(conn, node.maxPacketSize(), node.maxMessageSize()),
 		pipeline:   pipeline,
 		rpcHandler: rpcHandler,
 	}
 
 	// binding session
-	s := session.New(a)
+	s := session.NewWith(node.connections.SessionID(), a)
 	a.session = s
 
 	return a
@@ -106,6 +126,15 @@ func (a *agent) send(m pendingMessage) (err error) {
 	return
 }
 
+// kick queues the encoded kick packet, it is dropped if the send queue is full
+// since the session is going to be closed anyway
+func (a *agent) kick(data []byte) {
+	if a.status() >= statusClosing || len(a.chSend) >= agentWriteBacklog {
+		return
+	}
+	a.send(pendingMessage{raw: data})
+}
+
 // LastMid implements the session.NetworkEntity interface
 func (a *agent) LastMid() uint64 {
 	return a.lastMid
@@ -118,17 +147,17 @@ func (a *agent) Push(route string, v interface{}) error {
 	}
 
 	if len(a.chSend) >= agentWriteBacklog {
+		a.node.metrics.dropped.With(message.Push.String()).Inc()
 		return ErrBufferExceeded
 	}
 
-	if env.Debug {
+	if logger.Enabled(logging.LevelDebug) {
 		switch d := v.(type) {
 		case []byte:
-			log.Printf("Type=Push, ID=%d, UID=%d, Route=%s, Data=%dbytes",
-				a.session.ID(), a.session.UID(), route, len(d))
+			logger.Debug("push", "sid", a.session.ID(), "uid", a.session.UID(), "route", route, "bytes", len(d))
 		default:
-			log.Printf("Type=Push, ID=%d, UID=%d, Route=%s, Data=%+v",
-				a.session.ID(), a.session.UID(), route, v)
+			logger.Debug("push", "sid", a.session.ID(), "uid", a.session.UID(), "route", route,
+				"data", fmt.Sprintf("%+v", v))
 		}
 	}
 
@@ -142,16 +171,17 @@ func (a *agent) Notify(route string, v interface{}) error {
 	}
 
 	// TODO: buffer
-	data, err := message.Serialize(v)
+	data, err := message.SerializeWith(a.node.serializer(), v)
 	if err != nil {
 		return err
 	}
 	msg := &message.Message{
-		Type:  message.Notify,
-		Route: route,
-		Data:  data,
+		Type:        message.Notify,
+		Route:       route,
+		Data:        data,
+		Traceparent: a.trace.get(),
 	}
-	a.rpcHandler(a.session, msg, true)
+	a.rpcHandler(a.session, msg)
 	return nil
 }
 
@@ -173,45 +203,113 @@ func (a *agent) ResponseMid(mid uint64, v interface{}) error {
 	}
 
 	if len(a.chSend) >= agentWriteBacklog {
+		a.node.metrics.dropped.With(message.Response.String()).Inc()
 		return ErrBufferExceeded
 	}
 
-	if env.Debug {
+	if logger.Enabled(logging.LevelDebug) {
 		switch d := v.(type) {
 		case []byte:
-			log.Printf("Type=Response, ID=%d, UID=%d, MID=%d, Data=%dbytes",
-				a.session.ID(), a.session.UID(), mid, len(d))
+			logger.Debug("response", "sid", a.session.ID(), "uid", a.session.UID(), "mid", mid, "bytes", len(d))
 		default:
-			log.Printf("Type=Response, ID=%d, UID=%d, MID=%d, Data=%+v",
-				a.session.ID(), a.session.UID(), mid, v)
+			logger.Debug("response", "sid", a.session.ID(), "uid", a.session.UID(), "mid", mid,
+				"data", fmt.Sprintf("%+v", v))
 		}
 	}
 
 	return a.send(pendingMessage{typ: message.Response, mid: mid, payload: v})
 }
 
+// BindUID records the UID binding to the session registry
+func (a *agent) BindUID(uid string) error {
+	return a.node.bindSession(a.session, a.node.ServiceAddr, a.session.ID(), uid)
+}
+
+// UnbindUID removes the UID binding from the session registry
+func (a *agent) UnbindUID(string) {
+	a.node.unbindSession(a.session)
+}
+
+// Lifecycle implements the lifecycle provider of session
+func (a *agent) Lifecycle() *session.Lifecycle {
+	return a.node.Lifecycle
+}
+
 // Close, implementation for session.NetworkEntity interface
 // Close closes the agent, clean inner state and close low-level connection.
 // Any blocked Read or Write operations will be unblocked and return errors.
 func (a *agent) Close() error {
-	if a.setStatus(statusClosed) == statusClosed {
-		return ErrCloseClosedSession
-	}
+	return a.closeWith(session.CloseKicked)
+}
+
+// CloseReason returns the reason of closing
+func (a *agent) CloseReason() session.CloseReason {
+	return session.CloseReason(atomic.LoadInt32(&a.reason))
+}
 
-	if env.Debug {
-		log.Printf("session closed, ID=%d, UID=%d, IP=%s",
-			a.session.ID(), a.session.UID(), a.conn.RemoteAddr())
+// closeWith closes the agent with the reason. It never blocks: the write
+// goroutine is signaled to stop, and the OnClosing callbacks are posted to
+// the scheduler. The messages pushed by the callbacks and the pending ones
+// are written before the low-level connection is closed.
+func (a *agent) closeWith(reason session.CloseReason) error {
+	atomic.CompareAndSwapInt32(&a.reason, int32(session.CloseUnknown), int32(reason))
+	for {
+		state := a.status()
+		if state >= statusClosing {
+			return ErrCloseClosedSession
+		}
+		if atomic.CompareAndSwapInt32(&a.state, state, statusClosing) {
+			break
+		}
 	}
 
-	// prevent closing closed channel
+	close(a.chDie)
+	a.runLifecycle(func() {
+		defer close(a.chClosing)
+		a.node.Lifecycle.NotifyClosing(a.session, a.CloseReason())
+	})
+	return nil
+}
+
+// shutdown notifies the session is closed after the low-level connection
+// is closed, it is called by the write goroutine
+func (a *agent) shutdown() {
+	a.setStatus(statusClosed)
+
+	logger.Debug("session closed", "sid", a.session.ID(), "uid", a.session.UID(),
+		"remote", a.conn.RemoteAddr(), "reason", a.CloseReason())
+
+	a.node.metrics.closed.With(a.CloseReason().String()).Inc()
+	a.waitClosing()
+	a.runLifecycle(func() { a.node.Lifecycle.NotifyClosed(a.session, a.CloseReason()) })
+}
+
+// waitClosing waits for the OnClosing callbacks for agentClosingTimeout at most
+func (a *agent) waitClosing() {
+	timer := time.NewTimer(agentClosingTimeout)
+	defer timer.Stop()
+
 	select {
-	case <-a.chDie:
-	default:
-		close(a.chDie)
-		scheduler.Run(func() { session.Lifetime.Close(a.session) })
+	case <-a.chClosing:
+	case <-timer.C:
+		logger.Warn("session closing callbacks timeout", "sid", a.session.ID(), "uid", a.session.UID())
 	}
+}
 
-	return a.conn.Close()
+// runLifecycle posts the lifecycle callbacks to the scheduler of the node,
+// they are called in a new goroutine rather than blocking the caller if the
+// scheduler is busy or stopped
+func (a *agent) runLifecycle(task scheduler.Task) {
+	if !a.node.Scheduler.TryRun(task) {
+		go func() {
+			defer func() {
+				if err := recover(); err != nil {
+					logger.Error("handle lifecycle panic", "err", err, "stack", string(debug.Stack()))
+				}
+			}()
+			task()
+		}()
+	}
 }
 
 // RemoteAddr, implementation for session.NetworkEntity interface
@@ -234,75 +332,153 @@ func (a *agent) setStatus(state int32) (oldstate int32) {
 }
 
 func (a *agent) write() {
-	ticker := time.NewTicker(env.Heartbeat)
+	heartbeat := a.node.heartbeat()
+	ticker := time.NewTicker(heartbeat)
+	reason := session.CloseUnknown
 	// clean func
 	defer func() {
 		ticker.Stop()
 		close(a.chSend)
-		a.Close()
-		if env.Debug {
-			log.Printf("session write goroutine exit, SessionID=%d, UID=%d", a.session.ID(), a.session.UID())
-		}
+		a.closeWith(reason)
+		a.conn.Close()
+		a.shutdown()
+		logger.Debug("session write goroutine exit", "sid", a.session.ID(), "uid", a.session.UID())
 	}()
 
 	for {
 		select {
 		case <-ticker.C:
-			deadline := time.Now().Add(-2 * env.Heartbeat).Unix()
+			deadline := time.Now().Add(-2 * heartbeat).Unix()
 			if atomic.LoadInt64(&a.lastAt) < deadline {
-				log.Printf("session heartbeat timeout, LastTime=%d, Deadline=%d", atomic.LoadInt64(&a.lastAt), deadline)
+				logger.Info("session heartbeat timeout", "sid", a.session.ID(), "uid", a.session.UID(),
+					"last", atomic.LoadInt64(&a.lastAt), "deadline", deadline)
+				reason = session.CloseHeartbeatTimeout
 				return
 			}
 
 			// close agent while low-level conn broken
+			a.setWriteDeadline()
 			if _, err := a.conn.Write(hbd); err != nil {
-				log.Print(err.Error())
+				logger.Error("write heartbeat failed", "sid", a.session.ID(), "err", err)
+				reason = writeCloseReason(err)
 				return
 			}
 
 		case data := <-a.chSend:
-			payload, err := message.Serialize(data.payload)
-			if err != nil {
-				switch data.typ {
-				case message.Push:
-					log.Printf("push: %s error: %s", data.route, err.Error())
-				case message.Response:
-					log.Printf("response message(id: %d) error: %s", data.mid, err.Error())
-				}
-				break
+			if err := a.writePending(data); err != nil {
+				logger.Error("write message failed", "sid", a.session.ID(), "err", err)
+				reason = writeCloseReason(err)
+				return
 			}
 
-			// construct message and encode
-			m := &message.Message{
-				Type:  data.typ,
-				Data:  payload,
-				Route: data.route,
-				ID:    data.mid,
-			}
-			if pipe := a.pipeline; pipe != nil {
-				err := pipe.Outbound().Process(a.session, m)
-				if err != nil {
-					log.Print("broken pipeline", err.Error())
-					break
+		case <-a.chDie: // agent closed signal
+			// write the messages pushed before and during closing
+			a.waitClosing()
+			for {
+				select {
+				case data := <-a.chSend:
+					if err := a.writePending(data); err != nil {
+						logger.Error("write message failed", "sid", a.session.ID(), "err", err)
+						return
+					}
+				default:
+					return
 				}
 			}
 
-			if err := a.writeMessage(m); err != nil {
-				log.Print(err.Error())
-				return
-			}
-
-		case <-a.chDie: // agent closed signal
+		case <-a.node.die: // node shut down
+			reason = session.CloseServerShutdown
 			return
+		}
+	}
+}
 
-		case <-env.Die: // application quit
-			return
+// writePending encodes the pending message and writes it to the connection,
+// only the failure of writing is returned
+func (a *agent) writePending(data pendingMessage) error {
+	if data.raw != nil {
+		a.setWriteDeadline()
+		_, err := a.conn.Write(data.raw)
+		return err
+	}
+
+	payload, err := message.SerializeWith(a.node.serializer(), data.payload)
+	if err != nil {
+		a.node.metrics.serializationErrors.With("marshal").Inc()
+		switch data.typ {
+		case message.Push:
+			logger.Error("serialize push failed", "sid", a.session.ID(), "route", data.route, "err", err)
+		case message.Response:
+			logger.Error("serialize response failed", "sid", a.session.ID(), "mid", data.mid, "err", err)
+		}
+		return nil
+	}
+
+	// construct message and encode
+	m := &message.Message{
+		Type:  data.typ,
+		Data:  payload,
+		Route: data.route,
+		ID:    data.mid,
+	}
+	if pipe := a.pipeline; pipe != nil {
+		err := pipe.Outbound().Process(a.session, m)
+		if err != nil {
+			logger.Error("broken pipeline", "sid", a.session.ID(), "route", m.Route, "err", err)
+			return nil
+		}
+
+		ctx := pipeline.NewContext(a.session, m)
+		ctx.Data = data.payload
+		var (
+			werr    error
+			written bool
+		)
+		err = pipeline.Execute(pipe.Outbound(), ctx, func() error {
+			werr, written = a.writeMessage(m), true
+			return werr
+		})
+		if err != nil && werr == nil {
+			logger.Error("broken pipeline", "sid", a.session.ID(), "route", m.Route, "err", err)
 		}
+		if written && werr == nil {
+			a.node.metrics.sent.With(m.Route, m.Type.String()).Inc()
+		}
+		return werr
 	}
+
+	if err := a.writeMessage(m); err != nil {
+		return err
+	}
+	a.node.metrics.sent.With(m.Route, m.Type.String()).Inc()
+	return nil
+}
+
+// setWriteDeadline applies the write timeout to the next write
+func (a *agent) setWriteDeadline() {
+	if timeout := a.node.writeTimeout(); timeout > 0 {
+		a.conn.SetWriteDeadline(time.Now().Add(timeout))
+	}
+}
+
+// isTimeout decides whether err is caused by an expired deadline
+func isTimeout(err error) bool {
+	ne, ok := err.(net.Error)
+	return ok && ne.Timeout()
+}
+
+// writeCloseReason returns the reason of closing the session for the write error
+func writeCloseReason(err error) session.CloseReason {
+	if isTimeout(err) {
+		return session.CloseWriteTimeout
+	}
+	return session.CloseWriteError
 }
 
 // writeMessage supports a "writev"-like batch write optimization
 func (a *agent) writeMessage(m *message.Message) (err error) {
+	m = a.compressMessage(m)
+	a.setWriteDeadline()
 	if _, ok := a.conn.(*wsConn); ok {
 		return a.writeMessageWS(m)
 	}
@@ -311,13 +487,17 @@ func (a *agent) writeMessage(m *message.Message) (err error) {
 	var buff [3][]byte
 	b := net.Buffers(buff[:])
 	b[2] = m.Data
-	b[1], err = m.EncodeHeader()
+	b[1], err = a.node.dict.EncodeHeader(m)
 	if err != nil {
 		return err
 	}
 
 	// packet encode
-	b[0], err = codec.EncodeHeader(packet.Data, len(b[1])+len(b[2]))
+	if size := atomic.LoadInt32(&a.fragment); size > 0 && len(b[1])+len(b[2]) > int(size) {
+		b, err = codec.EncodeFragments(int(size), b[1], b[2])
+	} else {
+		b[0], err = codec.EncodeHeader(packet.Data, len(b[1])+len(b[2]))
+	}
 	if err != nil {
 		return err
 	}
@@ -332,13 +512,20 @@ func (a *agent) writeMessage(m *message.Message) (err error) {
 
 // writeMessageWS converts m to bytes and writes to web socket.
 func (a *agent) writeMessageWS(m *message.Message) error {
-	em, err := m.Encode()
+	em, err := a.node.dict.Encode(m)
 	if err != nil {
 		return err
 	}
 
-	// packet encode
-	p, err := codec.Encode(packet.Data, em)
+	// packet encode, the fragments are written in one WebSocket message
+	var p []byte
+	if size := atomic.LoadInt32(&a.fragment); size > 0 && len(em) > int(size) {
+		var fragments [][]byte
+		fragments, err = codec.EncodeFragments(int(size), em)
+		p = bytes.Join(fragments, nil)
+	} else {
+		p, err = codec.Encode(packet.Data, em)
+	}
 	if err != nil {
 		return err
 	}
@@ -354,22 +541,21 @@ func (a *agent) writeMessageWS(m *message.Message) error {
 func (a *agent) notifySessionClosed(rpcClient *rpcClient, members []string) {
 	request := &clusterpb.SessionClosedRequest{
 		SessionId: int64(a.session.ID()),
+		Reason:    int32(a.CloseReason()),
 	}
 
 	for _, remote := range members {
 		pool, err := rpcClient.getConnPool(remote)
 		if err != nil {
-			log.Print("cannot retrieve connection pool for address", remote, err)
+			logger.Error("retrieve connection pool failed", "member", remote, "err", err)
 			continue
 		}
 		client := clusterpb.NewMemberClient(pool.Get())
 		_, err = client.SessionClosed(context.Background(), request)
 		if err != nil {
-			log.Print("cannot closed session in remote address", remote, err)
+			logger.Error("notify session closed failed", "sid", a.session.ID(), "member", remote, "err", err)
 			continue
 		}
-		if env.Debug {
-			log.Print("notify remote server success", remote)
-		}
+		logger.Debug("notify session closed", "sid", a.session.ID(), "member", remote)
 	}
 }
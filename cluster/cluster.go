@@ -26,8 +26,6 @@ import (
 	"sync"
 
 	"github.com/nano-kit/go-nano/cluster/clusterpb"
-	"github.com/nano-kit/go-nano/internal/env"
-	"github.com/nano-kit/go-nano/internal/log"
 )
 
 // cluster represents a nano cluster, which contains a bunch of nano nodes
@@ -77,9 +75,7 @@ func (c *cluster) Register(_ context.Context, req *clusterpb.RegisterRequest) (*
 		}
 	}
 
-	if env.Debug {
-		log.Print("new peer register to cluster", req.MemberInfo.ServiceAddr)
-	}
+	logger.Debug("new peer register to cluster", "member", req.MemberInfo.ServiceAddr)
 
 	// Register services to current node
 	c.currentNode.handler.addRemoteService(req.MemberInfo)
@@ -124,8 +120,11 @@ func (c *cluster) Unregister(_ context.Context, req *clusterpb.UnregisterRequest
 		}
 	}
 
-	if env.Debug {
-		log.Print("node unregister from cluster", req.ServiceAddr)
+	logger.Debug("node unregister from cluster", "member", req.ServiceAddr)
+
+	// Remove the UID bindings of sessions connected to the member
+	if err := c.currentNode.registry.Purge(req.ServiceAddr); err != nil {
+		logger.Error("purge session registry failed", "member", req.ServiceAddr, "err", err)
 	}
 
 	// Register services to current node
@@ -154,6 +153,19 @@ func (c *cluster) remoteAddrs() []string {
 	return addrs
 }
 
+// gateAddrs returns the service addresses of the gate members
+func (c *cluster) gateAddrs() []string {
+	var addrs []string
+	c.mu.RLock()
+	for _, m := range c.members {
+		if m.MemberInfo.Gate {
+			addrs = append(addrs, m.ServiceAddr)
+		}
+	}
+	c.mu.RUnlock()
+	return addrs
+}
+
 func (c *cluster) initMembers(members []*clusterpb.MemberInfo) {
 	c.mu.Lock()
 	for _, info := range members {
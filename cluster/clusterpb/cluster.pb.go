@@ -28,6 +28,7 @@ type MemberInfo struct {
 	Label                string   `protobuf:"bytes,1,opt,name=label,proto3" json:"label,omitempty"`
 	ServiceAddr          string   `protobuf:"bytes,2,opt,name=serviceAddr,proto3" json:"serviceAddr,omitempty"`
 	Services             []string `protobuf:"bytes,3,rep,name=services,proto3" json:"services,omitempty"`
+	Gate                 bool     `protobuf:"varint,4,opt,name=gate,proto3" json:"gate,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -79,6 +80,13 @@ func (m *MemberInfo) GetServices() []string {
 	return nil
 }
 
+func (m *MemberInfo) GetGate() bool {
+	if m != nil {
+		return m.Gate
+	}
+	return false
+}
+
 type RegisterRequest struct {
 	MemberInfo           *MemberInfo `protobuf:"bytes,1,opt,name=memberInfo,proto3" json:"memberInfo,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
@@ -227,22 +235,339 @@ func (m *UnregisterResponse) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_UnregisterResponse proto.InternalMessageInfo
 
-type RequestMessage struct {
+type SessionRecord struct {
 	GateAddr             string   `protobuf:"bytes,1,opt,name=gateAddr,proto3" json:"gateAddr,omitempty"`
 	SessionId            int64    `protobuf:"varint,2,opt,name=sessionId,proto3" json:"sessionId,omitempty"`
-	Id                   uint64   `protobuf:"varint,3,opt,name=id,proto3" json:"id,omitempty"`
-	Route                string   `protobuf:"bytes,4,opt,name=route,proto3" json:"route,omitempty"`
-	Data                 []byte   `protobuf:"bytes,5,opt,name=data,proto3" json:"data,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
+func (m *SessionRecord) Reset()         { *m = SessionRecord{} }
+func (m *SessionRecord) String() string { return proto.CompactTextString(m) }
+func (*SessionRecord) ProtoMessage()    {}
+func (*SessionRecord) Descriptor() ([]byte, []int) {
+	return fileDescriptor_3cfb3b8ec240c376, []int{5}
+}
+
+func (m *SessionRecord) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SessionRecord.Unmarshal(m, b)
+}
+func (m *SessionRecord) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SessionRecord.Marshal(b, m, deterministic)
+}
+func (m *SessionRecord) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SessionRecord.Merge(m, src)
+}
+func (m *SessionRecord) XXX_Size() int {
+	return xxx_messageInfo_SessionRecord.Size(m)
+}
+func (m *SessionRecord) XXX_DiscardUnknown() {
+	xxx_messageInfo_SessionRecord.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SessionRecord proto.InternalMessageInfo
+
+func (m *SessionRecord) GetGateAddr() string {
+	if m != nil {
+		return m.GateAddr
+	}
+	return ""
+}
+
+func (m *SessionRecord) GetSessionId() int64 {
+	if m != nil {
+		return m.SessionId
+	}
+	return 0
+}
+
+type BindSessionRequest struct {
+	Uid                  string         `protobuf:"bytes,1,opt,name=uid,proto3" json:"uid,omitempty"`
+	Record               *SessionRecord `protobuf:"bytes,2,opt,name=record,proto3" json:"record,omitempty"`
+	MaxSessions          int32          `protobuf:"varint,3,opt,name=maxSessions,proto3" json:"maxSessions,omitempty"`
+	RejectNew            bool           `protobuf:"varint,4,opt,name=rejectNew,proto3" json:"rejectNew,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *BindSessionRequest) Reset()         { *m = BindSessionRequest{} }
+func (m *BindSessionRequest) String() string { return proto.CompactTextString(m) }
+func (*BindSessionRequest) ProtoMessage()    {}
+func (*BindSessionRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_3cfb3b8ec240c376, []int{6}
+}
+
+func (m *BindSessionRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BindSessionRequest.Unmarshal(m, b)
+}
+func (m *BindSessionRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BindSessionRequest.Marshal(b, m, deterministic)
+}
+func (m *BindSessionRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BindSessionRequest.Merge(m, src)
+}
+func (m *BindSessionRequest) XXX_Size() int {
+	return xxx_messageInfo_BindSessionRequest.Size(m)
+}
+func (m *BindSessionRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_BindSessionRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BindSessionRequest proto.InternalMessageInfo
+
+func (m *BindSessionRequest) GetUid() string {
+	if m != nil {
+		return m.Uid
+	}
+	return ""
+}
+
+func (m *BindSessionRequest) GetRecord() *SessionRecord {
+	if m != nil {
+		return m.Record
+	}
+	return nil
+}
+
+func (m *BindSessionRequest) GetMaxSessions() int32 {
+	if m != nil {
+		return m.MaxSessions
+	}
+	return 0
+}
+
+func (m *BindSessionRequest) GetRejectNew() bool {
+	if m != nil {
+		return m.RejectNew
+	}
+	return false
+}
+
+type BindSessionResponse struct {
+	Rejected             bool             `protobuf:"varint,1,opt,name=rejected,proto3" json:"rejected,omitempty"`
+	Kicked               []*SessionRecord `protobuf:"bytes,2,rep,name=kicked,proto3" json:"kicked,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *BindSessionResponse) Reset()         { *m = BindSessionResponse{} }
+func (m *BindSessionResponse) String() string { return proto.CompactTextString(m) }
+func (*BindSessionResponse) ProtoMessage()    {}
+func (*BindSessionResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_3cfb3b8ec240c376, []int{7}
+}
+
+func (m *BindSessionResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BindSessionResponse.Unmarshal(m, b)
+}
+func (m *BindSessionResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BindSessionResponse.Marshal(b, m, deterministic)
+}
+func (m *BindSessionResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BindSessionResponse.Merge(m, src)
+}
+func (m *BindSessionResponse) XXX_Size() int {
+	return xxx_messageInfo_BindSessionResponse.Size(m)
+}
+func (m *BindSessionResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_BindSessionResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BindSessionResponse proto.InternalMessageInfo
+
+func (m *BindSessionResponse) GetRejected() bool {
+	if m != nil {
+		return m.Rejected
+	}
+	return false
+}
+
+func (m *BindSessionResponse) GetKicked() []*SessionRecord {
+	if m != nil {
+		return m.Kicked
+	}
+	return nil
+}
+
+type UnbindSessionRequest struct {
+	Uid                  string         `protobuf:"bytes,1,opt,name=uid,proto3" json:"uid,omitempty"`
+	Record               *SessionRecord `protobuf:"bytes,2,opt,name=record,proto3" json:"record,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *UnbindSessionRequest) Reset()         { *m = UnbindSessionRequest{} }
+func (m *UnbindSessionRequest) String() string { return proto.CompactTextString(m) }
+func (*UnbindSessionRequest) ProtoMessage()    {}
+func (*UnbindSessionRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_3cfb3b8ec240c376, []int{8}
+}
+
+func (m *UnbindSessionRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UnbindSessionRequest.Unmarshal(m, b)
+}
+func (m *UnbindSessionRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UnbindSessionRequest.Marshal(b, m, deterministic)
+}
+func (m *UnbindSessionRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UnbindSessionRequest.Merge(m, src)
+}
+func (m *UnbindSessionRequest) XXX_Size() int {
+	return xxx_messageInfo_UnbindSessionRequest.Size(m)
+}
+func (m *UnbindSessionRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_UnbindSessionRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UnbindSessionRequest proto.InternalMessageInfo
+
+func (m *UnbindSessionRequest) GetUid() string {
+	if m != nil {
+		return m.Uid
+	}
+	return ""
+}
+
+func (m *UnbindSessionRequest) GetRecord() *SessionRecord {
+	if m != nil {
+		return m.Record
+	}
+	return nil
+}
+
+type UnbindSessionResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UnbindSessionResponse) Reset()         { *m = UnbindSessionResponse{} }
+func (m *UnbindSessionResponse) String() string { return proto.CompactTextString(m) }
+func (*UnbindSessionResponse) ProtoMessage()    {}
+func (*UnbindSessionResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_3cfb3b8ec240c376, []int{9}
+}
+
+func (m *UnbindSessionResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UnbindSessionResponse.Unmarshal(m, b)
+}
+func (m *UnbindSessionResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UnbindSessionResponse.Marshal(b, m, deterministic)
+}
+func (m *UnbindSessionResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UnbindSessionResponse.Merge(m, src)
+}
+func (m *UnbindSessionResponse) XXX_Size() int {
+	return xxx_messageInfo_UnbindSessionResponse.Size(m)
+}
+func (m *UnbindSessionResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_UnbindSessionResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UnbindSessionResponse proto.InternalMessageInfo
+
+type FindSessionRequest struct {
+	Uid                  string   `protobuf:"bytes,1,opt,name=uid,proto3" json:"uid,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FindSessionRequest) Reset()         { *m = FindSessionRequest{} }
+func (m *FindSessionRequest) String() string { return proto.CompactTextString(m) }
+func (*FindSessionRequest) ProtoMessage()    {}
+func (*FindSessionRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_3cfb3b8ec240c376, []int{10}
+}
+
+func (m *FindSessionRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FindSessionRequest.Unmarshal(m, b)
+}
+func (m *FindSessionRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FindSessionRequest.Marshal(b, m, deterministic)
+}
+func (m *FindSessionRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FindSessionRequest.Merge(m, src)
+}
+func (m *FindSessionRequest) XXX_Size() int {
+	return xxx_messageInfo_FindSessionRequest.Size(m)
+}
+func (m *FindSessionRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_FindSessionRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FindSessionRequest proto.InternalMessageInfo
+
+func (m *FindSessionRequest) GetUid() string {
+	if m != nil {
+		return m.Uid
+	}
+	return ""
+}
+
+type FindSessionResponse struct {
+	Records              []*SessionRecord `protobuf:"bytes,1,rep,name=records,proto3" json:"records,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *FindSessionResponse) Reset()         { *m = FindSessionResponse{} }
+func (m *FindSessionResponse) String() string { return proto.CompactTextString(m) }
+func (*FindSessionResponse) ProtoMessage()    {}
+func (*FindSessionResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_3cfb3b8ec240c376, []int{11}
+}
+
+func (m *FindSessionResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FindSessionResponse.Unmarshal(m, b)
+}
+func (m *FindSessionResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FindSessionResponse.Marshal(b, m, deterministic)
+}
+func (m *FindSessionResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FindSessionResponse.Merge(m, src)
+}
+func (m *FindSessionResponse) XXX_Size() int {
+	return xxx_messageInfo_FindSessionResponse.Size(m)
+}
+func (m *FindSessionResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_FindSessionResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FindSessionResponse proto.InternalMessageInfo
+
+func (m *FindSessionResponse) GetRecords() []*SessionRecord {
+	if m != nil {
+		return m.Records
+	}
+	return nil
+}
+
+type RequestMessage struct {
+	GateAddr             string            `protobuf:"bytes,1,opt,name=gateAddr,proto3" json:"gateAddr,omitempty"`
+	SessionId            int64             `protobuf:"varint,2,opt,name=sessionId,proto3" json:"sessionId,omitempty"`
+	Id                   uint64            `protobuf:"varint,3,opt,name=id,proto3" json:"id,omitempty"`
+	Route                string            `protobuf:"bytes,4,opt,name=route,proto3" json:"route,omitempty"`
+	Data                 []byte            `protobuf:"bytes,5,opt,name=data,proto3" json:"data,omitempty"`
+	Uid                  string            `protobuf:"bytes,6,opt,name=uid,proto3" json:"uid,omitempty"`
+	Metadata             map[string][]byte `protobuf:"bytes,7,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Claims               []byte            `protobuf:"bytes,8,opt,name=claims,proto3" json:"claims,omitempty"`
+	ExpiresAt            int64             `protobuf:"varint,9,opt,name=expiresAt,proto3" json:"expiresAt,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
 func (m *RequestMessage) Reset()         { *m = RequestMessage{} }
 func (m *RequestMessage) String() string { return proto.CompactTextString(m) }
 func (*RequestMessage) ProtoMessage()    {}
 func (*RequestMessage) Descriptor() ([]byte, []int) {
-	return fileDescriptor_3cfb3b8ec240c376, []int{5}
+	return fileDescriptor_3cfb3b8ec240c376, []int{12}
 }
 
 func (m *RequestMessage) XXX_Unmarshal(b []byte) error {
@@ -298,21 +623,53 @@ func (m *RequestMessage) GetData() []byte {
 	return nil
 }
 
+func (m *RequestMessage) GetUid() string {
+	if m != nil {
+		return m.Uid
+	}
+	return ""
+}
+
+func (m *RequestMessage) GetMetadata() map[string][]byte {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+func (m *RequestMessage) GetClaims() []byte {
+	if m != nil {
+		return m.Claims
+	}
+	return nil
+}
+
+func (m *RequestMessage) GetExpiresAt() int64 {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return 0
+}
+
 type NotifyMessage struct {
-	GateAddr             string   `protobuf:"bytes,1,opt,name=gateAddr,proto3" json:"gateAddr,omitempty"`
-	SessionId            int64    `protobuf:"varint,2,opt,name=sessionId,proto3" json:"sessionId,omitempty"`
-	Route                string   `protobuf:"bytes,3,opt,name=route,proto3" json:"route,omitempty"`
-	Data                 []byte   `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	GateAddr             string            `protobuf:"bytes,1,opt,name=gateAddr,proto3" json:"gateAddr,omitempty"`
+	SessionId            int64             `protobuf:"varint,2,opt,name=sessionId,proto3" json:"sessionId,omitempty"`
+	Route                string            `protobuf:"bytes,3,opt,name=route,proto3" json:"route,omitempty"`
+	Data                 []byte            `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	Uid                  string            `protobuf:"bytes,5,opt,name=uid,proto3" json:"uid,omitempty"`
+	Metadata             map[string][]byte `protobuf:"bytes,6,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Claims               []byte            `protobuf:"bytes,7,opt,name=claims,proto3" json:"claims,omitempty"`
+	ExpiresAt            int64             `protobuf:"varint,8,opt,name=expiresAt,proto3" json:"expiresAt,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
 }
 
 func (m *NotifyMessage) Reset()         { *m = NotifyMessage{} }
 func (m *NotifyMessage) String() string { return proto.CompactTextString(m) }
 func (*NotifyMessage) ProtoMessage()    {}
 func (*NotifyMessage) Descriptor() ([]byte, []int) {
-	return fileDescriptor_3cfb3b8ec240c376, []int{6}
+	return fileDescriptor_3cfb3b8ec240c376, []int{13}
 }
 
 func (m *NotifyMessage) XXX_Unmarshal(b []byte) error {
@@ -361,6 +718,34 @@ func (m *NotifyMessage) GetData() []byte {
 	return nil
 }
 
+func (m *NotifyMessage) GetUid() string {
+	if m != nil {
+		return m.Uid
+	}
+	return ""
+}
+
+func (m *NotifyMessage) GetMetadata() map[string][]byte {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+func (m *NotifyMessage) GetClaims() []byte {
+	if m != nil {
+		return m.Claims
+	}
+	return nil
+}
+
+func (m *NotifyMessage) GetExpiresAt() int64 {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return 0
+}
+
 type ResponseMessage struct {
 	SessionId            int64    `protobuf:"varint,1,opt,name=sessionId,proto3" json:"sessionId,omitempty"`
 	Id                   uint64   `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"`
@@ -374,7 +759,7 @@ func (m *ResponseMessage) Reset()         { *m = ResponseMessage{} }
 func (m *ResponseMessage) String() string { return proto.CompactTextString(m) }
 func (*ResponseMessage) ProtoMessage()    {}
 func (*ResponseMessage) Descriptor() ([]byte, []int) {
-	return fileDescriptor_3cfb3b8ec240c376, []int{7}
+	return fileDescriptor_3cfb3b8ec240c376, []int{14}
 }
 
 func (m *ResponseMessage) XXX_Unmarshal(b []byte) error {
@@ -429,7 +814,7 @@ func (m *PushMessage) Reset()         { *m = PushMessage{} }
 func (m *PushMessage) String() string { return proto.CompactTextString(m) }
 func (*PushMessage) ProtoMessage()    {}
 func (*PushMessage) Descriptor() ([]byte, []int) {
-	return fileDescriptor_3cfb3b8ec240c376, []int{8}
+	return fileDescriptor_3cfb3b8ec240c376, []int{15}
 }
 
 func (m *PushMessage) XXX_Unmarshal(b []byte) error {
@@ -481,7 +866,7 @@ func (m *MemberHandleResponse) Reset()         { *m = MemberHandleResponse{} }
 func (m *MemberHandleResponse) String() string { return proto.CompactTextString(m) }
 func (*MemberHandleResponse) ProtoMessage()    {}
 func (*MemberHandleResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_3cfb3b8ec240c376, []int{9}
+	return fileDescriptor_3cfb3b8ec240c376, []int{16}
 }
 
 func (m *MemberHandleResponse) XXX_Unmarshal(b []byte) error {
@@ -513,7 +898,7 @@ func (m *NewMemberRequest) Reset()         { *m = NewMemberRequest{} }
 func (m *NewMemberRequest) String() string { return proto.CompactTextString(m) }
 func (*NewMemberRequest) ProtoMessage()    {}
 func (*NewMemberRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_3cfb3b8ec240c376, []int{10}
+	return fileDescriptor_3cfb3b8ec240c376, []int{17}
 }
 
 func (m *NewMemberRequest) XXX_Unmarshal(b []byte) error {
@@ -551,7 +936,7 @@ func (m *NewMemberResponse) Reset()         { *m = NewMemberResponse{} }
 func (m *NewMemberResponse) String() string { return proto.CompactTextString(m) }
 func (*NewMemberResponse) ProtoMessage()    {}
 func (*NewMemberResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_3cfb3b8ec240c376, []int{11}
+	return fileDescriptor_3cfb3b8ec240c376, []int{18}
 }
 
 func (m *NewMemberResponse) XXX_Unmarshal(b []byte) error {
@@ -583,7 +968,7 @@ func (m *DelMemberRequest) Reset()         { *m = DelMemberRequest{} }
 func (m *DelMemberRequest) String() string { return proto.CompactTextString(m) }
 func (*DelMemberRequest) ProtoMessage()    {}
 func (*DelMemberRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_3cfb3b8ec240c376, []int{12}
+	return fileDescriptor_3cfb3b8ec240c376, []int{19}
 }
 
 func (m *DelMemberRequest) XXX_Unmarshal(b []byte) error {
@@ -621,7 +1006,7 @@ func (m *DelMemberResponse) Reset()         { *m = DelMemberResponse{} }
 func (m *DelMemberResponse) String() string { return proto.CompactTextString(m) }
 func (*DelMemberResponse) ProtoMessage()    {}
 func (*DelMemberResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_3cfb3b8ec240c376, []int{13}
+	return fileDescriptor_3cfb3b8ec240c376, []int{20}
 }
 
 func (m *DelMemberResponse) XXX_Unmarshal(b []byte) error {
@@ -644,6 +1029,7 @@ var xxx_messageInfo_DelMemberResponse proto.InternalMessageInfo
 
 type SessionClosedRequest struct {
 	SessionId            int64    `protobuf:"varint,1,opt,name=sessionId,proto3" json:"sessionId,omitempty"`
+	Reason               int32    `protobuf:"varint,2,opt,name=reason,proto3" json:"reason,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -653,7 +1039,7 @@ func (m *SessionClosedRequest) Reset()         { *m = SessionClosedRequest{} }
 func (m *SessionClosedRequest) String() string { return proto.CompactTextString(m) }
 func (*SessionClosedRequest) ProtoMessage()    {}
 func (*SessionClosedRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_3cfb3b8ec240c376, []int{14}
+	return fileDescriptor_3cfb3b8ec240c376, []int{21}
 }
 
 func (m *SessionClosedRequest) XXX_Unmarshal(b []byte) error {
@@ -681,6 +1067,13 @@ func (m *SessionClosedRequest) GetSessionId() int64 {
 	return 0
 }
 
+func (m *SessionClosedRequest) GetReason() int32 {
+	if m != nil {
+		return m.Reason
+	}
+	return 0
+}
+
 type SessionClosedResponse struct {
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
@@ -691,7 +1084,7 @@ func (m *SessionClosedResponse) Reset()         { *m = SessionClosedResponse{} }
 func (m *SessionClosedResponse) String() string { return proto.CompactTextString(m) }
 func (*SessionClosedResponse) ProtoMessage()    {}
 func (*SessionClosedResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_3cfb3b8ec240c376, []int{15}
+	return fileDescriptor_3cfb3b8ec240c376, []int{22}
 }
 
 func (m *SessionClosedResponse) XXX_Unmarshal(b []byte) error {
@@ -714,6 +1107,7 @@ var xxx_messageInfo_SessionClosedResponse proto.InternalMessageInfo
 
 type CloseSessionRequest struct {
 	SessionId            int64    `protobuf:"varint,1,opt,name=sessionId,proto3" json:"sessionId,omitempty"`
+	KickReason           string   `protobuf:"bytes,2,opt,name=kickReason,proto3" json:"kickReason,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -723,7 +1117,7 @@ func (m *CloseSessionRequest) Reset()         { *m = CloseSessionRequest{} }
 func (m *CloseSessionRequest) String() string { return proto.CompactTextString(m) }
 func (*CloseSessionRequest) ProtoMessage()    {}
 func (*CloseSessionRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_3cfb3b8ec240c376, []int{16}
+	return fileDescriptor_3cfb3b8ec240c376, []int{23}
 }
 
 func (m *CloseSessionRequest) XXX_Unmarshal(b []byte) error {
@@ -751,6 +1145,13 @@ func (m *CloseSessionRequest) GetSessionId() int64 {
 	return 0
 }
 
+func (m *CloseSessionRequest) GetKickReason() string {
+	if m != nil {
+		return m.KickReason
+	}
+	return ""
+}
+
 type CloseSessionResponse struct {
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
@@ -761,7 +1162,7 @@ func (m *CloseSessionResponse) Reset()         { *m = CloseSessionResponse{} }
 func (m *CloseSessionResponse) String() string { return proto.CompactTextString(m) }
 func (*CloseSessionResponse) ProtoMessage()    {}
 func (*CloseSessionResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_3cfb3b8ec240c376, []int{17}
+	return fileDescriptor_3cfb3b8ec240c376, []int{24}
 }
 
 func (m *CloseSessionResponse) XXX_Unmarshal(b []byte) error {
@@ -782,14 +1183,258 @@ func (m *CloseSessionResponse) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_CloseSessionResponse proto.InternalMessageInfo
 
+type SyncSessionRequest struct {
+	SessionId            int64             `protobuf:"varint,1,opt,name=sessionId,proto3" json:"sessionId,omitempty"`
+	Uid                  string            `protobuf:"bytes,2,opt,name=uid,proto3" json:"uid,omitempty"`
+	Metadata             map[string][]byte `protobuf:"bytes,3,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *SyncSessionRequest) Reset()         { *m = SyncSessionRequest{} }
+func (m *SyncSessionRequest) String() string { return proto.CompactTextString(m) }
+func (*SyncSessionRequest) ProtoMessage()    {}
+func (*SyncSessionRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_3cfb3b8ec240c376, []int{25}
+}
+
+func (m *SyncSessionRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SyncSessionRequest.Unmarshal(m, b)
+}
+func (m *SyncSessionRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SyncSessionRequest.Marshal(b, m, deterministic)
+}
+func (m *SyncSessionRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SyncSessionRequest.Merge(m, src)
+}
+func (m *SyncSessionRequest) XXX_Size() int {
+	return xxx_messageInfo_SyncSessionRequest.Size(m)
+}
+func (m *SyncSessionRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SyncSessionRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SyncSessionRequest proto.InternalMessageInfo
+
+func (m *SyncSessionRequest) GetSessionId() int64 {
+	if m != nil {
+		return m.SessionId
+	}
+	return 0
+}
+
+func (m *SyncSessionRequest) GetUid() string {
+	if m != nil {
+		return m.Uid
+	}
+	return ""
+}
+
+func (m *SyncSessionRequest) GetMetadata() map[string][]byte {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+type SyncSessionResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SyncSessionResponse) Reset()         { *m = SyncSessionResponse{} }
+func (m *SyncSessionResponse) String() string { return proto.CompactTextString(m) }
+func (*SyncSessionResponse) ProtoMessage()    {}
+func (*SyncSessionResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_3cfb3b8ec240c376, []int{26}
+}
+
+func (m *SyncSessionResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SyncSessionResponse.Unmarshal(m, b)
+}
+func (m *SyncSessionResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SyncSessionResponse.Marshal(b, m, deterministic)
+}
+func (m *SyncSessionResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SyncSessionResponse.Merge(m, src)
+}
+func (m *SyncSessionResponse) XXX_Size() int {
+	return xxx_messageInfo_SyncSessionResponse.Size(m)
+}
+func (m *SyncSessionResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_SyncSessionResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SyncSessionResponse proto.InternalMessageInfo
+
+type SessionInfo struct {
+	Id                   int64             `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Uid                  string            `protobuf:"bytes,2,opt,name=uid,proto3" json:"uid,omitempty"`
+	RemoteAddr           string            `protobuf:"bytes,3,opt,name=remoteAddr,proto3" json:"remoteAddr,omitempty"`
+	LastActivity         int64             `protobuf:"varint,4,opt,name=lastActivity,proto3" json:"lastActivity,omitempty"`
+	Routes               map[string]string `protobuf:"bytes,5,rep,name=routes,proto3" json:"routes,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	QueueDepth           int32             `protobuf:"varint,6,opt,name=queueDepth,proto3" json:"queueDepth,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *SessionInfo) Reset()         { *m = SessionInfo{} }
+func (m *SessionInfo) String() string { return proto.CompactTextString(m) }
+func (*SessionInfo) ProtoMessage()    {}
+func (*SessionInfo) Descriptor() ([]byte, []int) {
+	return fileDescriptor_3cfb3b8ec240c376, []int{27}
+}
+
+func (m *SessionInfo) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SessionInfo.Unmarshal(m, b)
+}
+func (m *SessionInfo) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SessionInfo.Marshal(b, m, deterministic)
+}
+func (m *SessionInfo) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SessionInfo.Merge(m, src)
+}
+func (m *SessionInfo) XXX_Size() int {
+	return xxx_messageInfo_SessionInfo.Size(m)
+}
+func (m *SessionInfo) XXX_DiscardUnknown() {
+	xxx_messageInfo_SessionInfo.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SessionInfo proto.InternalMessageInfo
+
+func (m *SessionInfo) GetId() int64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *SessionInfo) GetUid() string {
+	if m != nil {
+		return m.Uid
+	}
+	return ""
+}
+
+func (m *SessionInfo) GetRemoteAddr() string {
+	if m != nil {
+		return m.RemoteAddr
+	}
+	return ""
+}
+
+func (m *SessionInfo) GetLastActivity() int64 {
+	if m != nil {
+		return m.LastActivity
+	}
+	return 0
+}
+
+func (m *SessionInfo) GetRoutes() map[string]string {
+	if m != nil {
+		return m.Routes
+	}
+	return nil
+}
+
+func (m *SessionInfo) GetQueueDepth() int32 {
+	if m != nil {
+		return m.QueueDepth
+	}
+	return 0
+}
+
+type ListSessionsRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListSessionsRequest) Reset()         { *m = ListSessionsRequest{} }
+func (m *ListSessionsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListSessionsRequest) ProtoMessage()    {}
+func (*ListSessionsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_3cfb3b8ec240c376, []int{28}
+}
+
+func (m *ListSessionsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListSessionsRequest.Unmarshal(m, b)
+}
+func (m *ListSessionsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListSessionsRequest.Marshal(b, m, deterministic)
+}
+func (m *ListSessionsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListSessionsRequest.Merge(m, src)
+}
+func (m *ListSessionsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListSessionsRequest.Size(m)
+}
+func (m *ListSessionsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListSessionsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListSessionsRequest proto.InternalMessageInfo
+
+type ListSessionsResponse struct {
+	Sessions             []*SessionInfo `protobuf:"bytes,1,rep,name=sessions,proto3" json:"sessions,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *ListSessionsResponse) Reset()         { *m = ListSessionsResponse{} }
+func (m *ListSessionsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListSessionsResponse) ProtoMessage()    {}
+func (*ListSessionsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_3cfb3b8ec240c376, []int{29}
+}
+
+func (m *ListSessionsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListSessionsResponse.Unmarshal(m, b)
+}
+func (m *ListSessionsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListSessionsResponse.Marshal(b, m, deterministic)
+}
+func (m *ListSessionsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListSessionsResponse.Merge(m, src)
+}
+func (m *ListSessionsResponse) XXX_Size() int {
+	return xxx_messageInfo_ListSessionsResponse.Size(m)
+}
+func (m *ListSessionsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListSessionsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListSessionsResponse proto.InternalMessageInfo
+
+func (m *ListSessionsResponse) GetSessions() []*SessionInfo {
+	if m != nil {
+		return m.Sessions
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*MemberInfo)(nil), "clusterpb.MemberInfo")
 	proto.RegisterType((*RegisterRequest)(nil), "clusterpb.RegisterRequest")
 	proto.RegisterType((*RegisterResponse)(nil), "clusterpb.RegisterResponse")
 	proto.RegisterType((*UnregisterRequest)(nil), "clusterpb.UnregisterRequest")
 	proto.RegisterType((*UnregisterResponse)(nil), "clusterpb.UnregisterResponse")
+	proto.RegisterType((*SessionRecord)(nil), "clusterpb.SessionRecord")
+	proto.RegisterType((*BindSessionRequest)(nil), "clusterpb.BindSessionRequest")
+	proto.RegisterType((*BindSessionResponse)(nil), "clusterpb.BindSessionResponse")
+	proto.RegisterType((*UnbindSessionRequest)(nil), "clusterpb.UnbindSessionRequest")
+	proto.RegisterType((*UnbindSessionResponse)(nil), "clusterpb.UnbindSessionResponse")
+	proto.RegisterType((*FindSessionRequest)(nil), "clusterpb.FindSessionRequest")
+	proto.RegisterType((*FindSessionResponse)(nil), "clusterpb.FindSessionResponse")
 	proto.RegisterType((*RequestMessage)(nil), "clusterpb.RequestMessage")
+	proto.RegisterMapType((map[string][]byte)(nil), "clusterpb.RequestMessage.MetadataEntry")
 	proto.RegisterType((*NotifyMessage)(nil), "clusterpb.NotifyMessage")
+	proto.RegisterMapType((map[string][]byte)(nil), "clusterpb.NotifyMessage.MetadataEntry")
 	proto.RegisterType((*ResponseMessage)(nil), "clusterpb.ResponseMessage")
 	proto.RegisterType((*PushMessage)(nil), "clusterpb.PushMessage")
 	proto.RegisterType((*MemberHandleResponse)(nil), "clusterpb.MemberHandleResponse")
@@ -801,49 +1446,90 @@ func init() {
 	proto.RegisterType((*SessionClosedResponse)(nil), "clusterpb.SessionClosedResponse")
 	proto.RegisterType((*CloseSessionRequest)(nil), "clusterpb.CloseSessionRequest")
 	proto.RegisterType((*CloseSessionResponse)(nil), "clusterpb.CloseSessionResponse")
+	proto.RegisterType((*SyncSessionRequest)(nil), "clusterpb.SyncSessionRequest")
+	proto.RegisterMapType((map[string][]byte)(nil), "clusterpb.SyncSessionRequest.MetadataEntry")
+	proto.RegisterType((*SyncSessionResponse)(nil), "clusterpb.SyncSessionResponse")
+	proto.RegisterType((*SessionInfo)(nil), "clusterpb.SessionInfo")
+	proto.RegisterMapType((map[string]string)(nil), "clusterpb.SessionInfo.RoutesEntry")
+	proto.RegisterType((*ListSessionsRequest)(nil), "clusterpb.ListSessionsRequest")
+	proto.RegisterType((*ListSessionsResponse)(nil), "clusterpb.ListSessionsResponse")
 }
 
 func init() { proto.RegisterFile("cluster.proto", fileDescriptor_3cfb3b8ec240c376) }
 
 var fileDescriptor_3cfb3b8ec240c376 = []byte{
-	// 591 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x95, 0x5d, 0x73, 0xd2, 0x4c,
-	0x14, 0xc7, 0x09, 0xa1, 0x3c, 0xe5, 0xf0, 0xf2, 0xc0, 0x81, 0x62, 0x8c, 0xa8, 0x99, 0x5c, 0x71,
-	0x85, 0x33, 0xd4, 0x7e, 0x00, 0xa7, 0x3a, 0xc2, 0x38, 0x54, 0x4d, 0xed, 0xbd, 0xa1, 0xd9, 0x62,
-	0x66, 0x52, 0x82, 0xd9, 0xa0, 0xe3, 0xbd, 0x1f, 0xc3, 0x6f, 0xea, 0x8d, 0x93, 0x6c, 0xb2, 0x9c,
-	0x0d, 0x41, 0x99, 0xe9, 0x1d, 0xbb, 0x67, 0xcf, 0xef, 0xbc, 0xfe, 0x09, 0xb4, 0x6f, 0x83, 0x2d,
-	0x8f, 0x59, 0x34, 0xd9, 0x44, 0x61, 0x1c, 0x62, 0x23, 0x3b, 0x6e, 0x96, 0xf6, 0x67, 0x80, 0x05,
-	0xbb, 0x5f, 0xb2, 0x68, 0xbe, 0xbe, 0x0b, 0x71, 0x00, 0x27, 0x81, 0xbb, 0x64, 0x81, 0xa1, 0x59,
-	0xda, 0xb8, 0xe1, 0x88, 0x03, 0x5a, 0xd0, 0xe4, 0x2c, 0xfa, 0xe6, 0xdf, 0xb2, 0x57, 0x9e, 0x17,
-	0x19, 0xd5, 0xd4, 0x46, 0xaf, 0xd0, 0x84, 0xd3, 0xec, 0xc8, 0x0d, 0xdd, 0xd2, 0xc7, 0x0d, 0x47,
-	0x9e, 0xed, 0x19, 0xfc, 0xef, 0xb0, 0x95, 0x9f, 0xc4, 0x73, 0xd8, 0xd7, 0x2d, 0xe3, 0x31, 0x5e,
-	0x00, 0xdc, 0xcb, 0xa0, 0x69, 0xac, 0xe6, 0xf4, 0x6c, 0x22, 0x93, 0x9a, 0xec, 0x32, 0x72, 0xc8,
-	0x43, 0xfb, 0x12, 0xba, 0x3b, 0x12, 0xdf, 0x84, 0x6b, 0xce, 0xf0, 0x05, 0xfc, 0x27, 0x5e, 0x70,
-	0x43, 0xb3, 0xf4, 0xc3, 0x9c, 0xfc, 0x95, 0x7d, 0x01, 0xbd, 0x9b, 0x75, 0x54, 0x48, 0xa8, 0x50,
-	0xa1, 0xb6, 0x57, 0xa1, 0x3d, 0x00, 0xa4, 0x6e, 0x22, 0xba, 0xfd, 0x53, 0x83, 0x4e, 0xc6, 0x58,
-	0x30, 0xce, 0xdd, 0x15, 0x4b, 0x5a, 0xb1, 0x72, 0x63, 0xca, 0x91, 0x67, 0x1c, 0x41, 0x83, 0x33,
-	0xce, 0xfd, 0x70, 0x3d, 0xf7, 0xd2, 0x36, 0xea, 0xce, 0xee, 0x02, 0x3b, 0x50, 0xf5, 0x3d, 0x43,
-	0xb7, 0xb4, 0x71, 0xcd, 0xa9, 0xfa, 0x5e, 0x32, 0x8c, 0x28, 0xdc, 0xc6, 0xcc, 0xa8, 0x89, 0x61,
-	0xa4, 0x07, 0x44, 0xa8, 0x79, 0x6e, 0xec, 0x1a, 0x27, 0x96, 0x36, 0x6e, 0x39, 0xe9, 0x6f, 0x9b,
-	0x43, 0xfb, 0x2a, 0x8c, 0xfd, 0xbb, 0x1f, 0x0f, 0x4f, 0x42, 0x06, 0xd5, 0xcb, 0x82, 0xd6, 0x48,
-	0xd0, 0xeb, 0x64, 0xae, 0xa2, 0x0f, 0x79, 0x58, 0x05, 0xad, 0x95, 0xd7, 0x57, 0x95, 0xf5, 0xe5,
-	0x50, 0x9d, 0x40, 0x6f, 0xa0, 0xf9, 0x61, 0xcb, 0xbf, 0x1c, 0x07, 0x94, 0xb9, 0x56, 0xcb, 0x72,
-	0xa5, 0xd8, 0x21, 0x0c, 0xc4, 0x2e, 0xcc, 0xdc, 0xb5, 0x17, 0x30, 0x39, 0xbf, 0x39, 0x74, 0xaf,
-	0xd8, 0x77, 0x61, 0x7a, 0xe0, 0x72, 0xf6, 0xa1, 0x47, 0x50, 0x19, 0xff, 0x25, 0x74, 0x5f, 0xb3,
-	0x40, 0xe5, 0xff, 0x7b, 0xd7, 0xfa, 0xd0, 0x23, 0x5e, 0x12, 0x35, 0xb8, 0x16, 0x95, 0x5f, 0x06,
-	0x21, 0x67, 0x5e, 0x8e, 0xfb, 0x6b, 0x8b, 0xec, 0x47, 0x70, 0x56, 0xf0, 0xca, 0x70, 0xe7, 0xd0,
-	0x4f, 0x6f, 0x32, 0xeb, 0x71, 0xb4, 0x21, 0x0c, 0x54, 0x27, 0x01, 0x9b, 0xfe, 0xd2, 0xa0, 0xbe,
-	0x70, 0x93, 0xfe, 0xe0, 0x1b, 0x38, 0xcd, 0x35, 0x8a, 0x26, 0xe9, 0x5a, 0xe1, 0x2f, 0xc0, 0x7c,
-	0x52, 0x6a, 0xcb, 0x92, 0xab, 0xe0, 0x3b, 0x80, 0x9d, 0xdc, 0x70, 0x44, 0x1e, 0xef, 0x89, 0xd7,
-	0x7c, 0x7a, 0xc0, 0x9a, 0xc3, 0xa6, 0xbf, 0x6b, 0x50, 0x17, 0xdd, 0xc4, 0x05, 0xb4, 0xf3, 0x15,
-	0x10, 0x05, 0x3f, 0x56, 0xf2, 0xa0, 0x4a, 0x36, 0x9f, 0xef, 0x0d, 0xbd, 0xb0, 0x3d, 0x49, 0x9a,
-	0x2d, 0x71, 0x27, 0xe4, 0x87, 0x06, 0x71, 0x51, 0x14, 0x79, 0x0c, 0xec, 0x2d, 0x80, 0xb8, 0x4b,
-	0x14, 0x80, 0x43, 0xe2, 0x40, 0x24, 0x71, 0x0c, 0xe8, 0x3d, 0x74, 0xd4, 0xbb, 0xc2, 0x24, 0x14,
-	0xd1, 0x1e, 0x03, 0x9c, 0x41, 0x43, 0xee, 0x36, 0xd2, 0xc9, 0x15, 0xc5, 0x63, 0x8e, 0xca, 0x8d,
-	0x94, 0x24, 0x57, 0x5b, 0x21, 0x15, 0x65, 0xa2, 0x90, 0xf6, 0xd5, 0x50, 0xc1, 0x4f, 0xd0, 0x56,
-	0x36, 0x1b, 0x69, 0x1d, 0x65, 0x4a, 0x31, 0xad, 0xc3, 0x0f, 0x24, 0xf5, 0x23, 0xb4, 0xe8, 0x86,
-	0xe3, 0x33, 0xe2, 0x53, 0xa2, 0x17, 0xa5, 0x79, 0x65, 0xd2, 0xb0, 0x2b, 0xcb, 0x7a, 0xfa, 0xcd,
-	0x3d, 0xff, 0x13, 0x00, 0x00, 0xff, 0xff, 0x80, 0x79, 0x99, 0xc0, 0x84, 0x07, 0x00, 0x00,
+	// 1121 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x57, 0xcd, 0x72, 0xe3, 0x44,
+	0x10, 0x46, 0x52, 0xec, 0xd8, 0xed, 0x38, 0x9b, 0x8c, 0x9d, 0x20, 0x44, 0x48, 0x5c, 0x3a, 0x2c,
+	0xa9, 0xa2, 0xca, 0x50, 0x86, 0xad, 0x82, 0xe5, 0x94, 0xcd, 0xae, 0x37, 0x81, 0x75, 0x80, 0x09,
+	0xb9, 0x70, 0x93, 0xad, 0xd9, 0xac, 0x88, 0x2d, 0x79, 0x35, 0x72, 0x76, 0xfd, 0x0a, 0x1c, 0x39,
+	0x71, 0xe2, 0x49, 0x78, 0x02, 0x0e, 0x3c, 0x13, 0x35, 0x3f, 0x92, 0x66, 0x64, 0x29, 0x6b, 0x08,
+	0xdc, 0x3c, 0x3d, 0xad, 0xaf, 0xbf, 0xfe, 0xa6, 0xa7, 0x7b, 0x0c, 0xed, 0xc9, 0x74, 0x41, 0x13,
+	0x12, 0xf7, 0xe7, 0x71, 0x94, 0x44, 0xa8, 0x29, 0x97, 0xf3, 0xb1, 0x9b, 0x00, 0x8c, 0xc8, 0x6c,
+	0x4c, 0xe2, 0xf3, 0xf0, 0x65, 0x84, 0xba, 0x50, 0x9b, 0x7a, 0x63, 0x32, 0xb5, 0x8d, 0x9e, 0x71,
+	0xdc, 0xc4, 0x62, 0x81, 0x7a, 0xd0, 0xa2, 0x24, 0xbe, 0x0d, 0x26, 0xe4, 0xc4, 0xf7, 0x63, 0xdb,
+	0xe4, 0x7b, 0xaa, 0x09, 0x39, 0xd0, 0x90, 0x4b, 0x6a, 0x5b, 0x3d, 0xeb, 0xb8, 0x89, 0xb3, 0x35,
+	0x42, 0xb0, 0x71, 0xed, 0x25, 0xc4, 0xde, 0xe8, 0x19, 0xc7, 0x0d, 0xcc, 0x7f, 0xbb, 0x67, 0xf0,
+	0x00, 0x93, 0xeb, 0x80, 0x71, 0xc0, 0xe4, 0xf5, 0x82, 0xd0, 0x04, 0x3d, 0x02, 0x98, 0x65, 0x44,
+	0x78, 0xfc, 0xd6, 0x60, 0xaf, 0x9f, 0x11, 0xed, 0xe7, 0x2c, 0xb1, 0xe2, 0xe8, 0x9e, 0xc2, 0x4e,
+	0x8e, 0x44, 0xe7, 0x51, 0x48, 0x09, 0xfa, 0x14, 0x36, 0x85, 0x07, 0xb5, 0x8d, 0x9e, 0x55, 0x8d,
+	0x93, 0x7a, 0xb9, 0x8f, 0x60, 0xf7, 0x2a, 0x8c, 0x0b, 0x84, 0x0a, 0x59, 0x1b, 0x2b, 0x59, 0xbb,
+	0x5d, 0x40, 0xea, 0x67, 0x22, 0xba, 0x7b, 0x0e, 0xed, 0x4b, 0x42, 0x69, 0x10, 0x85, 0x98, 0x4c,
+	0xa2, 0xd8, 0x67, 0xe2, 0xb0, 0xa4, 0x15, 0x94, 0x6c, 0x8d, 0x0e, 0xa0, 0x49, 0x85, 0xf3, 0xb9,
+	0xcf, 0x85, 0xb5, 0x70, 0x6e, 0x70, 0x7f, 0x33, 0x00, 0x3d, 0x09, 0x42, 0x3f, 0xc3, 0x13, 0xcc,
+	0x76, 0xc0, 0x5a, 0x04, 0xbe, 0xc4, 0x62, 0x3f, 0xd1, 0x67, 0x50, 0x8f, 0x79, 0x30, 0x8e, 0xd1,
+	0x1a, 0xd8, 0x4a, 0xc2, 0x1a, 0x19, 0x2c, 0xfd, 0x58, 0x76, 0x33, 0xef, 0xad, 0xdc, 0x63, 0x87,
+	0x66, 0x1c, 0xd7, 0xb0, 0x6a, 0x62, 0xd4, 0x62, 0xf2, 0x33, 0x99, 0x24, 0x17, 0xe4, 0x8d, 0x3c,
+	0xbc, 0xdc, 0xe0, 0x4e, 0xa0, 0xa3, 0x31, 0x93, 0xd2, 0x3b, 0xd0, 0x10, 0x3e, 0x44, 0xf0, 0x6b,
+	0xe0, 0x6c, 0xcd, 0x48, 0xde, 0x04, 0x93, 0x1b, 0xc2, 0x48, 0x5a, 0x77, 0x93, 0x14, 0x7e, 0xee,
+	0x4f, 0xd0, 0xbd, 0x0a, 0xc7, 0xff, 0x8b, 0x00, 0xee, 0xfb, 0xb0, 0x57, 0xc0, 0x96, 0xe7, 0xf7,
+	0x10, 0xd0, 0x70, 0x8d, 0x90, 0xee, 0x39, 0x74, 0x86, 0x25, 0x0a, 0x0c, 0x60, 0x53, 0x44, 0x48,
+	0x8b, 0xaf, 0x9a, 0x4a, 0xea, 0xe8, 0xfe, 0x65, 0xc2, 0xb6, 0x0c, 0x34, 0x22, 0x94, 0x7a, 0xd7,
+	0xe4, 0xdf, 0x17, 0x0d, 0xda, 0x06, 0x33, 0xf0, 0xf9, 0x81, 0x6e, 0x60, 0x33, 0xf0, 0xd9, 0x9d,
+	0x8e, 0xa3, 0x85, 0xbc, 0x80, 0x4d, 0x2c, 0x16, 0xec, 0x56, 0xfa, 0x5e, 0xe2, 0xd9, 0xb5, 0x9e,
+	0x71, 0xbc, 0x85, 0xf9, 0xef, 0x34, 0xc7, 0x7a, 0x2e, 0xeb, 0x29, 0x34, 0x66, 0x24, 0xf1, 0xb8,
+	0xe7, 0x26, 0xcf, 0xe6, 0x63, 0x25, 0x1b, 0x9d, 0x72, 0x7f, 0x24, 0x3d, 0x9f, 0x85, 0x49, 0xbc,
+	0xc4, 0xd9, 0x87, 0x68, 0x1f, 0xea, 0x93, 0xa9, 0x17, 0xcc, 0xa8, 0xdd, 0xe0, 0xc1, 0xe4, 0x8a,
+	0xa5, 0x41, 0xde, 0xce, 0x83, 0x98, 0xd0, 0x93, 0xc4, 0x6e, 0x8a, 0x34, 0x32, 0x83, 0xf3, 0x35,
+	0xb4, 0x35, 0x40, 0xc6, 0xee, 0x86, 0x2c, 0xd3, 0x13, 0xb8, 0x21, 0x4b, 0x96, 0xd9, 0xad, 0x37,
+	0x5d, 0x10, 0xae, 0xc1, 0x16, 0x16, 0x8b, 0xc7, 0xe6, 0x97, 0x86, 0xfb, 0x87, 0x09, 0xed, 0x8b,
+	0x28, 0x09, 0x5e, 0x2e, 0xef, 0xaf, 0x67, 0xa6, 0x9f, 0x55, 0xa6, 0xdf, 0xc6, 0xaa, 0x7e, 0xb5,
+	0x5c, 0xbf, 0x27, 0x8a, 0x7e, 0x75, 0xae, 0xdf, 0x43, 0x45, 0x3f, 0x8d, 0xe1, 0x1a, 0xf2, 0x6d,
+	0x56, 0xcb, 0xd7, 0xf8, 0x4f, 0xe5, 0xbb, 0x84, 0x07, 0x69, 0x3d, 0xa7, 0xfa, 0x69, 0x1a, 0x19,
+	0xe5, 0x35, 0x67, 0x66, 0x35, 0x97, 0xaa, 0x63, 0xe5, 0xea, 0xb8, 0x57, 0xd0, 0xfa, 0x7e, 0x41,
+	0x5f, 0xad, 0x07, 0x98, 0x89, 0x6e, 0x96, 0x89, 0xae, 0xc2, 0xee, 0x43, 0x57, 0xb4, 0xf4, 0x33,
+	0x2f, 0xf4, 0xa7, 0x44, 0x69, 0xc3, 0x3b, 0x17, 0xe4, 0x8d, 0xd8, 0xba, 0xe7, 0x8c, 0xe9, 0xc0,
+	0xae, 0x02, 0x25, 0xf1, 0xbf, 0x80, 0x9d, 0xa7, 0x64, 0xaa, 0xe3, 0xbf, 0x7b, 0x64, 0x74, 0x60,
+	0x57, 0xf9, 0x4a, 0x42, 0xbd, 0x80, 0xae, 0x6c, 0x0c, 0xa7, 0xd3, 0x88, 0x12, 0x3f, 0x85, 0xbb,
+	0x5b, 0xa2, 0x7d, 0xd6, 0xf2, 0x3c, 0x1a, 0x85, 0x5c, 0xa3, 0x1a, 0x96, 0x2b, 0xd6, 0xd8, 0x0a,
+	0x68, 0x32, 0xcc, 0x25, 0x74, 0xb8, 0xa5, 0xd0, 0xd9, 0xee, 0x8e, 0x72, 0x08, 0xc0, 0x9a, 0x31,
+	0xce, 0x23, 0x35, 0xb1, 0x62, 0x61, 0xf2, 0xeb, 0xa0, 0x32, 0xd8, 0x9f, 0x06, 0xa0, 0xcb, 0x65,
+	0x38, 0xf9, 0x47, 0xc1, 0xe4, 0x05, 0x32, 0xf3, 0x0b, 0xf4, 0x5c, 0xb9, 0x40, 0x16, 0xbf, 0x40,
+	0x9f, 0xa8, 0xed, 0x74, 0x25, 0x40, 0xd5, 0x2d, 0xba, 0xdf, 0x7d, 0xd8, 0x83, 0x8e, 0x16, 0x4a,
+	0xe6, 0xf8, 0x8b, 0x09, 0x2d, 0x69, 0xe3, 0xaf, 0x27, 0x71, 0x0b, 0x44, 0x56, 0x66, 0x50, 0x96,
+	0xce, 0x21, 0x40, 0x4c, 0x66, 0x91, 0xec, 0x43, 0xa2, 0xa1, 0x28, 0x16, 0xe4, 0xc2, 0xd6, 0xd4,
+	0xa3, 0xc9, 0xc9, 0x24, 0x09, 0x6e, 0x83, 0x64, 0xc9, 0xbb, 0x8b, 0x85, 0x35, 0x1b, 0x7a, 0x0c,
+	0x75, 0x7e, 0x1b, 0xa8, 0x5d, 0xe3, 0x82, 0xb8, 0xab, 0xf3, 0x85, 0xb1, 0xe9, 0x63, 0xee, 0x24,
+	0x74, 0x90, 0x5f, 0xb0, 0xf8, 0xaf, 0x17, 0x64, 0x41, 0x9e, 0x92, 0x79, 0xf2, 0x8a, 0x37, 0xfa,
+	0x1a, 0x56, 0x2c, 0xce, 0x57, 0xd0, 0x52, 0x3e, 0x7b, 0x97, 0x46, 0xcd, 0x82, 0x46, 0x2f, 0x02,
+	0x9a, 0x48, 0x06, 0x54, 0x9e, 0x87, 0xfb, 0x0d, 0x74, 0x75, 0x73, 0x36, 0x26, 0x1b, 0xf2, 0xdc,
+	0xd3, 0x39, 0xb9, 0x5f, 0x9e, 0x07, 0xce, 0xfc, 0x06, 0xbf, 0x5a, 0x50, 0x1f, 0x79, 0xcc, 0x05,
+	0x3d, 0x83, 0x46, 0xfa, 0xec, 0x43, 0x8e, 0x36, 0x92, 0xb4, 0x47, 0x9c, 0xf3, 0x61, 0xe9, 0x9e,
+	0x3c, 0xbf, 0xf7, 0xd0, 0xb7, 0x00, 0xf9, 0x0b, 0x0e, 0x1d, 0x28, 0xce, 0x2b, 0xef, 0x41, 0xe7,
+	0xa3, 0x8a, 0xdd, 0x0c, 0xec, 0x02, 0x5a, 0xca, 0x93, 0x08, 0xa9, 0xfe, 0xab, 0x8f, 0x38, 0xe7,
+	0xb0, 0x6a, 0x3b, 0xc3, 0xfb, 0x11, 0xda, 0xda, 0x0b, 0x05, 0x1d, 0x69, 0x0c, 0x56, 0xdf, 0x45,
+	0x4e, 0xaf, 0xda, 0x41, 0x65, 0x39, 0xac, 0x60, 0x39, 0xbc, 0x9b, 0xe5, 0xb0, 0x0c, 0x6f, 0xf0,
+	0x7b, 0x1d, 0xea, 0xa2, 0x9f, 0xa1, 0x11, 0xb4, 0xd3, 0x26, 0x2c, 0x6e, 0xfb, 0x07, 0x95, 0x8f,
+	0x05, 0xe7, 0x68, 0xa5, 0xed, 0x16, 0xfa, 0x37, 0x3b, 0x9c, 0x2d, 0x61, 0x13, 0x73, 0x12, 0xd9,
+	0x55, 0xa3, 0x73, 0x1d, 0xb0, 0xe7, 0x00, 0xc2, 0xc6, 0x66, 0x10, 0x52, 0x6b, 0x4d, 0x19, 0x4a,
+	0xeb, 0x00, 0x7d, 0x07, 0xdb, 0xba, 0xad, 0x50, 0x7f, 0xda, 0xd8, 0x5c, 0x07, 0xf0, 0x0c, 0x9a,
+	0xd9, 0x74, 0x41, 0x6a, 0xbd, 0x16, 0xc7, 0x97, 0x73, 0x50, 0xbe, 0xa9, 0x22, 0x65, 0xc3, 0x45,
+	0x43, 0x2a, 0x0e, 0x2a, 0xe7, 0xa0, 0x7c, 0x53, 0x2d, 0x3d, 0x6d, 0x86, 0x68, 0xa5, 0x57, 0x36,
+	0xab, 0x9c, 0x5e, 0xb5, 0x43, 0x86, 0xfa, 0x03, 0x6c, 0xa9, 0xb3, 0x02, 0xa9, 0xc5, 0x55, 0x32,
+	0x99, 0x9c, 0xa3, 0xca, 0x7d, 0xb5, 0x9a, 0x95, 0xce, 0xac, 0x55, 0xf3, 0xea, 0x70, 0x70, 0x0e,
+	0xab, 0xb6, 0x55, 0x8a, 0x6a, 0xbb, 0xd2, 0x28, 0x96, 0xb4, 0x37, 0xe7, 0xa8, 0x72, 0x3f, 0x85,
+	0x1c, 0xd7, 0xf9, 0x7f, 0xee, 0xcf, 0xff, 0x1e, 0x00, 0xe0, 0x71, 0xe5, 0x12, 0x84, 0x0f, 0x00,
+	0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -860,6 +1546,9 @@ const _ = grpc.SupportPackageIsVersion4
 type MasterClient interface {
 	Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*RegisterResponse, error)
 	Unregister(ctx context.Context, in *UnregisterRequest, opts ...grpc.CallOption) (*UnregisterResponse, error)
+	BindSession(ctx context.Context, in *BindSessionRequest, opts ...grpc.CallOption) (*BindSessionResponse, error)
+	UnbindSession(ctx context.Context, in *UnbindSessionRequest, opts ...grpc.CallOption) (*UnbindSessionResponse, error)
+	FindSession(ctx context.Context, in *FindSessionRequest, opts ...grpc.CallOption) (*FindSessionResponse, error)
 }
 
 type masterClient struct {
@@ -888,10 +1577,40 @@ func (c *masterClient) Unregister(ctx context.Context, in *UnregisterRequest, op
 	return out, nil
 }
 
+func (c *masterClient) BindSession(ctx context.Context, in *BindSessionRequest, opts ...grpc.CallOption) (*BindSessionResponse, error) {
+	out := new(BindSessionResponse)
+	err := c.cc.Invoke(ctx, "/clusterpb.Master/BindSession", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *masterClient) UnbindSession(ctx context.Context, in *UnbindSessionRequest, opts ...grpc.CallOption) (*UnbindSessionResponse, error) {
+	out := new(UnbindSessionResponse)
+	err := c.cc.Invoke(ctx, "/clusterpb.Master/UnbindSession", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *masterClient) FindSession(ctx context.Context, in *FindSessionRequest, opts ...grpc.CallOption) (*FindSessionResponse, error) {
+	out := new(FindSessionResponse)
+	err := c.cc.Invoke(ctx, "/clusterpb.Master/FindSession", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MasterServer is the server API for Master service.
 type MasterServer interface {
 	Register(context.Context, *RegisterRequest) (*RegisterResponse, error)
 	Unregister(context.Context, *UnregisterRequest) (*UnregisterResponse, error)
+	BindSession(context.Context, *BindSessionRequest) (*BindSessionResponse, error)
+	UnbindSession(context.Context, *UnbindSessionRequest) (*UnbindSessionResponse, error)
+	FindSession(context.Context, *FindSessionRequest) (*FindSessionResponse, error)
 }
 
 // UnimplementedMasterServer can be embedded to have forward compatible implementations.
@@ -904,6 +1623,15 @@ func (*UnimplementedMasterServer) Register(ctx context.Context, req *RegisterReq
 func (*UnimplementedMasterServer) Unregister(ctx context.Context, req *UnregisterRequest) (*UnregisterResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Unregister not implemented")
 }
+func (*UnimplementedMasterServer) BindSession(ctx context.Context, req *BindSessionRequest) (*BindSessionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BindSession not implemented")
+}
+func (*UnimplementedMasterServer) UnbindSession(ctx context.Context, req *UnbindSessionRequest) (*UnbindSessionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnbindSession not implemented")
+}
+func (*UnimplementedMasterServer) FindSession(ctx context.Context, req *FindSessionRequest) (*FindSessionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FindSession not implemented")
+}
 
 func RegisterMasterServer(s *grpc.Server, srv MasterServer) {
 	s.RegisterService(&_Master_serviceDesc, srv)
@@ -945,6 +1673,60 @@ func _Master_Unregister_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Master_BindSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BindSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServer).BindSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/clusterpb.Master/BindSession",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServer).BindSession(ctx, req.(*BindSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Master_UnbindSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnbindSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServer).UnbindSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/clusterpb.Master/UnbindSession",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServer).UnbindSession(ctx, req.(*UnbindSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Master_FindSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FindSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServer).FindSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/clusterpb.Master/FindSession",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServer).FindSession(ctx, req.(*FindSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Master_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "clusterpb.Master",
 	HandlerType: (*MasterServer)(nil),
@@ -957,6 +1739,18 @@ var _Master_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Unregister",
 			Handler:    _Master_Unregister_Handler,
 		},
+		{
+			MethodName: "BindSession",
+			Handler:    _Master_BindSession_Handler,
+		},
+		{
+			MethodName: "UnbindSession",
+			Handler:    _Master_UnbindSession_Handler,
+		},
+		{
+			MethodName: "FindSession",
+			Handler:    _Master_FindSession_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "cluster.proto",
@@ -974,6 +1768,8 @@ type MemberClient interface {
 	DelMember(ctx context.Context, in *DelMemberRequest, opts ...grpc.CallOption) (*DelMemberResponse, error)
 	SessionClosed(ctx context.Context, in *SessionClosedRequest, opts ...grpc.CallOption) (*SessionClosedResponse, error)
 	CloseSession(ctx context.Context, in *CloseSessionRequest, opts ...grpc.CallOption) (*CloseSessionResponse, error)
+	SyncSession(ctx context.Context, in *SyncSessionRequest, opts ...grpc.CallOption) (*SyncSessionResponse, error)
+	ListSessions(ctx context.Context, in *ListSessionsRequest, opts ...grpc.CallOption) (*ListSessionsResponse, error)
 }
 
 type memberClient struct {
@@ -1056,6 +1852,24 @@ func (c *memberClient) CloseSession(ctx context.Context, in *CloseSessionRequest
 	return out, nil
 }
 
+func (c *memberClient) SyncSession(ctx context.Context, in *SyncSessionRequest, opts ...grpc.CallOption) (*SyncSessionResponse, error) {
+	out := new(SyncSessionResponse)
+	err := c.cc.Invoke(ctx, "/clusterpb.Member/SyncSession", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *memberClient) ListSessions(ctx context.Context, in *ListSessionsRequest, opts ...grpc.CallOption) (*ListSessionsResponse, error) {
+	out := new(ListSessionsResponse)
+	err := c.cc.Invoke(ctx, "/clusterpb.Member/ListSessions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MemberServer is the server API for Member service.
 type MemberServer interface {
 	HandleRequest(context.Context, *RequestMessage) (*MemberHandleResponse, error)
@@ -1066,6 +1880,8 @@ type MemberServer interface {
 	DelMember(context.Context, *DelMemberRequest) (*DelMemberResponse, error)
 	SessionClosed(context.Context, *SessionClosedRequest) (*SessionClosedResponse, error)
 	CloseSession(context.Context, *CloseSessionRequest) (*CloseSessionResponse, error)
+	SyncSession(context.Context, *SyncSessionRequest) (*SyncSessionResponse, error)
+	ListSessions(context.Context, *ListSessionsRequest) (*ListSessionsResponse, error)
 }
 
 // UnimplementedMemberServer can be embedded to have forward compatible implementations.
@@ -1096,6 +1912,12 @@ func (*UnimplementedMemberServer) SessionClosed(ctx context.Context, req *Sessio
 func (*UnimplementedMemberServer) CloseSession(ctx context.Context, req *CloseSessionRequest) (*CloseSessionResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CloseSession not implemented")
 }
+func (*UnimplementedMemberServer) SyncSession(ctx context.Context, req *SyncSessionRequest) (*SyncSessionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SyncSession not implemented")
+}
+func (*UnimplementedMemberServer) ListSessions(ctx context.Context, req *ListSessionsRequest) (*ListSessionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSessions not implemented")
+}
 
 func RegisterMemberServer(s *grpc.Server, srv MemberServer) {
 	s.RegisterService(&_Member_serviceDesc, srv)
@@ -1245,6 +2067,42 @@ func _Member_CloseSession_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Member_SyncSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SyncSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MemberServer).SyncSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/clusterpb.Member/SyncSession",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MemberServer).SyncSession(ctx, req.(*SyncSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Member_ListSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSessionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MemberServer).ListSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/clusterpb.Member/ListSessions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MemberServer).ListSessions(ctx, req.(*ListSessionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Member_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "clusterpb.Member",
 	HandlerType: (*MemberServer)(nil),
@@ -1281,6 +2139,14 @@ var _Member_serviceDesc = grpc.ServiceDesc{
 			MethodName: "CloseSession",
 			Handler:    _Member_CloseSession_Handler,
 		},
+		{
+			MethodName: "SyncSession",
+			Handler:    _Member_SyncSession_Handler,
+		},
+		{
+			MethodName: "ListSessions",
+			Handler:    _Member_ListSessions_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "cluster.proto",
@@ -0,0 +1,43 @@
+package cluster
+
+import (
+	"sync/atomic"
+
+	"github.com/nano-kit/go-nano/internal/message"
+)
+
+// compressMessage returns a copy of m with the data compressed if the client
+// negotiated compression and the data is not smaller than the threshold,
+// otherwise m itself is returned
+func (a *agent) compressMessage(m *message.Message) *message.Message {
+	threshold := a.node.CompressThreshold
+	if threshold <= 0 || len(m.Data) < threshold || m.DataCompressed || atomic.LoadInt32(&a.compress) == 0 {
+		return m
+	}
+
+	data, err := message.Deflate(m.Data)
+	if err != nil || len(data) >= len(m.Data) {
+		return m
+	}
+	a.node.metrics.compressionBytes.With("raw").Add(float64(len(m.Data)))
+	a.node.metrics.compressionBytes.With("compressed").Add(float64(len(data)))
+
+	compressed := *m
+	compressed.Data = data
+	compressed.DataCompressed = true
+	return &compressed
+}
+
+// maxInflatedSize returns the maximum size of the compressed message data sent
+// by the client after decompression, zero if the client did not negotiate
+// compression. It is the maximum message size of the node, or the maximum
+// packet size if the fragments are rejected.
+func (a *agent) maxInflatedSize() int {
+	if atomic.LoadInt32(&a.compress) == 0 {
+		return 0
+	}
+	if size := a.node.maxMessageSize(); size > 0 {
+		return size
+	}
+	return a.node.maxPacketSize()
+}
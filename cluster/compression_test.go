@@ -0,0 +1,139 @@
+package cluster
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/nano-kit/go-nano/internal/message"
+	"github.com/nano-kit/go-nano/internal/packet"
+)
+
+func TestCompression(t *testing.T) {
+	n := newTestNode(t, Options{CompressThreshold: 64}, nil)
+	h := n.handler
+
+	for _, tc := range []struct {
+		handshake string
+		expect    string
+	}{
+		{`{"sys": {"compression": ["snappy", "deflate"]}}`, message.CompressionDeflate},
+		{`{"sys": {"compression": ["snappy"]}}`, ""},
+		{``, ""},
+	} {
+		a, client := newTestAgent(n)
+
+		go func() {
+			if err := h.processPacket(a, &packet.Packet{Type: packet.Handshake, Data: []byte(tc.handshake)}); err != nil {
+				t.Error(err)
+			}
+		}()
+		var resp struct {
+			Sys struct {
+				Compression string `json:"compression"`
+			} `json:"sys"`
+		}
+		if err := json.Unmarshal(readPacket(t, client).Data, &resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Sys.Compression != tc.expect {
+			t.Fatalf("%s: expect compression %q, got %q", tc.handshake, tc.expect, resp.Sys.Compression)
+		}
+
+		// the small messages are not compressed
+		data := bytes.Repeat([]byte("inventory "), 100)
+		for _, payload := range [][]byte{data, data[:32]} {
+			m := &message.Message{Type: message.Push, Route: "Inventory.Snapshot", Data: payload}
+			go func() {
+				if err := a.writeMessage(m); err != nil {
+					t.Error(err)
+				}
+			}()
+			p := readPacket(t, client)
+			compressed := len(p.Data) < len(payload)
+			if expect := tc.expect != "" && len(payload) >= n.CompressThreshold; compressed != expect {
+				t.Fatalf("%s: expect compressed %v for %d bytes, got %d bytes", tc.handshake, expect, len(payload), len(p.Data))
+			}
+			dm, err := message.DecodeInflated(p.Data, len(payload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dm.Route != m.Route || !bytes.Equal(dm.Data, payload) || m.DataCompressed {
+				t.Fatalf("unexpected message %+v", dm)
+			}
+		}
+
+		// the compressed data sent by the client is accepted only if negotiated
+		deflated, _ := message.Deflate(data)
+		em, _ := (&message.Message{Type: message.Notify, Route: "Room.Join", Data: deflated, DataCompressed: true}).Encode()
+		a.setStatus(statusWorking)
+		err := h.processPacket(a, &packet.Packet{Type: packet.Data, Data: em})
+		if accepted := err == nil; accepted != (tc.expect != "") {
+			t.Fatalf("%s: expect the compressed message accepted %v, got %v", tc.handshake, tc.expect != "", err)
+		}
+		a.conn.Close()
+		client.Close()
+	}
+}
+
+func TestWSCompression(t *testing.T) {
+	n := newTestNode(t, Options{CompressThreshold: 64}, nil)
+	h := n.handler
+	upgrader := websocket.Upgrader{EnableCompression: true}
+
+	for _, deflate := range []bool{true, false} {
+		conns := make(chan *wsConn, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			conns <- &wsConn{conn: conn, deflate: offersDeflate(r.Header)}
+		}))
+		dialer := websocket.Dialer{EnableCompression: deflate}
+		client, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a := newAgent(<-conns, n, h.pipeline, h.remoteProcess)
+
+		handshake := []byte(`{"sys": {"compression": ["deflate"]}}`)
+		if err := h.processPacket(a, &packet.Packet{Type: packet.Handshake, Data: handshake}); err != nil {
+			t.Fatal(err)
+		}
+		_, data, err := client.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if negotiated := bytes.Contains(data, []byte(message.CompressionDeflate)); negotiated == deflate {
+			t.Fatalf("permessage-deflate %v: unexpected handshake response %s", deflate, data)
+		}
+		client.Close()
+		a.conn.Close()
+		server.Close()
+	}
+}
+
+func TestMaxInflatedSize(t *testing.T) {
+	n := newTestNode(t, Options{CompressThreshold: 64, MaxPacketSize: 128, MaxMessageSize: 256}, nil)
+	a, client := newTestAgent(n)
+	defer client.Close()
+
+	if size := a.maxInflatedSize(); size != 0 {
+		t.Fatalf("expect 0 without compression, got %d", size)
+	}
+	atomic.StoreInt32(&a.compress, 1)
+	if size := a.maxInflatedSize(); size != 256 {
+		t.Fatalf("expect the maximum message size 256, got %d", size)
+	}
+	n.MaxMessageSize = -1
+	if size := a.maxInflatedSize(); size != 128 {
+		t.Fatalf("expect the maximum packet size 128 without fragments, got %d", size)
+	}
+}
@@ -28,7 +28,6 @@ import (
 	"time"
 
 	"github.com/nano-kit/go-nano/internal/env"
-	"github.com/nano-kit/go-nano/internal/log"
 	"google.golang.org/grpc"
 )
 
@@ -41,26 +40,27 @@ type rpcClient struct {
 	sync.RWMutex
 	isClosed bool
 	pools    map[string]*connPool
+	options  []grpc.DialOption
 }
 
-func newConnArray(maxSize uint, addr string) (*connPool, error) {
+func newConnArray(maxSize uint, addr string, options []grpc.DialOption) (*connPool, error) {
 	a := &connPool{
 		index: 0,
 		v:     make([]*grpc.ClientConn, maxSize),
 	}
-	if err := a.init(addr); err != nil {
+	if err := a.init(addr, options); err != nil {
 		return nil, err
 	}
 	return a, nil
 }
 
-func (a *connPool) init(addr string) error {
+func (a *connPool) init(addr string, options []grpc.DialOption) error {
 	for i := range a.v {
 		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 		conn, err := grpc.DialContext(
 			ctx,
 			addr,
-			env.GrpcOptions...,
+			options...,
 		)
 		cancel()
 		if err != nil {
@@ -88,9 +88,15 @@ func (a *connPool) Close() {
 	}
 }
 
-func newRPCClient() *rpcClient {
+// newRPCClient returns the client dialing the members with options, the
+// process-wide options of env are used if options is empty
+func newRPCClient(options []grpc.DialOption) *rpcClient {
+	if len(options) == 0 {
+		options = env.GrpcOptions
+	}
 	return &rpcClient{
-		pools: make(map[string]*connPool),
+		pools:   make(map[string]*connPool),
+		options: options,
 	}
 }
 
@@ -119,7 +125,7 @@ func (c *rpcClient) createConnPool(addr string) (*connPool, error) {
 	if !ok {
 		var err error
 		// TODO: make conn count configurable
-		array, err = newConnArray(1, addr)
+		array, err = newConnArray(1, addr, c.options)
 		if err != nil {
 			return nil, err
 		}
@@ -159,9 +165,7 @@ func (c *rpcClient) shrinkTo(addrs []string) {
 	for _, curr := range currs {
 		if !contains(addrs, curr) {
 			c.closePool(curr)
-			if env.Debug {
-				log.Print("close rpc client to", curr)
-			}
+			logger.Debug("close rpc client", "member", curr)
 		}
 	}
 }
@@ -30,4 +30,13 @@ var (
 	ErrInvalidRoute        = errors.New("invalid route")
 	ErrMemberNotRegistered = errors.New("member is not registered")
 	ErrRPC                 = errors.New("broken rpc")
+	ErrDuplicateLogin      = errors.New("uid has been bound to other sessions")
+	ErrNodeNotStarted      = errors.New("node is not started")
+	ErrUIDNotFound         = errors.New("uid is not bound to any session")
+	ErrTooManyConnections  = errors.New("too many connections")
+	ErrTooManyConnsFromIP  = errors.New("too many connections from the ip")
+	ErrAcceptRateExceeded  = errors.New("accept rate exceeded")
+	ErrAddressDenied       = errors.New("address denied")
+	ErrNodeShuttingDown    = errors.New("node is shutting down")
+	ErrReloadDisabled      = errors.New("configuration reload is disabled")
 )
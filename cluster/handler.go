@@ -24,55 +24,92 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"math/rand"
 	"net"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/nano-kit/go-nano/auth"
 	"github.com/nano-kit/go-nano/cluster/clusterpb"
 	"github.com/nano-kit/go-nano/component"
 	"github.com/nano-kit/go-nano/internal/codec"
-	"github.com/nano-kit/go-nano/internal/env"
-	"github.com/nano-kit/go-nano/internal/log"
 	"github.com/nano-kit/go-nano/internal/message"
 	"github.com/nano-kit/go-nano/internal/packet"
+	"github.com/nano-kit/go-nano/logging"
 	"github.com/nano-kit/go-nano/pipeline"
 	"github.com/nano-kit/go-nano/scheduler"
 	"github.com/nano-kit/go-nano/session"
+	"github.com/nano-kit/go-nano/trace"
 )
 
-var (
-	// cached serialized data
-	hrd []byte // handshake response data
-	hbd []byte // heartbeat packet data
-)
+// hbd is the cached heartbeat packet data
+var hbd []byte
+
+// logger writes the structured logs of package cluster
+var logger = logging.Get("cluster")
 
-type rpcHandler func(session *session.Session, msg *message.Message, noCopy bool) error
+type rpcHandler func(session *session.Session, msg *message.Message) error
+
+func init() {
+	var err error
+	hbd, err = codec.Encode(packet.Heartbeat, nil)
+	if err != nil {
+		panic(err)
+	}
+}
 
-func cache() {
+// handshakeResponse encodes the handshake response data which tells the
+// client the heartbeat interval and the negotiated protocol extensions
+func handshakeResponse(heartbeat time.Duration, neg negotiation) []byte {
+	sys := map[string]interface{}{"heartbeat": heartbeat.Seconds()}
+	if neg.Compression != "" {
+		sys["compression"] = neg.Compression
+	}
+	if neg.MaxPacketSize > 0 {
+		sys["maxPacketSize"] = neg.MaxPacketSize
+	}
 	data, err := json.Marshal(map[string]interface{}{
 		"code": 200,
-		"sys":  map[string]float64{"heartbeat": env.Heartbeat.Seconds()},
+		"sys":  sys,
 	})
 	if err != nil {
 		panic(err)
 	}
 
-	hrd, err = codec.Encode(packet.Handshake, data)
+	hrd, err := codec.Encode(packet.Handshake, data)
 	if err != nil {
 		panic(err)
 	}
+	return hrd
+}
 
-	hbd, err = codec.Encode(packet.Heartbeat, nil)
+// handshakeUnauthorized is the handshake response sent to the client whose
+// token is rejected, the connection is closed after it
+var handshakeUnauthorized = func() []byte {
+	data, err := json.Marshal(map[string]interface{}{
+		"code":    401,
+		"message": "unauthorized",
+	})
 	if err != nil {
 		panic(err)
 	}
-}
+	hrd, err := codec.Encode(packet.Handshake, data)
+	if err != nil {
+		panic(err)
+	}
+	return hrd
+}()
+
+// ingressStage inspects the message received by the gate before it is routed,
+// the message is dropped if the stage returns false
+type ingressStage func(agent *agent, msg *message.Message) bool
 
 // LocalHandler is the container for all local registered components
 type LocalHandler struct {
@@ -83,6 +120,7 @@ type LocalHandler struct {
 	remoteServices map[string][]*clusterpb.MemberInfo
 
 	pipeline    pipeline.Pipeline
+	ingress     []ingressStage // built-in stages run before the gate ingress pipeline
 	currentNode *Node
 }
 
@@ -95,6 +133,7 @@ func NewHandler(currentNode *Node, pipeline pipeline.Pipeline) *LocalHandler {
 		pipeline:       pipeline,
 		currentNode:    currentNode,
 	}
+	h.ingress = []ingressStage{currentNode.admitMessage, currentNode.rateLimit}
 
 	return h
 }
@@ -114,7 +153,7 @@ func (h *LocalHandler) register(comp component.Component, opts []component.Optio
 	h.localServices[s.Name] = s
 	for name, handler := range s.Handlers {
 		n := fmt.Sprintf("%s.%s", s.Name, name)
-		log.Print("register local handler", n)
+		logger.Info("register local handler", "route", n)
 		h.localHandlers[n] = handler
 	}
 	return nil
@@ -131,7 +170,7 @@ func (h *LocalHandler) addRemoteService(member *clusterpb.MemberInfo) {
 	defer h.mu.Unlock()
 
 	for _, s := range member.Services {
-		log.Printf("register remote service %s at node %s", s, member.ServiceAddr)
+		logger.Info("register remote service", "service", s, "member", member.ServiceAddr)
 		h.remoteServices[s] = append(h.remoteServices[s], member)
 	}
 }
@@ -170,11 +209,11 @@ func (h *LocalHandler) LocalService() []string {
 
 // CompInfo is the component information used by the node monitor
 type CompInfo struct {
-	Name         string
-	ReceiverType string
-	HandlerType  string
-	IsRawArg     bool
-	Scheduler    string
+	Name         string `json:"name"`
+	ReceiverType string `json:"receiverType"`
+	HandlerType  string `json:"handlerType"`
+	IsRawArg     bool   `json:"isRawArg"`
+	Scheduler    string `json:"scheduler"`
 }
 
 // Components show a sorted list of local components for the node monitor
@@ -211,7 +250,7 @@ func (h *LocalHandler) RemoteService() []string {
 
 // RemoteInfo is the remote component information used by the node monitor
 type RemoteInfo struct {
-	Name string
+	Name string `json:"name"`
 	*clusterpb.MemberInfo
 }
 
@@ -234,91 +273,138 @@ func (h *LocalHandler) Remotes() []RemoteInfo {
 
 func (h *LocalHandler) handle(conn net.Conn) {
 	// create a client agent and startup write gorontine
-	agent := newAgent(conn, h.pipeline, h.remoteProcess)
+	agent := newAgent(conn, h.currentNode, h.pipeline, h.remoteProcess)
+	h.currentNode.attachStore(agent.session)
 	h.currentNode.storeSession(agent.session)
+	h.currentNode.Scheduler.Run(func() { h.currentNode.Lifecycle.NotifyCreated(agent.session) })
 
 	// startup write goroutine
 	go agent.write()
 
-	if env.Debug {
-		log.Printf("new session established: %s", agent.String())
+	logger.Debug("new session established", "sid", agent.session.ID(), "remote", conn.RemoteAddr())
+
+	// close the connection which does not send handshake in time
+	if timeout := h.currentNode.HandshakeTimeout; timeout > 0 {
+		timer := time.AfterFunc(timeout, func() {
+			if agent.status() == statusStart {
+				logger.Info("handshake timeout", "sid", agent.session.ID(), "remote", conn.RemoteAddr())
+				agent.closeWith(session.CloseHandshakeTimeout)
+			}
+		})
+		defer timer.Stop()
 	}
 
 	// guarantee agent related resource be destroyed
+	reason := session.CloseUnknown
 	defer func() {
+		h.currentNode.admission.release(conn.RemoteAddr().String())
+		agent.closeWith(reason)
 		agent.notifySessionClosed(h.currentNode.rpcClient, h.currentNode.cluster.remoteAddrs())
+		h.currentNode.unbindSession(agent.session)
+		h.currentNode.flushSession(agent.session)
 		h.currentNode.removeSession(agent.session)
-		agent.Close()
-		if env.Debug {
-			log.Printf("session read goroutine exit, SessionID=%d, UID=%d", agent.session.ID(), agent.session.UID())
+		if l := h.currentNode.rateLimiter(); l != nil {
+			l.Remove(int64(agent.session.ID()))
 		}
+		logger.Debug("session read goroutine exit", "sid", agent.session.ID(), "uid", agent.session.UID())
 	}()
 
-	// read loop
-	buf := make([]byte, 2048)
+	// read loop, the packet is reused and its body is owned by processPacket
+	var p packet.Packet
 	for {
-		n, err := conn.Read(buf)
-		if err != nil {
-			if env.Debug {
-				log.Printf("read message error: %s, session will be closed immediately", err.Error())
+		if timeout := h.currentNode.readIdleTimeout(); timeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(timeout))
+		}
+		if err := agent.reader.Read(&p); err != nil {
+			logger.Debug("read message error, session will be closed immediately", "sid", agent.session.ID(), "err", err)
+			switch {
+			case err == io.EOF:
+				reason = session.CloseClientClosed
+			case isTimeout(err):
+				reason = session.CloseReadTimeout
+			case isDecodeError(err):
+				reason = session.CloseDecodeError
+			default:
+				reason = session.CloseReadError
 			}
 			return
 		}
 
-		// TODO(warning): decoder use slice for performance, packet data should be copy before next Decode
-		packets, err := agent.decoder.Decode(buf[:n])
-		if err != nil {
-			log.Print(err.Error())
+		if err := h.processPacket(agent, &p); err != nil {
+			logger.Error("process packet failed", "sid", agent.session.ID(), "err", err)
+			reason = closeReasonOf(err, session.CloseProtocolError)
 			return
 		}
-
-		if len(packets) < 1 {
-			continue
-		}
-
-		// process all packet
-		for i := range packets {
-			if err := h.processPacket(agent, packets[i]); err != nil {
-				log.Print(err.Error())
-				return
-			}
-		}
 	}
 }
 
+// isDecodeError decides whether err is caused by the invalid packets
+func isDecodeError(err error) bool {
+	return err == packet.ErrWrongPacketType || err == codec.ErrPacketSizeExceeded || err == codec.ErrMessageSizeExceeded
+}
+
+// processPacket processes the packet read by codec.Reader, the body is
+// released unless it is retained by the message dispatched to local handlers
 func (h *LocalHandler) processPacket(agent *agent, p *packet.Packet) error {
+	retained := false
+	defer func() {
+		if !retained {
+			codec.Release(p)
+		}
+	}()
+
 	switch p.Type {
 	case packet.Handshake:
-		if err := env.HandshakeValidator(p.Data); err != nil {
-			return err
+		if err := h.currentNode.handshakeValidator()(p.Data); err != nil {
+			h.currentNode.metrics.handshakes.With("invalid").Inc()
+			return withCloseReason(session.CloseProtocolError, err)
 		}
 
-		if _, err := agent.conn.Write(hrd); err != nil {
-			return err
+		if err := h.authenticate(agent, p.Data); err != nil {
+			h.currentNode.metrics.handshakes.With("auth_failed").Inc()
+			// tell the client the handshake is rejected before closing
+			agent.setWriteDeadline()
+			if _, werr := agent.conn.Write(handshakeUnauthorized); werr != nil {
+				logger.Debug("write handshake response failed", "sid", agent.session.ID(), "err", werr)
+			}
+			return withCloseReason(session.CloseAuthFailed, err)
 		}
+		h.currentNode.metrics.handshakes.With("ok").Inc()
 
-		agent.setStatus(statusHandshake)
-		if env.Debug {
-			log.Printf("session handshake Id=%d, Remote=%s", agent.session.ID(), agent.conn.RemoteAddr())
+		neg := h.currentNode.negotiate(p.Data)
+		if c, ok := agent.conn.(*wsConn); ok && c.deflate {
+			// the messages are compressed by permessage-deflate already
+			neg.Compression = ""
+		}
+		if neg.Compression != "" {
+			atomic.StoreInt32(&agent.compress, 1)
 		}
+		atomic.StoreInt32(&agent.fragment, int32(neg.MaxPacketSize))
+		agent.setWriteDeadline()
+		if _, err := agent.conn.Write(h.currentNode.handshakeData(neg)); err != nil {
+			return withCloseReason(writeCloseReason(err), err)
+		}
+
+		agent.setStatus(statusHandshake)
+		logger.Debug("session handshake", "sid", agent.session.ID(), "remote", agent.conn.RemoteAddr())
 
 	case packet.HandshakeAck:
 		agent.setStatus(statusWorking)
-		if env.Debug {
-			log.Printf("receive handshake ACK Id=%d, Remote=%s", agent.session.ID(), agent.conn.RemoteAddr())
-		}
+		h.currentNode.Scheduler.Run(func() { h.currentNode.Lifecycle.NotifyHandshake(agent.session) })
+		logger.Debug("receive handshake ACK", "sid", agent.session.ID(), "remote", agent.conn.RemoteAddr())
 
 	case packet.Data:
 		if agent.status() < statusWorking {
-			return fmt.Errorf("receive data on socket which not yet ACK, session will be closed immediately, remote=%s",
-				agent.conn.RemoteAddr().String())
+			return withCloseReason(session.CloseProtocolError, fmt.Errorf("receive data on socket which not yet ACK, session will be closed immediately, remote=%s",
+				agent.conn.RemoteAddr().String()))
 		}
 
-		msg, err := message.Decode(p.Data)
+		msg, err := h.currentNode.dict.DecodeInflated(p.Data, agent.maxInflatedSize())
 		if err != nil {
-			return err
+			return withCloseReason(session.CloseDecodeError, err)
 		}
-		h.processMessage(agent, msg)
+		atomic.StoreInt64(&agent.lastDataAt, time.Now().UnixNano())
+		retained = h.processMessage(agent, msg)
 
 	case packet.Heartbeat:
 	}
@@ -329,23 +415,56 @@ func (h *LocalHandler) processPacket(agent *agent, p *packet.Packet) error {
 	return nil
 }
 
+// authenticate verifies the token carried by the user data of handshake
+// request and binds the session if it is valid, the handshake without a
+// token is accepted and the session keeps unauthenticated.
+func (h *LocalHandler) authenticate(agent *agent, data []byte) error {
+	a := h.currentNode.Authenticator
+	if a == nil || len(data) == 0 {
+		return nil
+	}
+
+	var handshake struct {
+		User struct {
+			Token string `json:"token"`
+		} `json:"user"`
+	}
+	if err := json.Unmarshal(data, &handshake); err != nil {
+		logger.Warn("malformed handshake data, the session keeps unauthenticated",
+			"sid", agent.session.ID(), "remote", agent.conn.RemoteAddr().String(), "err", err)
+		return nil
+	}
+	if handshake.User.Token == "" {
+		return nil
+	}
+
+	id, err := auth.Login(agent.session, a, handshake.User.Token)
+	if err != nil {
+		return fmt.Errorf("authenticate failed: %v, remote=%s", err, agent.conn.RemoteAddr())
+	}
+	logger.Debug("session authenticated", "sid", agent.session.ID(), "uid", id.UID)
+	return nil
+}
+
 func (h *LocalHandler) findMembers(service string) []*clusterpb.MemberInfo {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 	return h.remoteServices[service]
 }
 
-func (h *LocalHandler) remoteProcess(session *session.Session, msg *message.Message, noCopy bool) error {
+// remoteProcess forwards the message to a member providing the service, the
+// message data is not retained after return
+func (h *LocalHandler) remoteProcess(session *session.Session, msg *message.Message) error {
 	index := strings.LastIndex(msg.Route, ".")
 	if index < 0 {
-		log.Printf("nano/handler: invalid route %s", msg.Route)
+		logger.Error("invalid route", "sid", session.ID(), "route", msg.Route)
 		return ErrInvalidRoute
 	}
 
 	service := msg.Route[:index]
 	members := h.findMembers(service)
 	if len(members) == 0 {
-		log.Printf("nano/handler: %s not found (forgot registered?)", msg.Route)
+		logger.Warn("route not found (forgot registered?)", "sid", session.ID(), "route", msg.Route)
 		return ErrMemberNotRegistered
 	}
 
@@ -361,14 +480,9 @@ func (h *LocalHandler) remoteProcess(session *session.Session, msg *message.Mess
 	}
 	pool, err := h.currentNode.rpcClient.getConnPool(remoteAddr)
 	if err != nil {
-		log.Print(err)
+		logger.Error("retrieve connection pool failed", "member", remoteAddr, "err", err)
 		return ErrRPC
 	}
-	var data = msg.Data
-	if !noCopy && len(msg.Data) > 0 {
-		data = make([]byte, len(msg.Data))
-		copy(data, msg.Data)
-	}
 
 	// Retrieve gate address and session id
 	gateAddr := h.currentNode.ServiceAddr
@@ -379,7 +493,15 @@ func (h *LocalHandler) remoteProcess(session *session.Session, msg *message.Mess
 		sessionID = v.sid
 	}
 
+	span := h.currentNode.Tracer.StartFrom(msg.Traceparent, "rpc")
+	span.SetAttribute("route", msg.Route)
+	span.SetAttribute("member", remoteAddr)
+	defer span.Finish()
+	ctx := withTraceparent(context.Background(), span.Traceparent())
+
 	client := clusterpb.NewMemberClient(pool.Get())
+	claims, expiresAt := encodeIdentity(session)
+	start := time.Now()
 	switch msg.Type {
 	case message.Request:
 		request := &clusterpb.RequestMessage{
@@ -387,26 +509,40 @@ func (h *LocalHandler) remoteProcess(session *session.Session, msg *message.Mess
 			SessionId: int64(sessionID),
 			Id:        msg.ID,
 			Route:     msg.Route,
-			Data:      data,
+			Data:      msg.Data,
+			Uid:       session.UID(),
+			Metadata:  encodeSessionData(session, h.currentNode.SyncKeys),
+			Claims:    claims,
+			ExpiresAt: expiresAt,
 		}
-		_, err = client.HandleRequest(context.Background(), request)
+		_, err = client.HandleRequest(ctx, request)
 	case message.Notify:
 		request := &clusterpb.NotifyMessage{
 			GateAddr:  gateAddr,
 			SessionId: int64(sessionID),
 			Route:     msg.Route,
-			Data:      data,
+			Data:      msg.Data,
+			Uid:       session.UID(),
+			Metadata:  encodeSessionData(session, h.currentNode.SyncKeys),
+			Claims:    claims,
+			ExpiresAt: expiresAt,
 		}
-		_, err = client.HandleNotify(context.Background(), request)
+		_, err = client.HandleNotify(ctx, request)
 	}
+	h.currentNode.metrics.forwardDuration.With(remoteAddr).ObserveSince(start)
 	if err != nil {
-		log.Printf("process remote message to %s error: %+v", msg.Route, err)
+		span.SetError(err)
+		logger.Error("process remote message failed", "sid", session.ID(), "uid", session.UID(),
+			"route", msg.Route, "member", remoteAddr, "err", err)
 		return ErrRPC
 	}
 	return nil
 }
 
-func (h *LocalHandler) processMessage(agent *agent, msg *message.Message) {
+// processMessage dispatches the message received by the gate, it returns true
+// if the message is retained by a local handler, otherwise the message has
+// been forwarded or dropped
+func (h *LocalHandler) processMessage(agent *agent, msg *message.Message) (retained bool) {
 	var lastMid uint64
 	switch msg.Type {
 	case message.Request:
@@ -414,98 +550,213 @@ func (h *LocalHandler) processMessage(agent *agent, msg *message.Message) {
 	case message.Notify:
 		lastMid = 0
 	default:
-		log.Print("invalid message type: " + msg.Type.String())
+		logger.Error("invalid message type", "sid", agent.session.ID(), "type", msg.Type.String())
 		return
 	}
 
+	for _, stage := range h.ingress {
+		if !stage(agent, msg) {
+			return
+		}
+	}
+
 	handler, found := h.localHandlers[msg.Route]
-	if !found {
-		h.remoteProcess(agent.session, msg, false)
-	} else {
-		h.localProcess(handler, lastMid, agent.session, msg)
+	h.currentNode.metrics.received.With(h.routeLabel(msg.Route, found), msg.Type.String()).Inc()
+
+	// the trace of the message starts at the gate
+	span := h.currentNode.Tracer.StartFrom(msg.Traceparent, "gate")
+	span.SetAttribute("route", msg.Route)
+	span.SetAttribute("session", strconv.FormatInt(int64(agent.session.ID()), 10))
+	msg.Traceparent = span.Traceparent()
+	defer span.Finish()
+
+	route := func() error {
+		// only whitelisted routes can be called before authentication
+		if h.currentNode.Authenticator != nil && !auth.IsAuthenticated(agent.session) &&
+			!h.currentNode.AuthWhitelist.Allow(msg.Route) {
+			logger.Info("reject unauthenticated message", "sid", agent.session.ID(), "route", msg.Route)
+			if msg.Type == message.Request {
+				resp := h.currentNode.AuthResponse
+				if resp == nil {
+					resp = auth.DefaultResponse
+				}
+				if err := agent.ResponseMid(msg.ID, resp); err != nil {
+					logger.Error("respond unauthenticated request failed", "sid", agent.session.ID(), "err", err)
+				}
+			}
+			return nil
+		}
+
+		if !found {
+			h.remoteProcess(agent.session, msg)
+		} else {
+			h.localProcess(handler, lastMid, agent.session, msg, nil)
+			retained = true
+		}
+		return nil
 	}
-}
 
-func (h *LocalHandler) handleWS(conn *websocket.Conn) {
-	c, err := newWSConn(conn)
-	if err != nil {
-		log.Print(err)
+	ingress := pipeline.StageOf(h.pipeline, pipeline.GateIngress)
+	if ingress == nil {
+		route()
+		return
+	}
+	if err := ingress.Process(agent.session, msg); err != nil {
+		logger.Error("gate ingress pipeline process failed", "sid", agent.session.ID(), "route", msg.Route, "err", err)
 		return
 	}
-	go h.handle(c)
+	ctx := pipeline.NewContext(agent.session, msg)
+	if found {
+		ctx.Handler = handler
+		if index := strings.LastIndex(msg.Route, "."); index > 0 {
+			ctx.Service = h.localServices[msg.Route[:index]]
+		}
+	}
+	if err := pipeline.Execute(ingress, ctx, route); err != nil {
+		logger.Error("gate ingress pipeline process failed", "sid", agent.session.ID(), "route", msg.Route, "err", err)
+	}
+	return
 }
 
-func (h *LocalHandler) localProcess(handler *component.Handler, lastMid uint64, session *session.Session, msg *message.Message) {
+// handleWS serves the upgraded WebSocket connection, the admission slot and
+// the connection are released by handle
+func (h *LocalHandler) handleWS(conn *websocket.Conn, deflate bool) {
+	go h.handle(newWSConn(conn, deflate))
+}
+
+// localProcess dispatches the message to the local handler, gate is the state
+// of the gate session sent with the message if it is forwarded by a gate
+func (h *LocalHandler) localProcess(handler *component.Handler, lastMid uint64, session *session.Session, msg *message.Message, gate *gateState) {
+	tracer := h.currentNode.Tracer
+	if tracer != nil && msg.Traceparent == "" {
+		// the message is not traced by the gate
+		root := tracer.Start(trace.SpanContext{}, "message")
+		root.SetAttribute("route", msg.Route)
+		msg.Traceparent = root.Traceparent()
+		defer root.Finish()
+	}
+
 	if pipe := h.pipeline; pipe != nil {
+		span := tracer.StartFrom(msg.Traceparent, "pipeline")
 		err := pipe.Inbound().Process(session, msg)
+		span.SetError(err)
+		span.Finish()
 		if err != nil {
-			log.Print("pipeline process failed: " + err.Error())
+			logger.Error("pipeline process failed", "sid", session.ID(), "route", msg.Route, "err", err)
 			return
 		}
 	}
 
+	// the message data is owned by the handler
 	var payload = msg.Data
 	var data interface{}
 	if handler.IsRawArg {
-		if len(payload) > 0 {
-			temp := make([]byte, len(payload))
-			copy(temp, payload)
-			payload = temp
-		}
 		data = payload
 	} else {
 		data = reflect.New(handler.Type.Elem()).Interface()
-		err := env.Serializer.Unmarshal(payload, data)
+		span := tracer.StartFrom(msg.Traceparent, "deserialize")
+		err := h.currentNode.serializer().Unmarshal(payload, data)
+		span.SetError(err)
+		span.Finish()
 		if err != nil {
-			log.Printf("deserialize to %T failed: %+v (%v)", data, err, payload)
+			h.currentNode.metrics.serializationErrors.With("unmarshal").Inc()
+			logger.Error("deserialize failed", "sid", session.ID(), "route", msg.Route,
+				"type", fmt.Sprintf("%T", data), "err", err)
 			return
 		}
 	}
 
-	if env.Debug {
-		log.Printf("UID=%d, Message={%s}, Data=%+v", session.UID(), msg.String(), data)
+	if logger.Enabled(logging.LevelDebug) {
+		logger.Debug("process message", "sid", session.ID(), "uid", session.UID(),
+			"message", msg.String(), "data", fmt.Sprintf("%+v", data))
 	}
 
+	index := strings.LastIndex(msg.Route, ".")
+	if index < 0 {
+		logger.Error("invalid route", "sid", session.ID(), "route", msg.Route)
+		return
+	}
+	svc := h.localServices[msg.Route[:index]]
+
 	args := []reflect.Value{handler.Receiver, reflect.ValueOf(session), reflect.ValueOf(data)}
+	call := func() error {
+		defer h.currentNode.metrics.handlerDuration.With(msg.Route).ObserveSince(time.Now())
+		result := handler.Method.Func.Call(args)
+		if len(result) > 0 {
+			if err := result[0].Interface(); err != nil {
+				return err.(error)
+			}
+		}
+		return nil
+	}
 	task := func() {
+		defer atomic.AddInt64(&h.currentNode.inflight, -1)
+
+		span := tracer.StartFrom(msg.Traceparent, "handler")
+		span.SetAttribute("route", msg.Route)
+		defer span.Finish()
+
+		var ac *acceptor
+		var tc *traceContext
+		// the messages sent by the handler are traced as the children of the
+		// span, the trace context is reset after the handler returns
 		switch v := session.NetworkEntity().(type) {
 		case *agent:
 			v.lastMid = lastMid
+			tc = &v.trace
 		case *acceptor:
 			v.lastMid = lastMid
+			tc = &v.trace
+			ac = v
+			// the gate state is applied after the earlier handlers of the
+			// session, whose changes may not be synchronized to the gate yet
+			if gate != nil {
+				h.currentNode.receiveSessionData(v, gate)
+			}
+		}
+		if tc != nil {
+			tc.set(span.Traceparent())
+			defer tc.set("")
 		}
 
-		result := handler.Method.Func.Call(args)
-		if len(result) > 0 {
-			if err := result[0].Interface(); err != nil {
-				log.Printf("service %s error: %+v", msg.Route, err)
-			}
+		var err error
+		if pipe := h.pipeline; pipe != nil {
+			ctx := pipeline.NewContext(session, msg)
+			ctx.Service, ctx.Handler, ctx.Data = svc, handler, data
+			err = pipeline.Execute(pipe.Inbound(), ctx, call)
+		} else {
+			err = call()
+		}
+		if err != nil {
+			span.SetError(err)
+			logger.Error("service failed", "sid", session.ID(), "uid", session.UID(), "route", msg.Route, "err", err)
 		}
-	}
 
-	index := strings.LastIndex(msg.Route, ".")
-	if index < 0 {
-		log.Printf("nano/handler: invalid route %s", msg.Route)
-		return
+		// synchronize the changes made by backend handler to the gate session,
+		// which are not sent with the response or pushes yet
+		if ac != nil {
+			go ac.flushSession()
+		}
 	}
 
 	// A message can be dispatch to global thread or a user customized thread
-	service := msg.Route[:index]
-	if s, found := h.localServices[service]; found && s.SchedName != "" {
-		sched := session.Value(s.SchedName)
+	if svc != nil && svc.SchedName != "" {
+		sched := session.Value(svc.SchedName)
 		if sched == nil {
-			log.Printf("nano/handler: cannot found `schedular.LocalScheduler` by %s", s.SchedName)
+			logger.Error("local scheduler not found", "sid", session.ID(), "key", svc.SchedName)
 			return
 		}
 
 		local, ok := sched.(scheduler.LocalScheduler)
 		if !ok {
-			log.Printf("nano/handler: Type %T does not implement the `schedular.LocalScheduler` interface",
-				sched)
+			logger.Error("value does not implement scheduler.LocalScheduler", "sid", session.ID(),
+				"key", svc.SchedName, "type", fmt.Sprintf("%T", sched))
 			return
 		}
+		atomic.AddInt64(&h.currentNode.inflight, 1)
 		local.Schedule(task)
 	} else {
-		scheduler.Run(task)
+		atomic.AddInt64(&h.currentNode.inflight, 1)
+		h.currentNode.Scheduler.Run(task)
 	}
 }
@@ -0,0 +1,99 @@
+package cluster
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/nano-kit/go-nano/auth"
+	"github.com/nano-kit/go-nano/internal/message"
+	"github.com/nano-kit/go-nano/internal/packet"
+	"github.com/nano-kit/go-nano/session"
+)
+
+func TestUnauthenticatedRequest(t *testing.T) {
+	n := newTestNode(t, Options{
+		Authenticator: auth.AuthenticatorFunc(func(string) (*auth.Identity, error) { return nil, auth.ErrMissingToken }),
+		AuthWhitelist: auth.Whitelist{"Room.Guest"},
+	}, nil)
+	h := n.handler
+	a, _ := newTestAgent(n)
+
+	h.processMessage(a, &message.Message{Type: message.Notify, Route: "Room.Join"})
+	if len(a.chSend) != 0 {
+		t.Fatalf("the notify should be dropped, got %d messages", len(a.chSend))
+	}
+	h.processMessage(a, &message.Message{Type: message.Request, ID: 3, Route: "Room.Join"})
+	if len(a.chSend) != 1 {
+		t.Fatalf("expect the unauthenticated response, got %d messages", len(a.chSend))
+	}
+	if m := <-a.chSend; m.typ != message.Response || m.mid != 3 || string(m.payload.([]byte)) != string(auth.DefaultResponse) {
+		t.Fatalf("unexpected response %+v", m)
+	}
+}
+
+func TestHandshakeAuthFailed(t *testing.T) {
+	n := newTestNode(t, Options{
+		Authenticator: auth.AuthenticatorFunc(func(string) (*auth.Identity, error) { return nil, auth.ErrMissingToken }),
+	}, nil)
+	a, client := newTestAgent(n)
+	defer client.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- n.handler.processPacket(a, &packet.Packet{Type: packet.Handshake, Data: []byte(`{"user":{"token":"bad"}}`)})
+	}()
+
+	p := readPacket(t, client)
+	var resp struct {
+		Code int `json:"code"`
+	}
+	if err := json.Unmarshal(p.Data, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if p.Type != packet.Handshake || resp.Code != 401 {
+		t.Fatalf("unexpected handshake response %v %s", p.Type, p.Data)
+	}
+	if err := <-done; closeReasonOf(err, session.CloseUnknown) != session.CloseAuthFailed {
+		t.Fatalf("expect auth failed, got %v", err)
+	}
+}
+
+func TestNodeSessionState(t *testing.T) {
+	dict := map[string]uint16{"Room.Join": 1}
+	n1 := newTestNode(t, Options{GateID: 1, Dictionary: dict}, nil)
+	n2 := newTestNode(t, Options{GateID: 2}, nil)
+	a1, c1 := newTestAgent(n1)
+	a2, c2 := newTestAgent(n2)
+	defer c1.Close()
+	defer c2.Close()
+
+	// the session IDs are generated by the gates
+	if sid := a1.session.ID(); sid>>32 != 1 {
+		t.Fatalf("expect the session ID of gate 1, got %v", sid)
+	}
+	if sid := a2.session.ID(); sid>>32 != 2 {
+		t.Fatalf("expect the session ID of gate 2, got %v", sid)
+	}
+
+	// the routes are compressed with the dictionary of the node
+	m := &message.Message{Type: message.Notify, Route: "Room.Join", Data: []byte(`{}`)}
+	em1, err := n1.dict.Encode(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	em2, err := n2.dict.Encode(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(em1) >= len(em2) {
+		t.Fatalf("expect the route compressed by node 1 only, got %d and %d bytes", len(em1), len(em2))
+	}
+	a1.setStatus(statusWorking)
+	if err := n1.handler.processPacket(a1, &packet.Packet{Type: packet.Data, Data: em1}); err != nil {
+		t.Fatal(err)
+	}
+	a2.setStatus(statusWorking)
+	if err := n2.handler.processPacket(a2, &packet.Packet{Type: packet.Data, Data: em1}); err == nil {
+		t.Fatal("expect the compressed route rejected by node 2")
+	}
+}
@@ -0,0 +1,75 @@
+package cluster
+
+import (
+	"encoding/json"
+
+	"github.com/nano-kit/go-nano/internal/codec"
+	"github.com/nano-kit/go-nano/internal/message"
+)
+
+// defaultMaxMessageSize is the maximum message reassembled from fragments if
+// Options.MaxMessageSize is not specified
+const defaultMaxMessageSize = 1 << 20
+
+// negotiation is the protocol extensions negotiated in handshake
+type negotiation struct {
+	Compression   string // the compression of message data
+	MaxPacketSize int    // the size of fragments, zero if the client does not support fragments
+}
+
+// negotiate returns the protocol extensions supported by both the node and the
+// client, which lists them in the handshake request, e.g.
+// {"sys": {"compression": ["deflate"], "fragment": true}}
+func (n *Node) negotiate(data []byte) negotiation {
+	var neg negotiation
+	if len(data) == 0 {
+		return neg
+	}
+
+	var handshake struct {
+		Sys struct {
+			Compression []string `json:"compression"`
+			Fragment    bool     `json:"fragment"`
+		} `json:"sys"`
+	}
+	if err := json.Unmarshal(data, &handshake); err != nil {
+		return neg
+	}
+	if n.CompressThreshold > 0 {
+		for _, c := range handshake.Sys.Compression {
+			if c == message.CompressionDeflate {
+				neg.Compression = c
+				break
+			}
+		}
+	}
+	if handshake.Sys.Fragment && n.maxMessageSize() > 0 {
+		neg.MaxPacketSize = n.maxPacketSize()
+	}
+	return neg
+}
+
+// maxPacketSize returns the maximum size of the packets read and written
+func (n *Node) maxPacketSize() int {
+	switch {
+	case n.MaxPacketSize <= 0:
+		return codec.MaxPacketSize
+	case n.MaxPacketSize > codec.MaxLength:
+		return codec.MaxLength
+	}
+	return n.MaxPacketSize
+}
+
+// maxMessageSize returns the maximum message reassembled from the fragments
+// of a session, zero if the fragments are rejected
+func (n *Node) maxMessageSize() int {
+	switch {
+	case n.MaxMessageSize == 0:
+		return defaultMaxMessageSize
+	case n.MaxMessageSize < 0:
+		return 0
+	case n.MaxMessageSize > codec.MaxLength:
+		return codec.MaxLength
+	}
+	return n.MaxMessageSize
+}
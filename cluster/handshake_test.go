@@ -0,0 +1,109 @@
+package cluster
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/nano-kit/go-nano/internal/codec"
+	"github.com/nano-kit/go-nano/internal/message"
+	"github.com/nano-kit/go-nano/internal/packet"
+)
+
+func TestFragments(t *testing.T) {
+	n := newTestNode(t, Options{MaxPacketSize: 32, MaxMessageSize: 256}, nil)
+	h := n.handler
+
+	for _, tc := range []struct {
+		handshake string
+		expect    int
+	}{
+		{`{"sys": {"fragment": true}}`, 32},
+		{`{"sys": {}}`, 0},
+	} {
+		a, client := newTestAgent(n)
+
+		go func() {
+			if err := h.processPacket(a, &packet.Packet{Type: packet.Handshake, Data: []byte(tc.handshake)}); err != nil {
+				t.Error(err)
+			}
+		}()
+		var resp struct {
+			Sys struct {
+				MaxPacketSize int `json:"maxPacketSize"`
+			} `json:"sys"`
+		}
+		if err := json.Unmarshal(readPacket(t, client).Data, &resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Sys.MaxPacketSize != tc.expect {
+			t.Fatalf("%s: expect max packet size %d, got %d", tc.handshake, tc.expect, resp.Sys.MaxPacketSize)
+		}
+
+		m := &message.Message{Type: message.Push, Route: "Inventory.Snapshot", Data: bytes.Repeat([]byte("item "), 40)}
+		go func() {
+			if err := a.writeMessage(m); err != nil {
+				t.Error(err)
+			}
+		}()
+		d := codec.NewDecoderWithLimits(codec.MaxPacketSize, 1024)
+		var packets []*packet.Packet
+		count := 0
+		for len(packets) == 0 {
+			p := readPacket(t, client)
+			count++
+			data, _ := codec.Encode(p.Type, p.Data)
+			ps, err := d.Decode(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			packets = append(packets, ps...)
+		}
+		expect := 1
+		if tc.expect > 0 {
+			expect = 7 // 220 bytes of message in 32 bytes packets
+		}
+		if count != expect {
+			t.Fatalf("%s: expect %d packets, got %d", tc.handshake, expect, count)
+		}
+		dm, err := message.Decode(packets[0].Data)
+		if err != nil || dm.Route != m.Route || !bytes.Equal(dm.Data, m.Data) {
+			t.Fatalf("unexpected message %+v, %v", dm, err)
+		}
+		a.conn.Close()
+		client.Close()
+	}
+
+	// the fragments received are limited per session
+	a, client := newTestAgent(n)
+	defer client.Close()
+	fragment, _ := codec.Encode(packet.Fragment, bytes.Repeat([]byte{0}, 32))
+	go func() {
+		for i := 0; i < 9; i++ {
+			if _, err := client.Write(fragment); err != nil {
+				return
+			}
+		}
+	}()
+	var p packet.Packet
+	if err := a.reader.Read(&p); err != codec.ErrMessageSizeExceeded {
+		t.Fatalf("expect %v, got %v", codec.ErrMessageSizeExceeded, err)
+	}
+	a.conn.Close()
+}
+
+func TestMaxMessageSize(t *testing.T) {
+	for _, tc := range []struct {
+		configured, expect int
+	}{
+		{0, defaultMaxMessageSize},
+		{-1, 0},
+		{256, 256},
+		{codec.MaxLength + 1, codec.MaxLength},
+	} {
+		n := &Node{Options: Options{MaxMessageSize: tc.configured}}
+		if size := n.maxMessageSize(); size != tc.expect {
+			t.Fatalf("expect %d for %d, got %d", tc.expect, tc.configured, size)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package cluster
+
+import (
+	"io"
+	"net"
+	"testing"
+
+	"github.com/nano-kit/go-nano/component"
+	"github.com/nano-kit/go-nano/internal/codec"
+	"github.com/nano-kit/go-nano/internal/packet"
+	"github.com/nano-kit/go-nano/pipeline"
+	"github.com/nano-kit/go-nano/service"
+	"github.com/nano-kit/go-nano/session"
+)
+
+type RoomComponent struct{ component.Base }
+
+func (r *RoomComponent) Join(s *session.Session, _ []byte) error { return nil }
+
+// newTestNode returns a node which is not started, its handler runs the
+// pipeline and serves the Room component
+func newTestNode(t *testing.T, opts Options, pipe pipeline.Pipeline) *Node {
+	t.Helper()
+	n := &Node{
+		Options:  opts,
+		registry: NewMemoryRegistry(),
+		sessions: map[service.SID]*session.Session{},
+	}
+	n.initSessionState()
+	n.metrics = newNodeMetrics(n)
+	n.handler = NewHandler(n, pipe)
+	if err := n.handler.register(&RoomComponent{}, []component.Option{component.WithName("Room")}); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// newTestAgent returns an agent of the node and the client side of its connection
+func newTestAgent(n *Node) (*agent, net.Conn) {
+	server, client := net.Pipe()
+	return newAgent(server, n, n.handler.pipeline, n.handler.remoteProcess), client
+}
+
+// readPacket reads a packet from the client side of the pipe
+func readPacket(t *testing.T, conn net.Conn) *packet.Packet {
+	t.Helper()
+	header := make([]byte, codec.HeadLength)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		t.Fatal(err)
+	}
+	body := make([]byte, int(header[1])<<16|int(header[2])<<8|int(header[3]))
+	if _, err := io.ReadFull(conn, body); err != nil {
+		t.Fatal(err)
+	}
+	return &packet.Packet{Type: packet.Type(header[0]), Length: len(body), Data: body}
+}
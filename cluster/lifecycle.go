@@ -0,0 +1,77 @@
+package cluster
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/nano-kit/go-nano/session"
+)
+
+// idleCheckInterval is the interval of checking the idle client sessions
+const idleCheckInterval = time.Second
+
+// CloseReasons returns the number of closed client sessions of the node by
+// reason, which helps to diagnose mass-disconnect incidents
+func (n *Node) CloseReasons() map[string]int64 {
+	values := n.metrics.closed.Values()
+	result := make(map[string]int64, len(values))
+	for reason, count := range values {
+		result[reason] = int64(count)
+	}
+	return result
+}
+
+// closeReasonError attaches the close reason to the error which terminates
+// the client session
+type closeReasonError struct {
+	reason session.CloseReason
+	error
+}
+
+func withCloseReason(reason session.CloseReason, err error) error {
+	return closeReasonError{reason: reason, error: err}
+}
+
+// closeReasonOf returns the reason attached to err, or def if there is none
+func closeReasonOf(err error, def session.CloseReason) session.CloseReason {
+	var re closeReasonError
+	if errors.As(err, &re) {
+		return re.reason
+	}
+	return def
+}
+
+// checkIdleSessions calls the OnIdle callbacks of the client sessions, which
+// is executed in the scheduler goroutine
+func (n *Node) checkIdleSessions() {
+	if !n.Lifecycle.HasIdle() {
+		return
+	}
+
+	now := time.Now().UnixNano()
+	for _, s := range n.Sessions() {
+		a, ok := s.NetworkEntity().(*agent)
+		if !ok || a.status() >= statusClosing {
+			continue
+		}
+		last := atomic.LoadInt64(&a.lastDataAt)
+		from := a.idleChecked
+		if last != a.idleCheckAt {
+			// the client has sent data since last check
+			from = 0
+		}
+		idle := time.Duration(now - last)
+		a.idleCheckAt, a.idleChecked = last, idle
+		n.Lifecycle.NotifyIdle(s, from, idle)
+	}
+}
+
+// closeSessions closes all client sessions connected to current node
+func (n *Node) closeSessions(reason session.CloseReason) {
+	for _, s := range n.Sessions() {
+		if a, ok := s.NetworkEntity().(*agent); ok {
+			a.closeWith(reason)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package cluster
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nano-kit/go-nano/session"
+)
+
+func TestAgentClosing(t *testing.T) {
+	lc := session.NewLifecycle()
+	closed := make(chan session.CloseReason, 1)
+	lc.OnClosing(func(s *session.Session, reason session.CloseReason) {
+		s.Push("bye", []byte("see you"))
+	})
+	lc.OnClosed(func(s *session.Session, reason session.CloseReason) {
+		closed <- reason
+	})
+
+	n := newTestNode(t, Options{Lifecycle: lc}, nil)
+	a, client := newTestAgent(n)
+	go a.write()
+
+	a.session.Close()
+	data, _ := ioutil.ReadAll(client)
+	if !bytes.Contains(data, []byte("see you")) {
+		t.Fatalf("message pushed in OnClosing should be delivered, got %q", data)
+	}
+	select {
+	case reason := <-closed:
+		if reason != session.CloseKicked {
+			t.Fatalf("expect %s, got %s", session.CloseKicked, reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnClosed not called")
+	}
+	if err := a.closeWith(session.CloseReadError); err != ErrCloseClosedSession {
+		t.Fatalf("expect %v, got %v", ErrCloseClosedSession, err)
+	}
+	if a.session.CloseReason() != session.CloseKicked {
+		t.Fatalf("the first reason should be recorded, got %s", a.session.CloseReason())
+	}
+	if n.CloseReasons()["kicked"] < 1 {
+		t.Fatalf("unexpected close reasons %v", n.CloseReasons())
+	}
+}
+
+func TestCloseReasonOf(t *testing.T) {
+	err := fmt.Errorf("process packet: %w", withCloseReason(session.CloseAuthFailed, errors.New("invalid token")))
+	if reason := closeReasonOf(err, session.CloseProtocolError); reason != session.CloseAuthFailed {
+		t.Fatalf("expect %s, got %s", session.CloseAuthFailed, reason)
+	}
+	if reason := closeReasonOf(errors.New("eof"), session.CloseProtocolError); reason != session.CloseProtocolError {
+		t.Fatalf("expect %s, got %s", session.CloseProtocolError, reason)
+	}
+}
+
+func TestIdleSessions(t *testing.T) {
+	lc := session.NewLifecycle()
+	var idle int32
+	lc.OnIdle(time.Minute, func(s *session.Session) {
+		atomic.AddInt32(&idle, 1)
+	})
+
+	n := newTestNode(t, Options{Lifecycle: lc}, nil)
+	a, _ := newTestAgent(n)
+	n.storeSession(a.session)
+
+	n.checkIdleSessions()
+	atomic.StoreInt64(&a.lastDataAt, time.Now().Add(-2*time.Minute).UnixNano())
+	n.checkIdleSessions()
+	n.checkIdleSessions()
+	if atomic.LoadInt32(&idle) != 1 {
+		t.Fatalf("OnIdle should be called once, got %d", idle)
+	}
+
+	// active again and idle again
+	atomic.StoreInt64(&a.lastDataAt, time.Now().Add(-time.Hour).UnixNano())
+	n.checkIdleSessions()
+	if atomic.LoadInt32(&idle) != 2 {
+		t.Fatalf("OnIdle should be called again, got %d", idle)
+	}
+}
+
+func TestWriteTimeout(t *testing.T) {
+	lc := session.NewLifecycle()
+	closed := make(chan session.CloseReason, 1)
+	lc.OnClosed(func(s *session.Session, reason session.CloseReason) {
+		closed <- reason
+	})
+
+	n := newTestNode(t, Options{Lifecycle: lc, WriteTimeout: 50 * time.Millisecond}, nil)
+	a, client := newTestAgent(n)
+	defer client.Close()
+	go a.write()
+
+	// the client never reads, so the push can not be written in time
+	a.session.Push("news", []byte("hello"))
+	select {
+	case reason := <-closed:
+		if reason != session.CloseWriteTimeout {
+			t.Fatalf("expect %s, got %s", session.CloseWriteTimeout, reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnClosed not called")
+	}
+}
+
+func TestTimeoutReason(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	server.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	_, err := server.Read(make([]byte, 1))
+	if !isTimeout(err) {
+		t.Fatalf("expect timeout error, got %v", err)
+	}
+	if reason := writeCloseReason(err); reason != session.CloseWriteTimeout {
+		t.Fatalf("expect %s, got %s", session.CloseWriteTimeout, reason)
+	}
+	if reason := writeCloseReason(errors.New("broken pipe")); reason != session.CloseWriteError {
+		t.Fatalf("expect %s, got %s", session.CloseWriteError, reason)
+	}
+}
+
+func TestAgentCloseInBusyScheduler(t *testing.T) {
+	lc := session.NewLifecycle()
+	closed := make(chan session.CloseReason, 1)
+	lc.OnClosed(func(s *session.Session, reason session.CloseReason) {
+		closed <- reason
+	})
+	n := newTestNode(t, Options{Lifecycle: lc}, nil)
+	a, client := newTestAgent(n)
+	go a.write()
+
+	// the handler closes the session while the queue of scheduler is full
+	done := make(chan struct{})
+	n.Scheduler.Run(func() {
+		for n.Scheduler.TryRun(func() {}) {
+		}
+		a.session.Close()
+		close(done)
+	})
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("closing session should not block the scheduler")
+	}
+	ioutil.ReadAll(client)
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("OnClosed not called")
+	}
+}
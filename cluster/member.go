@@ -24,6 +24,6 @@ import "github.com/nano-kit/go-nano/cluster/clusterpb"
 
 // Member is the remote component managed by cluster
 type Member struct {
-	IsMaster bool
+	IsMaster bool `json:"isMaster"`
 	*clusterpb.MemberInfo
 }
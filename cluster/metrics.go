@@ -0,0 +1,105 @@
+package cluster
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/nano-kit/go-nano/metrics"
+)
+
+// metricsRegistry collects the process-wide metrics of the application, which
+// are served at /metrics of every node monitor in the Prometheus text format
+// before the metrics of the node
+var metricsRegistry = metrics.NewRegistry()
+
+// Metrics returns the metrics registry of current process, the application
+// can register its own metrics which are served together at /metrics
+func Metrics() *metrics.Registry {
+	return metricsRegistry
+}
+
+// nodeMetrics is the metrics of a node, each node has its own registry so
+// that the nodes in one process do not mix their series
+type nodeMetrics struct {
+	registry *metrics.Registry
+
+	handshakes          *metrics.CounterVec
+	received            *metrics.CounterVec
+	sent                *metrics.CounterVec
+	serializationErrors *metrics.CounterVec
+	dropped             *metrics.CounterVec
+	compressionBytes    *metrics.CounterVec
+	admissions          *metrics.CounterVec
+	closed              *metrics.CounterVec
+	rateLimited         *metrics.CounterVec
+	handlerDuration     *metrics.HistogramVec
+	forwardDuration     *metrics.HistogramVec
+}
+
+// newNodeMetrics creates the metrics of the node, the gauges are collected
+// from the node when the metrics are written
+func newNodeMetrics(n *Node) *nodeMetrics {
+	r := metrics.NewRegistry()
+	r.GaugeFunc("nano_scheduler_queue_depth", "Tasks waiting in the scheduler.", func() float64 {
+		return float64(n.Scheduler.QueueLen())
+	})
+	r.GaugeFunc("nano_connections", "Current client connections.", func() float64 {
+		return float64(n.admission.connections())
+	})
+	r.GaugeFunc("nano_cluster_members", "Members of the cluster known by current node.", func() float64 {
+		if n.cluster == nil {
+			return 0
+		}
+		return float64(len(n.Members()))
+	})
+	return &nodeMetrics{
+		registry: r,
+		handshakes: r.Counter("nano_handshakes_total",
+			"Client handshakes by result.", "result"),
+		received: r.Counter("nano_messages_received_total",
+			"Messages received from clients by route and type.", "route", "type"),
+		sent: r.Counter("nano_messages_sent_total",
+			"Messages sent to clients by route and type.", "route", "type"),
+		serializationErrors: r.Counter("nano_serialization_errors_total",
+			"Payloads failed to marshal or unmarshal.", "op"),
+		dropped: r.Counter("nano_push_dropped_total",
+			"Messages dropped since the send queue of the session is full.", "type"),
+		compressionBytes: r.Counter("nano_compression_bytes_total",
+			"Data bytes of the compressed messages before and after compression.", "stage"),
+		admissions: r.Counter("nano_admissions_total",
+			"Client connections by admission result.", "result"),
+		closed: r.Counter("nano_session_closed_total",
+			"Closed client sessions by reason.", "reason"),
+		rateLimited: r.Counter("nano_rate_limited_total",
+			"Messages exceeding the rate limit by action.", "action"),
+		handlerDuration: r.Histogram("nano_handler_duration_seconds",
+			"Latency of the local handlers by route.", nil, "route"),
+		forwardDuration: r.Histogram("nano_rpc_forward_duration_seconds",
+			"Latency of forwarding messages to the cluster members by member address.", nil, "member"),
+	}
+}
+
+// Metrics returns the metrics registry of the node, which is served at
+// /metrics of the node monitor after the process-wide metrics
+func (n *Node) Metrics() *metrics.Registry {
+	return n.metrics.registry
+}
+
+// serveMetrics serves the process-wide metrics and the metrics of the node
+func (n *Node) serveMetrics(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", metrics.ContentType)
+	metricsRegistry.WriteTo(w)
+	n.metrics.registry.WriteTo(w)
+}
+
+// routeLabel returns the route as the metric label, the routes which are not
+// served by the cluster are merged to keep the label values bounded
+func (h *LocalHandler) routeLabel(route string, local bool) string {
+	if local {
+		return route
+	}
+	if index := strings.LastIndex(route, "."); index > 0 && len(h.findMembers(route[:index])) > 0 {
+		return route
+	}
+	return "unknown"
+}
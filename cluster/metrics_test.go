@@ -0,0 +1,88 @@
+package cluster
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nano-kit/go-nano/internal/message"
+	"github.com/nano-kit/go-nano/ratelimit"
+)
+
+func TestMessageMetrics(t *testing.T) {
+	n := newTestNode(t, Options{}, nil)
+	h := n.handler
+	a, _ := newTestAgent(n)
+
+	receivedCounter := n.metrics.received
+	joined := receivedCounter.With("Room.Join", message.Notify.String()).Value()
+	unknown := receivedCounter.With("unknown", message.Notify.String()).Value()
+	h.processMessage(a, &message.Message{Type: message.Notify, Route: "Room.Join"})
+	h.processMessage(a, &message.Message{Type: message.Notify, Route: "Hall.Enter"})
+	if v := receivedCounter.With("Room.Join", message.Notify.String()).Value(); v != joined+1 {
+		t.Fatalf("expect %v messages of the local route, got %v", joined+1, v)
+	}
+	if v := receivedCounter.With("unknown", message.Notify.String()).Value(); v != unknown+1 {
+		t.Fatalf("expect %v messages of the unknown routes, got %v", unknown+1, v)
+	}
+
+	droppedCounter := n.metrics.dropped
+	dropped := droppedCounter.With(message.Push.String()).Value()
+	for i := 0; i <= agentWriteBacklog; i++ {
+		a.Push("news", []byte("hello"))
+	}
+	if v := droppedCounter.With(message.Push.String()).Value(); v != dropped+1 {
+		t.Fatalf("expect %v dropped pushes, got %v", dropped+1, v)
+	}
+
+	n.limiter = ratelimit.New(ratelimit.Config{Session: ratelimit.Limit{Rate: 0.001, Burst: 1}})
+	msg := &message.Message{Type: message.Notify, Route: "Room.Join"}
+	if !n.rateLimit(a, msg) || n.rateLimit(a, msg) {
+		t.Fatal("expect the second message limited")
+	}
+	if v := n.metrics.rateLimited.With("limited").Value(); v != 1 {
+		t.Fatalf("expect 1 limited message, got %v", v)
+	}
+
+	var buf bytes.Buffer
+	n.Metrics().WriteTo(&buf)
+	if !strings.Contains(buf.String(), `nano_messages_received_total{route="Room.Join",type="Notify"}`) {
+		t.Fatalf("unexpected metrics:\n%s", buf.String())
+	}
+}
+
+func TestNodeMetrics(t *testing.T) {
+	var nodes []*Node
+	for i := 0; i < 2; i++ {
+		n := newTestNode(t, Options{}, nil)
+		admission, err := newAdmission(AdmissionOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		n.admission = admission
+		n.cluster = newCluster(n)
+		nodes = append(nodes, n)
+	}
+	if err := nodes[0].admit("127.0.0.1:3250"); err != nil {
+		t.Fatal(err)
+	}
+	defer nodes[0].admission.release("127.0.0.1:3250")
+
+	// each node reports its own series besides the process-wide metrics
+	for i, expect := range []string{"nano_connections 1\n", "nano_connections 0\n"} {
+		w := httptest.NewRecorder()
+		nodes[i].serveMetrics(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		body := w.Body.String()
+		if !strings.Contains(body, expect) || !strings.Contains(body, "nano_scheduler_queue_depth") {
+			t.Fatalf("unexpected metrics of node %d:\n%s", i, body)
+		}
+		if accepted := strings.Contains(body, `nano_admissions_total{result="accepted"} 1`); accepted != (i == 0) {
+			t.Fatalf("unexpected metrics of node %d:\n%s", i, body)
+		}
+	}
+	if nodes[0].Metrics() == nodes[1].Metrics() {
+		t.Fatal("the nodes should not share the metrics registry")
+	}
+}
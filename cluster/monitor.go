@@ -0,0 +1,258 @@
+package cluster
+
+import (
+	"crypto/subtle"
+	"expvar"
+	"html/template"
+	"net/http"
+	"net/http/pprof"
+)
+
+// BasicAuth is the credential protecting the node monitor
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+// NodeInfo is the JSON view of the node monitor
+type NodeInfo struct {
+	Type             string           `json:"type"`
+	Label            string           `json:"label"`
+	ServiceAddr      string           `json:"serviceAddr"`
+	IsMaster         bool             `json:"isMaster"`
+	RegistryAddr     string           `json:"registryAddr"`
+	RegisterInterval string           `json:"registerInterval"`
+	GateAddr         string           `json:"gateAddr"`
+	MonitorAddr      string           `json:"monitorAddr"`
+	IsWebsocket      bool             `json:"isWebsocket"`
+	WSPath           string           `json:"wsPath"`
+	IsTLS            bool             `json:"isTLS"`
+	Components       []CompInfo       `json:"components"`
+	Remotes          []RemoteInfo     `json:"remotes"`
+	Members          []*Member        `json:"members"`
+	Sessions         int              `json:"sessions"`
+	CloseReasons     map[string]int64 `json:"closeReasons"`
+}
+
+// nodeTmpl renders the node monitor page, which is embedded in the binary
+var nodeTmpl = template.Must(template.New("node").Parse(nodeHTML + componentsHTML +
+	remotesHTML + membersHTML + sessionsHTML + disconnectsHTML))
+
+// monitorHandler returns the handler of the node monitor server
+func (n *Node) monitorHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/debug/nano/node", n.nodeInfo)
+	mux.HandleFunc("/debug/nano/node.json", n.nodeInfoJSON)
+	mux.HandleFunc("/debug/nano/components.json", n.componentsJSON)
+	mux.HandleFunc("/debug/nano/remotes.json", n.remotesJSON)
+	mux.HandleFunc("/debug/nano/members.json", n.membersJSON)
+	mux.HandleFunc("/metrics", n.serveMetrics)
+	n.registerAdminHandlers(mux)
+
+	if n.MonitorAuth == nil {
+		return mux
+	}
+	return basicAuth(*n.MonitorAuth, mux)
+}
+
+// basicAuth rejects the requests without the credential
+func basicAuth(credential BasicAuth, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok ||
+			subtle.ConstantTimeCompare([]byte(username), []byte(credential.Username)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(password), []byte(credential.Password)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="nano monitor"`)
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (n *Node) nodeInfo(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := nodeTmpl.Execute(w, n); err != nil {
+		logger.Error("render node monitor failed", "err", err)
+	}
+}
+
+func (n *Node) nodeInfoJSON(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, &NodeInfo{
+		Type:             n.Type(),
+		Label:            n.Label,
+		ServiceAddr:      n.ServiceAddr,
+		IsMaster:         n.IsMaster,
+		RegistryAddr:     n.RegistryAddr,
+		RegisterInterval: n.RegisterInterval.String(),
+		GateAddr:         n.GateAddr,
+		MonitorAddr:      n.MonitorAddr,
+		IsWebsocket:      n.IsWebsocket,
+		WSPath:           n.WSPath,
+		IsTLS:            n.TSLCertificate != "",
+		Components:       n.handler.Components(),
+		Remotes:          n.handler.Remotes(),
+		Members:          n.Members(),
+		Sessions:         len(n.Sessions()),
+		CloseReasons:     n.CloseReasons(),
+	})
+}
+
+func (n *Node) componentsJSON(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, n.handler.Components())
+}
+
+func (n *Node) remotesJSON(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, n.handler.Remotes())
+}
+
+func (n *Node) membersJSON(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, n.Members())
+}
+
+const nodeHTML = `<html>
+<head>
+    <title>Nano {{.Type}} Server</title>
+</head>
+<body>
+<h1>/debug/nano/node {{.Type}} Server</h1>
+<h2>Node Options:</h2>
+<table>
+    <thead><tr><th>Key</th><th>Value</th></tr></thead>
+    <tbody>
+    <tr><td>Label</td><td>{{.Label}}</td></tr>
+    <tr><td>ServiceAddr</td><td>{{.ServiceAddr}}</td></tr>
+    <tr><td>IsMaster</td><td>{{.IsMaster}}</td></tr>
+    <tr><td>RegistryAddr</td><td>{{.RegistryAddr}}</td></tr>
+    <tr><td>RegisterInterval</td><td>{{.RegisterInterval}}</td></tr>
+    <tr><td>GateAddr</td><td>{{.GateAddr}}</td></tr>
+    <tr><td>MonitorAddr</td><td>{{.MonitorAddr}}</td></tr>
+    <tr><td>IsWebsocket</td><td>{{.IsWebsocket}}</td></tr>
+    <tr><td>TSLCertificate</td><td>{{.TSLCertificate}}</td></tr>
+    <tr><td>WSPath</td><td>{{.WSPath}}</td></tr>
+    </tbody>
+</table>
+{{template "components" .Handler.Components}}
+{{template "remotes" .Handler.Remotes}}
+{{template "members" .Members}}
+{{template "sessions" .Sessions}}
+{{template "disconnects" .CloseReasons}}
+</body>
+</html>
+`
+
+const componentsHTML = `{{define "components"}}
+<h2>Registered Components:</h2>
+<table>
+    <thead><tr>
+        <th>Name</th>
+        <th>ReceiverType</th>
+        <th>HandlerType</th>
+        <th>IsRawArg</th>
+        <th>Scheduler</th>
+    </tr></thead>
+    <tbody>
+    {{range .}}
+    <tr>
+        <td>{{.Name}}</td>
+        <td>{{.ReceiverType}}</td>
+        <td>{{.HandlerType}}</td>
+        <td>{{.IsRawArg}}</td>
+        <td>{{.Scheduler}}</td>
+    </tr>
+    {{end}}
+    </tbody>
+</table>
+{{end}}`
+
+const remotesHTML = `{{define "remotes"}}
+<h2>Remote Components:</h2>
+<table>
+    <thead><tr>
+        <th>Name</th>
+        <th>Label</th>
+        <th>ServiceAddr</th>
+        <th>Services</th>
+    </tr></thead>
+    <tbody>
+    {{range .}}
+    <tr>
+        <td>{{.Name}}</td>
+        <td>{{.Label}}</td>
+        <td>{{.ServiceAddr}}</td>
+        <td>{{.Services}}</td>
+    </tr>
+    {{end}}
+    </tbody>
+</table>
+{{end}}`
+
+const membersHTML = `{{define "members"}}
+<h2>Known Members:</h2>
+<table>
+    <thead><tr>
+        <th>Label</th>
+        <th>ServiceAddr</th>
+        <th>IsMaster</th>
+        <th>Services</th>
+    </tr></thead>
+    <tbody>
+    {{range .}}
+    <tr>
+        <td>{{.Label}}</td>
+        <td>{{.ServiceAddr}}</td>
+        <td>{{.IsMaster}}</td>
+        <td>{{.Services}}</td>
+    </tr>
+    {{end}}
+    </tbody>
+</table>
+{{end}}`
+
+const sessionsHTML = `{{define "sessions"}}
+<h2>User Sessions:</h2>
+<table>
+    <thead><tr>
+        <th>SessionID</th>
+        <th>UserID</th>
+        <th>LastActivity</th>
+        <th>RemoteAddr</th>
+        <th>LastMessageID</th>
+    </tr></thead>
+    <tbody>
+    {{range .}}
+    <tr>
+        <td>{{.ID}}</td>
+        <td>{{.UID}}</td>
+        <td>{{.LastActivity}}</td>
+        <td>{{.RemoteAddr}}</td>
+        <td>{{.LastMid}}</td>
+    </tr>
+    {{end}}
+    </tbody>
+</table>
+{{end}}`
+
+const disconnectsHTML = `{{define "disconnects"}}
+<h2>Disconnects:</h2>
+<table>
+    <thead><tr>
+        <th>Reason</th>
+        <th>Count</th>
+    </tr></thead>
+    <tbody>
+    {{range $reason, $count := .}}
+    <tr>
+        <td>{{$reason}}</td>
+        <td>{{$count}}</td>
+    </tr>
+    {{end}}
+    </tbody>
+</table>
+{{end}}`
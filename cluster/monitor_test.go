@@ -0,0 +1,73 @@
+package cluster
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nano-kit/go-nano/cluster/clusterpb"
+	"github.com/nano-kit/go-nano/service"
+	"github.com/nano-kit/go-nano/session"
+)
+
+func TestMonitor(t *testing.T) {
+	n := newTestNode(t, Options{Label: "gate-1", RegistryAddr: "127.0.0.1:3240", GateAddr: "127.0.0.1:3250"}, nil)
+	n.ServiceAddr = "127.0.0.1:3260"
+	n.cluster = newCluster(n)
+	n.cluster.members = []*Member{{MemberInfo: &clusterpb.MemberInfo{Label: "room-1", ServiceAddr: "127.0.0.1:3270", Services: []string{"Hall"}}}}
+	handler := n.monitorHandler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/nano/node", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "<td>Room.Join</td>") {
+		t.Fatalf("unexpected node page: %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/nano/node.json", nil))
+	var info NodeInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Type != "Gate" || info.Label != "gate-1" || len(info.Components) != 1 ||
+		info.Components[0].Name != "Room.Join" || len(info.Members) != 1 || info.Members[0].ServiceAddr != "127.0.0.1:3270" {
+		t.Fatalf("unexpected node info: %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/nano/members.json", nil))
+	if !strings.Contains(w.Body.String(), `"label":"room-1"`) {
+		t.Fatalf("unexpected members: %s", w.Body.String())
+	}
+}
+
+func TestMonitorAuth(t *testing.T) {
+	n := &Node{
+		Options:  Options{MonitorAuth: &BasicAuth{Username: "ops", Password: "secret"}},
+		sessions: map[service.SID]*session.Session{},
+	}
+	handler := n.monitorHandler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/nano/sessions", nil))
+	if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+		t.Fatalf("expect %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/debug/nano/sessions", nil)
+	r.SetBasicAuth("ops", "wrong")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expect %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	r.SetBasicAuth("ops", "secret")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != "[]" {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+}
@@ -28,17 +28,23 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/nano-kit/go-nano/auth"
 	"github.com/nano-kit/go-nano/cluster/clusterpb"
 	"github.com/nano-kit/go-nano/component"
+	"github.com/nano-kit/go-nano/internal/env"
 	"github.com/nano-kit/go-nano/internal/log"
 	"github.com/nano-kit/go-nano/internal/message"
 	"github.com/nano-kit/go-nano/pipeline"
+	"github.com/nano-kit/go-nano/ratelimit"
 	"github.com/nano-kit/go-nano/scheduler"
+	"github.com/nano-kit/go-nano/serialize"
 	"github.com/nano-kit/go-nano/service"
 	"github.com/nano-kit/go-nano/session"
+	"github.com/nano-kit/go-nano/trace"
 	"golang.org/x/sys/unix"
 	"google.golang.org/grpc"
 )
@@ -47,14 +53,53 @@ import (
 type Options struct {
 	Pipeline         pipeline.Pipeline
 	IsMaster         bool
+	ServiceAddr      string // current server service address
 	RegistryAddr     string
 	RegisterInterval time.Duration
 	GateAddr         string
+	GateID           uint16 // the gate ID in the high 32 bits of the session IDs
 	Components       *component.Components
 	Label            string
 	MonitorAddr      string
+	MonitorAuth      *BasicAuth         // protect the node monitor with basic authentication, nil to disable
+	MonitorAdmin     bool               // serve the session kick, push, close, log level and reload endpoints even if MonitorAuth is nil
+	Authenticator    auth.Authenticator // verify the token presented by clients
+	AuthWhitelist    auth.Whitelist     // routes can be called before authentication
+	AuthResponse     interface{}        // the response of the requests rejected before authentication, defaults to auth.DefaultResponse
+	SyncKeys         []string           // session data keys synchronized between gate and backends
+	BindPolicy       *BindPolicy        // enforce the sessions limitation of UID, nil to disable
+	SessionRegistry  SessionRegistry    // where the UID bindings are recorded, defaults to master
+
+	SessionStore         session.Store // persist the client session values keyed by UID, nil to disable
+	SessionStoreKeys     []string      // persisted session data keys, empty means all keys
+	SessionFlushInterval time.Duration // periodic flush interval, zero means write-through
+
+	Lifecycle *session.Lifecycle // session lifecycle callbacks of current node
+	RateLimit *ratelimit.Config  // limit the message rate of client sessions, nil to disable
+	Tracer    *trace.Tracer      // record the spans of messages, nil to disable
+
+	Scheduler  *scheduler.Scheduler // runs the handlers and the lifecycle callbacks, a new one is started by the node if nil
+	Dictionary map[string]uint16    // routes compressed to codes for the clients
+
+	GrpcOptions []grpc.DialOption // dial options of the members, defaults to env.GrpcOptions
+
+	Heartbeat          time.Duration        // heartbeat interval of client sessions, defaults to env.Heartbeat
+	Serializer         serialize.Serializer // serializer of handler payload, defaults to env.Serializer
+	HandshakeValidator func([]byte) error   // verify the custom data of handshake, defaults to env.HandshakeValidator
+
+	ReadIdleTimeout time.Duration // close the client connection if nothing is read in time, zero to disable
+	WriteTimeout    time.Duration // close the client connection if a write does not complete in time, zero to disable
+
+	CompressThreshold int // compress the data not smaller than it for the clients negotiated compression, zero to disable
+	MaxPacketSize     int // the maximum packet size, the larger messages are fragmented for the clients supporting it, defaults to 64KiB
+	MaxMessageSize    int // the maximum message reassembled from the fragments of a session, defaults to 1MiB and clamped to 16MiB-1, negative to reject fragments
+
+	ConfigFile string                        // the configuration file reloaded at runtime
+	Reloader   func() (*ReloadReport, error) // reload the configuration on POST /debug/nano/config/reload, nil to disable
 
 	WebsocketOptions
+	AdmissionOptions
+	ShutdownOptions
 }
 
 // WebsocketOptions contains WebSocket related configurations
@@ -65,6 +110,7 @@ type WebsocketOptions struct {
 	WSPath         string                   // WebSocket path (eg: ws://127.0.0.1/WSPath)
 	ServeMux       *http.ServeMux           // do not rely on http.DefaultServeMux, use a private mux
 	CheckOrigin    func(*http.Request) bool // check origin when websocket enabled
+	WSCompression  bool                     // negotiate the permessage-deflate extension with WebSocket clients
 }
 
 // NewOptions creates Options
@@ -82,16 +128,36 @@ func NewOptions() Options {
 // All services will register to cluster and messages will be forwarded to the node
 // which provides respective service
 type Node struct {
-	Options            // current node options
-	ServiceAddr string // current server service address
+	Options // current node options
 
 	cluster   *cluster
 	handler   *LocalHandler
 	rpcServer *grpc.Server
 	rpcClient *rpcClient
-
-	mu       sync.RWMutex
-	sessions map[service.SID]*session.Session
+	registry  SessionRegistry
+	admission *admission
+	draining  int32 // set when the node is shutting down
+	inflight  int64 // the number of handlers dispatched but not finished
+
+	mu         sync.RWMutex
+	sessions   map[service.SID]*session.Session
+	listener   net.Listener // the gate listener of TCP
+	httpServer *http.Server // the gate server of WebSocket
+	monitor    *http.Server // the node monitor server
+
+	reloadMu sync.RWMutex       // guards the reloadable settings, see Reload
+	reloaded *Reloadable        // the settings applied by Reload, which override the Options
+	limiter  *ratelimit.Limiter // rate limiter of client messages
+	hrd      []byte             // handshake response data
+
+	metrics *nodeMetrics // metrics of the node, see Metrics
+
+	connections   *service.ConnectionService // generates the session IDs
+	dict          *message.RouteDict         // compresses the routes of client messages
+	ownsScheduler bool                       // set if the scheduler is started by the node
+
+	dieOnce sync.Once
+	die     chan struct{} // closed when the node is shut down, stops the repeated tasks
 }
 
 func validateListenAddrWithExplicitPort(addr string) error {
@@ -120,19 +186,33 @@ func (n *Node) Startup() error {
 	}
 
 	n.sessions = map[service.SID]*session.Session{}
+	n.die = make(chan struct{})
+	if n.RateLimit != nil {
+		n.limiter = ratelimit.New(*n.RateLimit)
+	}
+	admission, err := newAdmission(n.AdmissionOptions)
+	if err != nil {
+		return err
+	}
+	n.admission = admission
 	n.cluster = newCluster(n)
+	n.initSessionState()
+	n.metrics = newNodeMetrics(n)
 	n.handler = NewHandler(n, n.Pipeline)
 	components := n.Components.List()
 	for _, c := range components {
 		err := n.handler.register(c.Comp, c.Opts)
 		if err != nil {
+			n.closeScheduler()
 			return err
 		}
 	}
 
-	cache()
+	n.hrd = handshakeResponse(n.heartbeat(), negotiation{})
 	n.adjustOpenFilesLimit()
+	n.initRegistry()
 	if err := n.initNode(); err != nil {
+		n.closeScheduler()
 		return err
 	}
 
@@ -159,11 +239,38 @@ func (n *Node) Startup() error {
 		n.waitForGate(time.Second)
 	}
 
-	n.startMonitor()
-	scheduler.Repeat(n.removeStaleSession, 67*time.Second)
+	// the monitor address is determined after the gate is listening
+	if err := n.startMonitor(); err != nil {
+		n.stopAccepting(context.Background())
+		n.shutdownComponents()
+		return err
+	}
+	n.Scheduler.RepeatUntil(n.removeStaleSession, 67*time.Second, n.die)
+	n.Scheduler.RepeatUntil(n.checkIdleSessions, idleCheckInterval, n.die)
+	if n.SessionStore != nil && n.SessionFlushInterval > 0 {
+		go n.flushPeriodically(n.SessionFlushInterval, n.die)
+	}
 	return nil
 }
 
+// initSessionState initializes the scheduler, the session ID generator and
+// the route dictionary of the node
+func (n *Node) initSessionState() {
+	if n.Scheduler == nil {
+		n.Scheduler = scheduler.New()
+		n.ownsScheduler = true
+	}
+	n.connections = service.NewConnectionService(n.GateID)
+	n.dict = message.NewRouteDict(n.Dictionary)
+}
+
+// closeScheduler stops the scheduler if it is started by the node
+func (n *Node) closeScheduler() {
+	if n.ownsScheduler {
+		n.Scheduler.Close()
+	}
+}
+
 func (n *Node) waitForGate(timeout time.Duration) {
 	begin := time.Now()
 	for time.Since(begin) < timeout {
@@ -180,18 +287,19 @@ func (n *Node) waitForGate(timeout time.Duration) {
 }
 
 func (n *Node) adjustOpenFilesLimit() {
-	const (
-		MinReservedFDs = 64
-		MaxClients     = 10000
-	)
+	const MinReservedFDs = 64
+	MaxClients := n.MaxConnections
+	if MaxClients <= 0 {
+		MaxClients = defaultMaxConnections
+	}
 	var (
 		err      error
-		maxfiles uint64 = MaxClients + MinReservedFDs
+		maxfiles = uint64(MaxClients + MinReservedFDs)
 		limit    unix.Rlimit
 	)
 
 	if err = unix.Getrlimit(unix.RLIMIT_NOFILE, &limit); err != nil {
-		log.Print("unable to obtain the current NOFILE limit", err)
+		logger.Warn("unable to obtain the current NOFILE limit", "err", err)
 		return
 	}
 
@@ -232,14 +340,27 @@ func (n *Node) adjustOpenFilesLimit() {
 			log.Fatalf("Your current 'ulimit -n' of %v is not enough for the server to start. "+
 				"Please increase your open file limit to at least %v. Exiting.", oldlimit, maxfiles)
 		}
-		log.Printf("You requested maxclients of %v requiring at least %v max file descriptors.", oldMaxclients, maxfiles)
-		log.Printf("Server can't set maximum open files to %v because of OS error: %v", maxfiles, err)
-		log.Printf("Current maximum open files is %v. "+
-			"maxclients has been reduced to %v to compensate for low ulimit. "+
-			"If you need higher maxclients increase 'ulimit -n'.", bestlimit, maxclients)
+		logger.Warn("maxclients has been reduced to compensate for low ulimit, increase 'ulimit -n' for higher maxclients",
+			"requested", oldMaxclients, "required", maxfiles, "current", bestlimit, "maxclients", maxclients, "err", err)
 	} else {
-		log.Printf("increased maximum number of open files to %v (it was originally set to %v)", maxfiles, oldlimit)
+		logger.Info("increased maximum number of open files", "limit", maxfiles, "original", oldlimit)
+	}
+}
+
+// serializer returns the serializer of handler payload
+func (n *Node) serializer() serialize.Serializer {
+	if n.Serializer != nil {
+		return n.Serializer
+	}
+	return env.Serializer
+}
+
+// handshakeValidator returns the validator of handshake data
+func (n *Node) handshakeValidator() func([]byte) error {
+	if n.HandshakeValidator != nil {
+		return n.HandshakeValidator
 	}
+	return env.HandshakeValidator
 }
 
 // Handler returns node's local handler
@@ -261,8 +382,8 @@ func (n *Node) initNode() error {
 
 	// Initialize the gRPC server and register service
 	n.rpcServer = grpc.NewServer()
-	n.rpcClient = newRPCClient()
-	scheduler.Repeat(n.shrinkRPCClient, 61*time.Second)
+	n.rpcClient = newRPCClient(n.GrpcOptions)
+	n.Scheduler.RepeatUntil(n.shrinkRPCClient, 61*time.Second, n.die)
 	clusterpb.RegisterMemberServer(n.rpcServer, n)
 
 	go func() {
@@ -280,6 +401,7 @@ func (n *Node) initNode() error {
 				Label:       n.Label,
 				ServiceAddr: n.ServiceAddr,
 				Services:    n.handler.LocalService(),
+				Gate:        n.GateAddr != "",
 			},
 		}
 		n.cluster.members = append(n.cluster.members, member)
@@ -295,6 +417,7 @@ func (n *Node) initNode() error {
 				Label:       n.Label,
 				ServiceAddr: n.ServiceAddr,
 				Services:    n.handler.LocalService(),
+				Gate:        n.GateAddr != "",
 			},
 		}
 		for {
@@ -305,7 +428,8 @@ func (n *Node) initNode() error {
 				break
 			}
 
-			log.Print("register current node to cluster failed", err, "and will unregister then retry in", n.RegisterInterval.String())
+			logger.Error("register current node to cluster failed, unregister and retry later", "err", err,
+				"interval", n.RegisterInterval.String())
 			time.Sleep(n.RegisterInterval)
 			n.unregister()
 		}
@@ -314,9 +438,22 @@ func (n *Node) initNode() error {
 	return nil
 }
 
+// Shutdown shuts down the node gracefully, the client sessions are drained
+// until ShutdownTimeout
+func (n *Node) Shutdown() {
+	timeout := n.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	n.ShutdownContext(ctx)
+}
+
 // Shutdown all components registered by application, that
 // call by reverse order against register
-func (n *Node) Shutdown() {
+func (n *Node) shutdownComponents() {
 	// reverse call `BeforeShutdown` hooks
 	components := n.Components.List()
 	length := len(components)
@@ -329,6 +466,9 @@ func (n *Node) Shutdown() {
 		components[i].Comp.Shutdown()
 	}
 
+	n.stop()
+	n.flushSessions()
+
 	if !n.IsMaster && n.RegistryAddr != "" {
 		n.unregister()
 	}
@@ -336,12 +476,14 @@ func (n *Node) Shutdown() {
 	if n.rpcServer != nil {
 		n.rpcServer.GracefulStop()
 	}
+	n.closeScheduler()
+	clearDefaultNode(n)
 }
 
 func (n *Node) unregister() error {
 	pool, err := n.rpcClient.getConnPool(n.RegistryAddr)
 	if err != nil {
-		log.Print("retrieve master address error", err)
+		logger.Error("retrieve connection pool failed", "member", n.RegistryAddr, "err", err)
 		return err
 	}
 	client := clusterpb.NewMasterClient(pool.Get())
@@ -350,7 +492,7 @@ func (n *Node) unregister() error {
 	}
 	_, err = client.Unregister(context.Background(), request)
 	if err != nil {
-		log.Print("unregister current node failed", err)
+		logger.Error("unregister current node failed", "err", err)
 		return err
 	}
 	return nil
@@ -362,49 +504,78 @@ func (n *Node) listenAndServe() {
 	if err != nil {
 		log.Fatal(err.Error())
 	}
+	n.mu.Lock()
+	n.listener = listener
+	n.mu.Unlock()
 
 	for {
 		conn, err := listener.Accept()
 		if err != nil {
-			log.Print(err.Error())
+			if n.isDraining() {
+				return
+			}
+			logger.Error("accept connection failed", "err", err)
 			continue
 		}
 
+		if err := n.admit(conn.RemoteAddr().String()); err != nil {
+			logger.Debug("reject connection", "remote", conn.RemoteAddr().String(), "err", err)
+			conn.Close()
+			continue
+		}
 		go n.handler.handle(conn)
 	}
 }
 
 func (n *Node) listenAndServeWS() {
-	n.setupWSHandler()
+	server := n.setupWSServer()
 
-	if err := http.ListenAndServe(n.GateAddr, n.ServeMux); err != nil {
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		log.Fatal(err.Error())
 	}
 }
 
 func (n *Node) listenAndServeWSTLS() {
-	n.setupWSHandler()
+	server := n.setupWSServer()
 
-	if err := http.ListenAndServeTLS(n.GateAddr, n.TSLCertificate, n.TSLKey, n.ServeMux); err != nil {
+	if err := server.ListenAndServeTLS(n.TSLCertificate, n.TSLKey); err != nil && err != http.ErrServerClosed {
 		log.Fatal(err.Error())
 	}
 }
 
+// setupWSServer creates the WebSocket gate server which is closed on shutdown
+func (n *Node) setupWSServer() *http.Server {
+	n.setupWSHandler()
+
+	server := &http.Server{Addr: n.GateAddr, Handler: n.ServeMux}
+	n.mu.Lock()
+	n.httpServer = server
+	n.mu.Unlock()
+	return server
+}
+
 func (n *Node) setupWSHandler() {
 	var upgrader = websocket.Upgrader{
-		ReadBufferSize:  1024,
-		WriteBufferSize: 1024,
-		CheckOrigin:     n.CheckOrigin,
+		ReadBufferSize:    1024,
+		WriteBufferSize:   1024,
+		CheckOrigin:       n.CheckOrigin,
+		EnableCompression: n.WSCompression,
 	}
 
 	n.ServeMux.HandleFunc("/"+strings.TrimPrefix(n.WSPath, "/"), func(w http.ResponseWriter, r *http.Request) {
+		if err := n.admit(r.RemoteAddr); err != nil {
+			logger.Debug("reject connection", "remote", r.RemoteAddr, "err", err)
+			rejectHTTP(w, err)
+			return
+		}
 		conn, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
-			log.Printf("upgrade failure, URI=%s, Error=%s", r.RequestURI, err.Error())
+			n.admission.release(r.RemoteAddr)
+			logger.Error("upgrade websocket failed", "uri", r.RequestURI, "err", err)
 			return
 		}
 
-		n.handler.handleWS(conn)
+		n.handler.handleWS(conn, n.WSCompression && offersDeflate(r.Header))
 	})
 }
 
@@ -441,18 +612,20 @@ func (n *Node) findOrCreateSession(sid service.SID, gateAddr string) (*session.S
 			gateClient: clusterpb.NewMemberClient(conns.Get()),
 			rpcHandler: n.handler.remoteProcess,
 			gateAddr:   gateAddr,
+			node:       n,
 		}
 		s = session.NewWith(sid, ac)
 		ac.session = s
 		n.mu.Lock()
 		n.sessions[sid] = s
 		n.mu.Unlock()
+		n.Scheduler.Run(func() { n.Lifecycle.NotifyCreated(s) })
 	}
 	return s, nil
 }
 
 // HandleRequest implements the MemberServer interface
-func (n *Node) HandleRequest(_ context.Context, req *clusterpb.RequestMessage) (*clusterpb.MemberHandleResponse, error) {
+func (n *Node) HandleRequest(ctx context.Context, req *clusterpb.RequestMessage) (*clusterpb.MemberHandleResponse, error) {
 	handler, found := n.handler.localHandlers[req.Route]
 	if !found {
 		return nil, fmt.Errorf("service not found in current node: %v", req.Route)
@@ -462,18 +635,24 @@ func (n *Node) HandleRequest(_ context.Context, req *clusterpb.RequestMessage) (
 		return nil, err
 	}
 	msg := &message.Message{
-		Type:  message.Request,
-		ID:    req.Id,
-		Route: req.Route,
-		Data:  req.Data,
-	}
-	n.handler.localProcess(handler, req.Id, s, msg)
+		Type:        message.Request,
+		ID:          req.Id,
+		Route:       req.Route,
+		Data:        req.Data,
+		Traceparent: traceparentOf(ctx),
+	}
+	n.handler.localProcess(handler, req.Id, s, msg, &gateState{
+		uid:       req.Uid,
+		data:      req.Metadata,
+		claims:    req.Claims,
+		expiresAt: req.ExpiresAt,
+	})
 	s.AdvanceLastTime()
 	return &clusterpb.MemberHandleResponse{}, nil
 }
 
 // HandleNotify implements the MemberServer interface
-func (n *Node) HandleNotify(_ context.Context, req *clusterpb.NotifyMessage) (*clusterpb.MemberHandleResponse, error) {
+func (n *Node) HandleNotify(ctx context.Context, req *clusterpb.NotifyMessage) (*clusterpb.MemberHandleResponse, error) {
 	handler, found := n.handler.localHandlers[req.Route]
 	if !found {
 		return nil, fmt.Errorf("service not found in current node: %v", req.Route)
@@ -483,17 +662,27 @@ func (n *Node) HandleNotify(_ context.Context, req *clusterpb.NotifyMessage) (*c
 		return nil, err
 	}
 	msg := &message.Message{
-		Type:  message.Notify,
-		Route: req.Route,
-		Data:  req.Data,
-	}
-	n.handler.localProcess(handler, 0, s, msg)
+		Type:        message.Notify,
+		Route:       req.Route,
+		Data:        req.Data,
+		Traceparent: traceparentOf(ctx),
+	}
+	n.handler.localProcess(handler, 0, s, msg, &gateState{
+		uid:       req.Uid,
+		data:      req.Metadata,
+		claims:    req.Claims,
+		expiresAt: req.ExpiresAt,
+	})
 	s.AdvanceLastTime()
 	return &clusterpb.MemberHandleResponse{}, nil
 }
 
 // HandlePush implements the MemberServer interface
-func (n *Node) HandlePush(_ context.Context, req *clusterpb.PushMessage) (*clusterpb.MemberHandleResponse, error) {
+func (n *Node) HandlePush(ctx context.Context, req *clusterpb.PushMessage) (*clusterpb.MemberHandleResponse, error) {
+	span := continueSpan(n.Tracer, traceparentOf(ctx), "gate.push")
+	span.SetAttribute("route", req.Route)
+	defer span.Finish()
+
 	s := n.findSession(service.SID(req.SessionId))
 	if s == nil {
 		return &clusterpb.MemberHandleResponse{}, fmt.Errorf("session not found: %v", req.SessionId)
@@ -502,7 +691,10 @@ func (n *Node) HandlePush(_ context.Context, req *clusterpb.PushMessage) (*clust
 }
 
 // HandleResponse implements the MemberServer interface
-func (n *Node) HandleResponse(_ context.Context, req *clusterpb.ResponseMessage) (*clusterpb.MemberHandleResponse, error) {
+func (n *Node) HandleResponse(ctx context.Context, req *clusterpb.ResponseMessage) (*clusterpb.MemberHandleResponse, error) {
+	span := continueSpan(n.Tracer, traceparentOf(ctx), "gate.response")
+	defer span.Finish()
+
 	s := n.findSession(service.SID(req.SessionId))
 	if s == nil {
 		return &clusterpb.MemberHandleResponse{}, fmt.Errorf("session not found: %v", req.SessionId)
@@ -532,11 +724,32 @@ func (n *Node) SessionClosed(_ context.Context, req *clusterpb.SessionClosedRequ
 	delete(n.sessions, sid)
 	n.mu.Unlock()
 	if found {
-		scheduler.Run(func() { session.Lifetime.Close(s) })
+		if ac, ok := s.NetworkEntity().(*acceptor); ok {
+			atomic.StoreInt32(&ac.reason, req.Reason)
+		}
+		n.Scheduler.Run(func() { n.Lifecycle.NotifyClosed(s, session.CloseReason(req.Reason)) })
 	}
 	return &clusterpb.SessionClosedResponse{}, nil
 }
 
+// SyncSession implements the MemberServer interface
+func (n *Node) SyncSession(_ context.Context, req *clusterpb.SyncSessionRequest) (*clusterpb.SyncSessionResponse, error) {
+	s := n.findSession(service.SID(req.SessionId))
+	if s == nil {
+		return &clusterpb.SyncSessionResponse{}, fmt.Errorf("session not found: %v", req.SessionId)
+	}
+	// an empty UID means the session is unbound by the backend
+	if req.Uid == "" {
+		s.Unbind()
+	} else if s.UID() != req.Uid {
+		if err := s.Bind(req.Uid); err != nil {
+			return &clusterpb.SyncSessionResponse{}, err
+		}
+	}
+	decodeSessionData(s, n.SyncKeys, req.Metadata)
+	return &clusterpb.SyncSessionResponse{}, nil
+}
+
 // CloseSession implements the MemberServer interface
 func (n *Node) CloseSession(_ context.Context, req *clusterpb.CloseSessionRequest) (*clusterpb.CloseSessionResponse, error) {
 	sid := service.SID(req.SessionId)
@@ -545,7 +758,7 @@ func (n *Node) CloseSession(_ context.Context, req *clusterpb.CloseSessionReques
 	delete(n.sessions, sid)
 	n.mu.Unlock()
 	if found {
-		s.Close()
+		closeKicked(s, req.KickReason)
 	}
 	return &clusterpb.CloseSessionResponse{}, nil
 }
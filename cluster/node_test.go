@@ -34,6 +34,13 @@ func (c *GateComponent) Test2(session *session.Session, ping *testdata.Ping) err
 	return session.Response(&testdata.Pong{Content: "gate server pong2"})
 }
 
+func (c *GateComponent) Login(session *session.Session, ping *testdata.Ping) error {
+	if err := session.Bind(ping.Content); err != nil {
+		return err
+	}
+	return session.Response(&testdata.Pong{Content: "login " + session.UID()})
+}
+
 func (c *GameComponent) Test(session *session.Session, _ []byte) error {
 	return session.Push("test", &testdata.Pong{Content: "game server pong"})
 }
@@ -51,10 +58,10 @@ func (s *nodeSuite) TestNodeStartup(c *C) {
 	masterComps.Register(&MasterComponent{})
 	masterNode := &cluster.Node{
 		Options: cluster.Options{
-			IsMaster:   true,
-			Components: masterComps,
+			ServiceAddr: "127.0.0.1:4450",
+			IsMaster:    true,
+			Components:  masterComps,
 		},
-		ServiceAddr: "127.0.0.1:4450",
 	}
 	err := masterNode.Startup()
 	c.Assert(err, IsNil)
@@ -65,11 +72,11 @@ func (s *nodeSuite) TestNodeStartup(c *C) {
 	member1Comps.Register(&GateComponent{})
 	memberNode1 := &cluster.Node{
 		Options: cluster.Options{
+			ServiceAddr:  "127.0.0.1:14451",
 			RegistryAddr: "127.0.0.1:4450",
 			GateAddr:     "127.0.0.1:14452",
 			Components:   member1Comps,
 		},
-		ServiceAddr: "127.0.0.1:14451",
 	}
 	err = memberNode1.Startup()
 	c.Assert(err, IsNil)
@@ -83,10 +90,10 @@ func (s *nodeSuite) TestNodeStartup(c *C) {
 	member2Comps.Register(&GameComponent{})
 	memberNode2 := &cluster.Node{
 		Options: cluster.Options{
+			ServiceAddr:  "127.0.0.1:24451",
 			RegistryAddr: "127.0.0.1:4450",
 			Components:   member2Comps,
 		},
-		ServiceAddr: "127.0.0.1:24451",
 	}
 	err = memberNode2.Startup()
 	c.Assert(err, IsNil)
@@ -137,4 +144,20 @@ func (s *nodeSuite) TestNodeStartup(c *C) {
 	err = connector.Notify("MasterComponent.Test", &testdata.Ping{Content: "ping"})
 	c.Assert(err, IsNil)
 	c.Assert(strings.Contains(<-onResult, "master server pong"), IsTrue)
+
+	err = connector.Request("GateComponent.Login", &testdata.Ping{Content: "u1001"}, func(data interface{}) {
+		onResult <- string(data.([]byte))
+	})
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(<-onResult, "login u1001"), IsTrue)
+
+	records, err := memberNode2.FindSessionByUID("u1001")
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 1)
+	c.Assert(records[0].GateAddr, Equals, "127.0.0.1:14451")
+
+	err = memberNode2.PushToUID("u1001", "test", &testdata.Pong{Content: "push to uid"})
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(<-onResult, "push to uid"), IsTrue)
+	c.Assert(memberNode2.PushToUID("u1002", "test", nil), Equals, cluster.ErrUIDNotFound)
 }
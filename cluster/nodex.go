@@ -21,18 +21,16 @@
 package cluster
 
 import (
+	"context"
 	"expvar"
-	"html/template"
+	"fmt"
 	"net"
 	"net/http"
-	"net/http/pprof"
 	"runtime"
 	"sort"
 	"strconv"
 	"time"
 
-	"github.com/nano-kit/go-nano/internal/env"
-	"github.com/nano-kit/go-nano/internal/log"
 	"github.com/nano-kit/go-nano/session"
 )
 
@@ -46,30 +44,39 @@ func publishvar(name string, f func() interface{}) {
 	}
 }
 
-func (n *Node) startMonitor() {
+// startMonitor starts the node monitor, an error is returned if it can not
+// listen on MonitorAddr. It is not started if MonitorAddr is not specified
+// and no port is available near the service address.
+func (n *Node) startMonitor() error {
 	if n.MonitorAddr == "" {
 		n.MonitorAddr = determineMonitorAddr(n.ServiceAddr)
 	}
 	if n.MonitorAddr == "" {
-		log.Print("can not start node monitor")
-		return
+		logger.Warn("can not start node monitor")
+		return nil
 	}
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/debug/pprof/", pprof.Index)
-	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
-	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
-	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
-	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
-	mux.Handle("/debug/vars", expvar.Handler())
-	mux.HandleFunc("/debug/nano/node", n.nodeInfo)
+	handler := n.monitorHandler()
 	publishvar("gomaxprocs", gomaxprocs)
 
+	listener, err := net.Listen("tcp", n.MonitorAddr)
+	if err != nil {
+		return fmt.Errorf("start node monitor: %v", err)
+	}
+	server := &http.Server{Handler: handler}
+	n.mu.Lock()
+	n.monitor = server
+	n.mu.Unlock()
+
 	go func() {
+		var err error
 		if len(n.TSLCertificate) != 0 {
-			log.Fatal(http.ListenAndServeTLS(n.MonitorAddr, n.TSLCertificate, n.TSLKey, mux))
+			err = server.ServeTLS(listener, n.TSLCertificate, n.TSLKey)
 		} else {
-			log.Fatal(http.ListenAndServe(n.MonitorAddr, mux))
+			err = server.Serve(listener)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			logger.Error("node monitor stopped", "addr", n.MonitorAddr, "err", err)
 		}
 	}()
 
@@ -77,7 +84,24 @@ func (n *Node) startMonitor() {
 	if len(n.TSLCertificate) != 0 {
 		monitorURL = "https://" + n.MonitorAddr
 	}
-	log.Print("node monitor running at", monitorURL)
+	logger.Info("node monitor running", "url", monitorURL)
+	return nil
+}
+
+// stopMonitor shuts down the node monitor, the requests being served are
+// closed if they are not completed until ctx is done
+func (n *Node) stopMonitor(ctx context.Context) {
+	n.mu.Lock()
+	server := n.monitor
+	n.monitor = nil
+	n.mu.Unlock()
+
+	if server == nil {
+		return
+	}
+	if err := server.Shutdown(ctx); err != nil {
+		server.Close()
+	}
 }
 
 // Members returns the cluster managed members
@@ -145,30 +169,9 @@ func (n *Node) removeStaleSession() {
 	deadline := time.Now().Add(-2 * time.Hour)
 	for id, session := range n.sessions {
 		if session.LastTime().Before(deadline) {
-			if env.Debug {
-				log.Printf("close stale session ID=%d, UID=%d", session.ID(), session.UID())
-			}
+			logger.Debug("close stale session", "sid", session.ID(), "uid", session.UID())
 			delete(n.sessions, id)
 			session.Close()
 		}
 	}
 }
-
-func (n *Node) nodeInfo(w http.ResponseWriter, r *http.Request) {
-	const tmplPath = "./tmpl/"
-	nodeTmpl, err := template.ParseFiles(
-		tmplPath+"node.html",
-		tmplPath+"components.html",
-		tmplPath+"remotes.html",
-		tmplPath+"members.html",
-		tmplPath+"sessions.html",
-	)
-	if err != nil {
-		log.Print(err)
-		return
-	}
-	if err := nodeTmpl.Execute(w, n); err != nil {
-		log.Print(err)
-		return
-	}
-}
@@ -0,0 +1,29 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/nano-kit/go-nano/internal/message"
+	"github.com/nano-kit/go-nano/pipeline"
+)
+
+func TestGateIngress(t *testing.T) {
+	pipe := pipeline.New()
+	var ctx *pipeline.Context
+	pipeline.Middlewares(pipeline.StageOf(pipe, pipeline.GateIngress)).Use("Room.*", func(c *pipeline.Context) error {
+		ctx = c
+		return c.Respond([]byte("room closed"))
+	})
+
+	n := newTestNode(t, Options{}, pipe)
+	h := n.handler
+	a, _ := newTestAgent(n)
+
+	h.processMessage(a, &message.Message{Type: message.Request, ID: 1, Route: "Room.Join"})
+	if ctx == nil || ctx.Handler == nil || ctx.Service == nil || ctx.Service.Name != "Room" {
+		t.Fatalf("the handler should be resolved before routing, ctx=%+v", ctx)
+	}
+	if len(a.chSend) != 1 {
+		t.Fatalf("expect the short-circuit response, got %d messages", len(a.chSend))
+	}
+}
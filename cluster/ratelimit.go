@@ -0,0 +1,36 @@
+package cluster
+
+import (
+	"github.com/nano-kit/go-nano/internal/message"
+	"github.com/nano-kit/go-nano/ratelimit"
+	"github.com/nano-kit/go-nano/session"
+)
+
+// rateLimit is the ingress stage which decides whether the message of the
+// client session should be processed, the limited message is dropped or responded according to the
+// action, and the session is kicked after repeated violations
+func (n *Node) rateLimit(a *agent, msg *message.Message) bool {
+	l := n.rateLimiter()
+	if l == nil {
+		return true
+	}
+
+	switch l.Allow(int64(a.session.ID()), a.session.UID(), msg.Route) {
+	case ratelimit.Allowed:
+		return true
+
+	case ratelimit.Kick:
+		n.metrics.rateLimited.With("kicked").Inc()
+		logger.Warn("kick session exceeding rate limit", "sid", a.session.ID(), "uid", a.session.UID(), "route", msg.Route)
+		a.closeWith(session.CloseRateLimited)
+		return false
+	}
+
+	n.metrics.rateLimited.With("limited").Inc()
+	if config := l.Config(); config.Action == ratelimit.Respond && msg.Type == message.Request {
+		if err := a.ResponseMid(msg.ID, config.Response); err != nil {
+			logger.Error("respond rate limited request failed", "sid", a.session.ID(), "err", err)
+		}
+	}
+	return false
+}
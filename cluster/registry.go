@@ -0,0 +1,468 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nano-kit/go-nano/cluster/clusterpb"
+	"github.com/nano-kit/go-nano/internal/message"
+	"github.com/nano-kit/go-nano/service"
+	"github.com/nano-kit/go-nano/session"
+)
+
+type (
+	// BindPolicy decides what happens when the number of sessions bound to
+	// a UID exceeds the limitation
+	BindPolicy struct {
+		MaxSessions int  // max concurrent sessions of a UID, zero means unlimited
+		RejectNew   bool // reject the new binding instead of kicking the oldest sessions
+	}
+
+	// SessionRegistry records the sessions bound to each UID across the cluster.
+	// The master holds an in-memory registry by default, an implementation backed
+	// by a shared store can be used by all nodes instead. Bind is called by
+	// session.Session.Bind in the handlers, so the implementations should bound
+	// the time of the calls to the store.
+	SessionRegistry interface {
+		// Bind records that the session is bound to uid, and returns the sessions
+		// which should be kicked to satisfy the policy. ErrDuplicateLogin should
+		// be returned if the binding is rejected by the policy.
+		Bind(uid string, record *clusterpb.SessionRecord, policy BindPolicy) ([]*clusterpb.SessionRecord, error)
+		// Unbind removes the record of the session
+		Unbind(uid string, record *clusterpb.SessionRecord) error
+		// Find returns the sessions bound to uid, the oldest first
+		Find(uid string) ([]*clusterpb.SessionRecord, error)
+		// Purge removes all records of sessions connected to the gate
+		Purge(gateAddr string) error
+	}
+
+	memoryRegistry struct {
+		mu      sync.RWMutex
+		records map[string][]*clusterpb.SessionRecord // uid map to sessions
+	}
+
+	remoteRegistry struct {
+		node *Node
+	}
+)
+
+// Predefined bind policies
+var (
+	// KickPrevious allows a single session per UID, the previous session is kicked
+	KickPrevious = BindPolicy{MaxSessions: 1}
+	// RejectDuplicate allows a single session per UID, the new binding is rejected
+	RejectDuplicate = BindPolicy{MaxSessions: 1, RejectNew: true}
+)
+
+func sameRecord(a, b *clusterpb.SessionRecord) bool {
+	return a.GateAddr == b.GateAddr && a.SessionId == b.SessionId
+}
+
+// NewMemoryRegistry returns a SessionRegistry which stores records in memory
+func NewMemoryRegistry() SessionRegistry {
+	return &memoryRegistry{records: map[string][]*clusterpb.SessionRecord{}}
+}
+
+func (r *memoryRegistry) Bind(uid string, record *clusterpb.SessionRecord, policy BindPolicy) ([]*clusterpb.SessionRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	records := r.records[uid]
+	for _, rec := range records {
+		if sameRecord(rec, record) {
+			return nil, nil
+		}
+	}
+
+	var kicked []*clusterpb.SessionRecord
+	if max := policy.MaxSessions; max > 0 && len(records) >= max {
+		if policy.RejectNew {
+			return nil, ErrDuplicateLogin
+		}
+		n := len(records) - max + 1
+		kicked = append(kicked, records[:n]...)
+		records = append([]*clusterpb.SessionRecord(nil), records[n:]...)
+	}
+	r.records[uid] = append(records, record)
+	return kicked, nil
+}
+
+func (r *memoryRegistry) Unbind(uid string, record *clusterpb.SessionRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	records := r.records[uid]
+	for i, rec := range records {
+		if sameRecord(rec, record) {
+			records = append(records[:i:i], records[i+1:]...)
+			break
+		}
+	}
+	if len(records) == 0 {
+		delete(r.records, uid)
+	} else {
+		r.records[uid] = records
+	}
+	return nil
+}
+
+func (r *memoryRegistry) Find(uid string) ([]*clusterpb.SessionRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	records := r.records[uid]
+	return append([]*clusterpb.SessionRecord(nil), records...), nil
+}
+
+func (r *memoryRegistry) Purge(gateAddr string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for uid, records := range r.records {
+		var remain []*clusterpb.SessionRecord
+		for _, rec := range records {
+			if rec.GateAddr != gateAddr {
+				remain = append(remain, rec)
+			}
+		}
+		if len(remain) == 0 {
+			delete(r.records, uid)
+		} else {
+			r.records[uid] = remain
+		}
+	}
+	return nil
+}
+
+// sessionRPCTimeout bounds the calls to the session registry of master and to
+// the gates kicking, pushing to, synchronizing or listing their sessions, a
+// slow or unavailable member fails the calls rather than blocking the handlers
+const sessionRPCTimeout = 3 * time.Second
+
+func (r *remoteRegistry) client() (clusterpb.MasterClient, error) {
+	pool, err := r.node.rpcClient.getConnPool(r.node.RegistryAddr)
+	if err != nil {
+		return nil, err
+	}
+	return clusterpb.NewMasterClient(pool.Get()), nil
+}
+
+func (r *remoteRegistry) Bind(uid string, record *clusterpb.SessionRecord, policy BindPolicy) ([]*clusterpb.SessionRecord, error) {
+	client, err := r.client()
+	if err != nil {
+		return nil, err
+	}
+	request := &clusterpb.BindSessionRequest{
+		Uid:         uid,
+		Record:      record,
+		MaxSessions: int32(policy.MaxSessions),
+		RejectNew:   policy.RejectNew,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sessionRPCTimeout)
+	defer cancel()
+	resp, err := client.BindSession(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Rejected {
+		return nil, ErrDuplicateLogin
+	}
+	return resp.Kicked, nil
+}
+
+func (r *remoteRegistry) Unbind(uid string, record *clusterpb.SessionRecord) error {
+	client, err := r.client()
+	if err != nil {
+		return err
+	}
+	request := &clusterpb.UnbindSessionRequest{Uid: uid, Record: record}
+	ctx, cancel := context.WithTimeout(context.Background(), sessionRPCTimeout)
+	defer cancel()
+	_, err = client.UnbindSession(ctx, request)
+	return err
+}
+
+func (r *remoteRegistry) Find(uid string) ([]*clusterpb.SessionRecord, error) {
+	client, err := r.client()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sessionRPCTimeout)
+	defer cancel()
+	resp, err := client.FindSession(ctx, &clusterpb.FindSessionRequest{Uid: uid})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Records, nil
+}
+
+// Purge is done by master when a member unregisters
+func (r *remoteRegistry) Purge(_ string) error {
+	return nil
+}
+
+// BindSession implements the MasterServer interface
+func (c *cluster) BindSession(_ context.Context, req *clusterpb.BindSessionRequest) (*clusterpb.BindSessionResponse, error) {
+	if req.Record == nil {
+		return nil, ErrInvalidRegisterReq
+	}
+	policy := BindPolicy{MaxSessions: int(req.MaxSessions), RejectNew: req.RejectNew}
+	kicked, err := c.currentNode.registry.Bind(req.Uid, req.Record, policy)
+	if err == ErrDuplicateLogin {
+		return &clusterpb.BindSessionResponse{Rejected: true}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &clusterpb.BindSessionResponse{Kicked: kicked}, nil
+}
+
+// UnbindSession implements the MasterServer interface
+func (c *cluster) UnbindSession(_ context.Context, req *clusterpb.UnbindSessionRequest) (*clusterpb.UnbindSessionResponse, error) {
+	if req.Record == nil {
+		return nil, ErrInvalidRegisterReq
+	}
+	if err := c.currentNode.registry.Unbind(req.Uid, req.Record); err != nil {
+		return nil, err
+	}
+	return &clusterpb.UnbindSessionResponse{}, nil
+}
+
+// FindSession implements the MasterServer interface
+func (c *cluster) FindSession(_ context.Context, req *clusterpb.FindSessionRequest) (*clusterpb.FindSessionResponse, error) {
+	records, err := c.currentNode.registry.Find(req.Uid)
+	if err != nil {
+		return nil, err
+	}
+	return &clusterpb.FindSessionResponse{Records: records}, nil
+}
+
+func (n *Node) initRegistry() {
+	switch {
+	case n.SessionRegistry != nil:
+		n.registry = n.SessionRegistry
+	case n.IsMaster || n.RegistryAddr == "":
+		n.registry = NewMemoryRegistry()
+	default:
+		n.registry = &remoteRegistry{node: n}
+	}
+}
+
+// bindSession records the UID binding to the session directory and kicks
+// the sessions exceeding the limitation of bind policy, the previous UID of
+// the session is unbound only if the new binding succeeds
+func (n *Node) bindSession(s *session.Session, gateAddr string, sid service.SID, uid string) error {
+	record := &clusterpb.SessionRecord{GateAddr: gateAddr, SessionId: int64(sid)}
+
+	// the directory is maintained even if no policy is enforced, but the
+	// binding should not fail because of the directory is unavailable
+	var policy BindPolicy
+	if n.BindPolicy != nil {
+		policy = *n.BindPolicy
+	}
+	kicked, err := n.registry.Bind(uid, record, policy)
+	if err != nil {
+		if n.BindPolicy != nil {
+			return err
+		}
+		logger.Error("record session binding failed", "sid", sid, "uid", uid, "err", err)
+	}
+
+	if old := s.UID(); old != "" && old != uid {
+		if err := n.registry.Unbind(old, record); err != nil {
+			logger.Error("unbind session failed", "sid", sid, "uid", old, "err", err)
+		}
+	}
+	for _, k := range kicked {
+		logger.Debug("kick session bound to the uid", "sid", k.SessionId, "uid", uid, "member", k.GateAddr)
+		if err := n.kickSession(k, ""); err != nil {
+			logger.Error("kick session failed", "sid", k.SessionId, "member", k.GateAddr, "err", err)
+		}
+	}
+	return nil
+}
+
+// unbindSession removes the UID binding of the closed gate session
+func (n *Node) unbindSession(s *session.Session) {
+	uid := s.UID()
+	if uid == "" {
+		return
+	}
+	record := &clusterpb.SessionRecord{GateAddr: n.ServiceAddr, SessionId: int64(s.ID())}
+	if err := n.registry.Unbind(uid, record); err != nil {
+		logger.Error("unbind session failed", "sid", s.ID(), "uid", uid, "err", err)
+	}
+}
+
+// kickSession closes the session which may be connected to another gate, the
+// kick packet with the reason is sent to the client first unless the reason
+// is empty
+func (n *Node) kickSession(record *clusterpb.SessionRecord, reason string) error {
+	if record.GateAddr == n.ServiceAddr {
+		if s := n.findSession(service.SID(record.SessionId)); s != nil {
+			closeKicked(s, reason)
+		}
+		return nil
+	}
+	if n.rpcClient == nil {
+		return ErrMemberNotRegistered
+	}
+
+	pool, err := n.rpcClient.getConnPool(record.GateAddr)
+	if err != nil {
+		return err
+	}
+	client := clusterpb.NewMemberClient(pool.Get())
+	request := &clusterpb.CloseSessionRequest{SessionId: record.SessionId, KickReason: reason}
+	ctx, cancel := context.WithTimeout(context.Background(), sessionRPCTimeout)
+	defer cancel()
+	_, err = client.CloseSession(ctx, request)
+	return err
+}
+
+// PushToUID pushes message to all sessions bound to uid across the cluster
+func (n *Node) PushToUID(uid, route string, v interface{}) error {
+	records, err := n.registry.Find(uid)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return ErrUIDNotFound
+	}
+	data, err := message.SerializeWith(n.serializer(), v)
+	if err != nil {
+		return err
+	}
+	return n.pushToRecords(records, route, data)
+}
+
+// PushToUIDs pushes message to all sessions bound to the uids across the
+// cluster, the offline uids are ignored
+func (n *Node) PushToUIDs(uids []string, route string, v interface{}) error {
+	data, err := message.SerializeWith(n.serializer(), v)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for _, uid := range uids {
+		records, err := n.registry.Find(uid)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if err := n.pushToRecords(records, route, data); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+func (n *Node) pushToRecords(records []*clusterpb.SessionRecord, route string, data []byte) error {
+	var lastErr error
+	for _, record := range records {
+		if err := n.pushToSession(record, route, data); err != nil {
+			logger.Error("push to session failed", "sid", record.SessionId, "member", record.GateAddr, "route", route, "err", err)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// pushToSession delivers the message via the gate which owns the session
+func (n *Node) pushToSession(record *clusterpb.SessionRecord, route string, data []byte) error {
+	if record.GateAddr == n.ServiceAddr {
+		s := n.findSession(service.SID(record.SessionId))
+		if s == nil {
+			return fmt.Errorf("session not found: %v", record.SessionId)
+		}
+		return s.Push(route, data)
+	}
+	if n.rpcClient == nil {
+		return ErrMemberNotRegistered
+	}
+
+	pool, err := n.rpcClient.getConnPool(record.GateAddr)
+	if err != nil {
+		return err
+	}
+	client := clusterpb.NewMemberClient(pool.Get())
+	request := &clusterpb.PushMessage{
+		SessionId: record.SessionId,
+		Route:     route,
+		Data:      data,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sessionRPCTimeout)
+	defer cancel()
+	_, err = client.HandlePush(ctx, request)
+	return err
+}
+
+// FindSessionByUID returns the sessions bound to uid across the cluster
+func (n *Node) FindSessionByUID(uid string) ([]*clusterpb.SessionRecord, error) {
+	return n.registry.Find(uid)
+}
+
+var (
+	defaultMu   sync.RWMutex
+	defaultNode *Node // the node started by nano.Listen
+)
+
+// SetDefaultNode makes n the node used by the package level FindSessionByUID,
+// PushToUID and PushToUIDs, it is cleared when n is shut down. The nodes of
+// the other servers in the process are used through their own methods.
+func SetDefaultNode(n *Node) {
+	defaultMu.Lock()
+	defaultNode = n
+	defaultMu.Unlock()
+}
+
+func clearDefaultNode(n *Node) {
+	defaultMu.Lock()
+	if defaultNode == n {
+		defaultNode = nil
+	}
+	defaultMu.Unlock()
+}
+
+func getDefaultNode() (*Node, error) {
+	defaultMu.RLock()
+	n := defaultNode
+	defaultMu.RUnlock()
+	if n == nil {
+		return nil, ErrNodeNotStarted
+	}
+	return n, nil
+}
+
+// FindSessionByUID returns the sessions bound to uid across the cluster,
+// it looks up the registry of the default node, see SetDefaultNode.
+func FindSessionByUID(uid string) ([]*clusterpb.SessionRecord, error) {
+	n, err := getDefaultNode()
+	if err != nil {
+		return nil, err
+	}
+	return n.FindSessionByUID(uid)
+}
+
+// PushToUID pushes message to all sessions bound to uid across the cluster
+// via the default node
+func PushToUID(uid, route string, v interface{}) error {
+	n, err := getDefaultNode()
+	if err != nil {
+		return err
+	}
+	return n.PushToUID(uid, route, v)
+}
+
+// PushToUIDs pushes message to all sessions bound to the uids across the
+// cluster via the default node
+func PushToUIDs(uids []string, route string, v interface{}) error {
+	n, err := getDefaultNode()
+	if err != nil {
+		return err
+	}
+	return n.PushToUIDs(uids, route, v)
+}
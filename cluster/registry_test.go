@@ -0,0 +1,82 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/nano-kit/go-nano/cluster/clusterpb"
+	"github.com/nano-kit/go-nano/mock"
+	"github.com/nano-kit/go-nano/session"
+)
+
+func TestMemoryRegistry(t *testing.T) {
+	r := NewMemoryRegistry()
+	s1 := &clusterpb.SessionRecord{GateAddr: "gate1", SessionId: 1}
+	s2 := &clusterpb.SessionRecord{GateAddr: "gate2", SessionId: 2}
+	s3 := &clusterpb.SessionRecord{GateAddr: "gate1", SessionId: 3}
+
+	kicked, err := r.Bind("uid", s1, KickPrevious)
+	if err != nil || len(kicked) != 0 {
+		t.Fatalf("unexpected bind result: %v, %v", kicked, err)
+	}
+	// binding the same session again is idempotent
+	if kicked, _ = r.Bind("uid", s1, KickPrevious); len(kicked) != 0 {
+		t.Fatalf("same session should not be kicked: %v", kicked)
+	}
+	if _, err = r.Bind("uid", s2, RejectDuplicate); err != ErrDuplicateLogin {
+		t.Fatalf("expect %v, got %v", ErrDuplicateLogin, err)
+	}
+	kicked, err = r.Bind("uid", s2, KickPrevious)
+	if err != nil || len(kicked) != 1 || !sameRecord(kicked[0], s1) {
+		t.Fatalf("previous session should be kicked: %v, %v", kicked, err)
+	}
+
+	if kicked, _ = r.Bind("uid", s3, BindPolicy{MaxSessions: 2}); len(kicked) != 0 {
+		t.Fatalf("unexpected kicked sessions: %v", kicked)
+	}
+	records, _ := r.Find("uid")
+	if len(records) != 2 || !sameRecord(records[0], s2) || !sameRecord(records[1], s3) {
+		t.Fatalf("unexpected records: %v", records)
+	}
+
+	r.Unbind("uid", s2)
+	r.Purge("gate1")
+	if records, _ = r.Find("uid"); len(records) != 0 {
+		t.Fatalf("records should be removed: %v", records)
+	}
+}
+
+func TestBindSessionRejected(t *testing.T) {
+	n := &Node{
+		Options:  Options{ServiceAddr: "gate1", BindPolicy: &RejectDuplicate},
+		registry: NewMemoryRegistry(),
+	}
+	s := session.New(mock.NewNetworkEntity())
+	if err := n.bindSession(s, n.ServiceAddr, s.ID(), "u1"); err != nil {
+		t.Fatal(err)
+	}
+	s.Bind("u1")
+	n.registry.Bind("u2", &clusterpb.SessionRecord{GateAddr: "gate2", SessionId: 1}, BindPolicy{})
+
+	// the rejected binding keeps the previous one
+	if err := n.bindSession(s, n.ServiceAddr, s.ID(), "u2"); err != ErrDuplicateLogin {
+		t.Fatalf("expect %v, got %v", ErrDuplicateLogin, err)
+	}
+	if records, _ := n.registry.Find("u1"); len(records) != 1 {
+		t.Fatalf("previous binding should be kept: %v", records)
+	}
+}
+
+func TestAcceptorBindWithoutPolicy(t *testing.T) {
+	n := &Node{registry: NewMemoryRegistry()}
+	ac := &acceptor{sid: 1, gateAddr: "gate1", node: n}
+	s := session.New(ac)
+	ac.session = s
+
+	// the gate records the binding when the UID is synchronized
+	if err := s.Bind("u1"); err != nil {
+		t.Fatal(err)
+	}
+	if records, _ := n.registry.Find("u1"); len(records) != 0 {
+		t.Fatalf("acceptor should not record the binding: %v", records)
+	}
+}
@@ -0,0 +1,105 @@
+package cluster
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/nano-kit/go-nano/internal/env"
+	"github.com/nano-kit/go-nano/ratelimit"
+)
+
+// Reloadable contains the settings which can be changed while the node is
+// running. The heartbeat applies to the sessions established after reload,
+// the timeouts and rate limits apply to all sessions immediately.
+type Reloadable struct {
+	Heartbeat       time.Duration
+	ReadIdleTimeout time.Duration
+	WriteTimeout    time.Duration
+	RateLimit       *ratelimit.Config
+}
+
+// ReloadReport is the result of reloading the configuration
+type ReloadReport struct {
+	Applied         []string `json:"applied"`         // the settings changed live
+	RestartRequired []string `json:"restartRequired"` // the settings changed but not applied until restart
+}
+
+// Reload applies the settings to the running node, the rate limiter is
+// replaced only if the rate limits are changed, which resets the counters.
+// The Options of the node keep the settings the node is started with.
+func (n *Node) Reload(r Reloadable) {
+	n.reloadMu.Lock()
+	defer n.reloadMu.Unlock()
+
+	current := n.reloadableLocked()
+	n.reloaded = &r
+	if current.Heartbeat != r.Heartbeat {
+		n.hrd = handshakeResponse(n.heartbeatLocked(), negotiation{})
+	}
+	if !reflect.DeepEqual(current.RateLimit, r.RateLimit) {
+		n.limiter = nil
+		if r.RateLimit != nil {
+			n.limiter = ratelimit.New(*r.RateLimit)
+		}
+	}
+}
+
+// reloadableLocked returns the settings in effect, which are the ones applied
+// by Reload or the Options if the node is never reloaded
+func (n *Node) reloadableLocked() Reloadable {
+	if n.reloaded != nil {
+		return *n.reloaded
+	}
+	return Reloadable{
+		Heartbeat:       n.Heartbeat,
+		ReadIdleTimeout: n.ReadIdleTimeout,
+		WriteTimeout:    n.WriteTimeout,
+		RateLimit:       n.RateLimit,
+	}
+}
+
+// heartbeat returns the heartbeat interval of client sessions
+func (n *Node) heartbeat() time.Duration {
+	n.reloadMu.RLock()
+	defer n.reloadMu.RUnlock()
+	return n.heartbeatLocked()
+}
+
+func (n *Node) heartbeatLocked() time.Duration {
+	if hb := n.reloadableLocked().Heartbeat; hb > 0 {
+		return hb
+	}
+	return env.Heartbeat
+}
+
+func (n *Node) readIdleTimeout() time.Duration {
+	n.reloadMu.RLock()
+	defer n.reloadMu.RUnlock()
+	return n.reloadableLocked().ReadIdleTimeout
+}
+
+func (n *Node) writeTimeout() time.Duration {
+	n.reloadMu.RLock()
+	defer n.reloadMu.RUnlock()
+	return n.reloadableLocked().WriteTimeout
+}
+
+func (n *Node) rateLimiter() *ratelimit.Limiter {
+	n.reloadMu.RLock()
+	defer n.reloadMu.RUnlock()
+	return n.limiter
+}
+
+// handshakeData returns the handshake response data, which is encoded on
+// startup or on demand for the node is not started or the protocol
+// extensions are negotiated
+func (n *Node) handshakeData(neg negotiation) []byte {
+	n.reloadMu.RLock()
+	hrd := n.hrd
+	n.reloadMu.RUnlock()
+
+	if hrd != nil && neg == (negotiation{}) {
+		return hrd
+	}
+	return handshakeResponse(n.heartbeat(), neg)
+}
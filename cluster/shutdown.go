@@ -0,0 +1,197 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	"github.com/nano-kit/go-nano/internal/codec"
+	"github.com/nano-kit/go-nano/internal/message"
+	"github.com/nano-kit/go-nano/internal/packet"
+	"github.com/nano-kit/go-nano/session"
+)
+
+const (
+	// defaultShutdownTimeout is the drain timeout of Node.Shutdown if
+	// ShutdownOptions.ShutdownTimeout is not specified
+	defaultShutdownTimeout = 10 * time.Second
+
+	// KickReasonRestarting is the reason of the kick packet sent to the
+	// clients when the node shuts down gracefully
+	KickReasonRestarting = "server restarting"
+
+	// drainPollInterval is the interval of checking whether the node is drained
+	drainPollInterval = 10 * time.Millisecond
+)
+
+// ShutdownOptions controls the graceful shutdown of the node
+type ShutdownOptions struct {
+	ShutdownTimeout  time.Duration // the drain timeout of Node.Shutdown, defaults to 10 seconds
+	ShutdownRedirect string        // the address the kicked clients should reconnect to, optional
+}
+
+// Kick is the payload of the kick packet, which tells the client why the
+// connection is broken and where to reconnect
+type Kick struct {
+	Reason   string `json:"reason"`
+	Redirect string `json:"redirect,omitempty"`
+}
+
+// encodeKick encodes the kick packet
+func encodeKick(k Kick) ([]byte, error) {
+	data, err := json.Marshal(k)
+	if err != nil {
+		return nil, err
+	}
+	return codec.Encode(packet.Kick, data)
+}
+
+// stop closes the die channel of the node, which stops the repeated tasks,
+// the periodic flushing and the remaining client sessions of the node
+func (n *Node) stop() {
+	n.dieOnce.Do(func() {
+		if n.die != nil {
+			close(n.die)
+		}
+	})
+}
+
+// closeKicked sends the kick packet with the reason to the client session
+// and closes it, the kick packet is omitted if the reason is empty
+func closeKicked(s *session.Session, reason string) {
+	if a, ok := s.NetworkEntity().(*agent); ok && reason != "" {
+		data, err := encodeKick(Kick{Reason: reason})
+		if err != nil {
+			logger.Error("encode kick packet failed", "sid", s.ID(), "err", err)
+		} else {
+			a.kick(data)
+		}
+	}
+	s.Close()
+}
+
+// isDraining decides whether the node is shutting down gracefully
+func (n *Node) isDraining() bool {
+	return atomic.LoadInt32(&n.draining) == 1
+}
+
+// admitMessage is the ingress stage which drops the messages received while
+// the node is draining, only the in-flight messages are processed
+func (n *Node) admitMessage(a *agent, msg *message.Message) bool {
+	if n.isDraining() {
+		logger.Debug("drop message while shutting down", "sid", a.session.ID(), "route", msg.Route)
+		return false
+	}
+	return true
+}
+
+// ShutdownContext shuts down the node gracefully. It stops accepting client
+// connections, kicks the connected clients, waits for the in-flight handlers
+// and the send queues to drain until ctx is done, then closes the sessions
+// and shuts down the components. The error of ctx is returned if the node
+// is not drained in time.
+func (n *Node) ShutdownContext(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt32(&n.draining, 0, 1) {
+		return ErrNodeShuttingDown
+	}
+
+	n.stopAccepting(ctx)
+	n.kickSessions(Kick{Reason: KickReasonRestarting, Redirect: n.ShutdownRedirect})
+	err := n.waitDrained(ctx)
+	n.closeSessions(session.CloseServerShutdown)
+	if werr := n.waitSessionsClosed(ctx); err == nil {
+		err = werr
+	}
+	if err != nil {
+		logger.Warn("node is not drained in time", "err", err)
+		n.closeConns()
+	}
+
+	n.shutdownComponents()
+	n.stopMonitor(ctx)
+	return err
+}
+
+// stopAccepting closes the gate listener, the connected clients are kept
+func (n *Node) stopAccepting(ctx context.Context) {
+	n.mu.RLock()
+	listener, server := n.listener, n.httpServer
+	n.mu.RUnlock()
+
+	if listener != nil {
+		listener.Close()
+	}
+	if server != nil {
+		// the hijacked WebSocket connections are not affected
+		server.Shutdown(ctx)
+	}
+}
+
+// kickSessions sends the kick packet to all client sessions
+func (n *Node) kickSessions(k Kick) {
+	data, err := encodeKick(k)
+	if err != nil {
+		logger.Error("encode kick packet failed", "err", err)
+		return
+	}
+	for _, a := range n.agents() {
+		a.kick(data)
+	}
+}
+
+// waitDrained waits for the in-flight handlers and the send queues of the
+// client sessions to drain
+func (n *Node) waitDrained(ctx context.Context) error {
+	return waitUntil(ctx, func() bool {
+		if atomic.LoadInt64(&n.inflight) > 0 {
+			return false
+		}
+		for _, a := range n.agents() {
+			if len(a.chSend) > 0 {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// waitSessionsClosed waits for the connections of the client sessions to be closed
+func (n *Node) waitSessionsClosed(ctx context.Context) error {
+	return waitUntil(ctx, func() bool {
+		return len(n.agents()) == 0
+	})
+}
+
+// closeConns closes the connections of the client sessions which are not
+// drained in time
+func (n *Node) closeConns() {
+	for _, a := range n.agents() {
+		a.conn.Close()
+	}
+}
+
+// agents returns the client sessions connected to current node
+func (n *Node) agents() []*agent {
+	var agents []*agent
+	for _, s := range n.Sessions() {
+		if a, ok := s.NetworkEntity().(*agent); ok {
+			agents = append(agents, a)
+		}
+	}
+	return agents
+}
+
+func waitUntil(ctx context.Context, done func() bool) error {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for !done() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
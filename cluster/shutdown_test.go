@@ -0,0 +1,98 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/nano-kit/go-nano/internal/codec"
+	"github.com/nano-kit/go-nano/internal/packet"
+	"github.com/nano-kit/go-nano/session"
+)
+
+func TestShutdownContext(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	lc := session.NewLifecycle()
+	closed := make(chan session.CloseReason, 1)
+	lc.OnClosed(func(s *session.Session, reason session.CloseReason) {
+		// skip the connection of waiting for the gate
+		if reason != session.CloseClientClosed {
+			closed <- reason
+		}
+	})
+
+	opts := NewOptions()
+	opts.GateAddr = addr
+	opts.Lifecycle = lc
+	opts.ShutdownRedirect = "127.0.0.1:3250"
+	n := newTestNode(t, opts, nil)
+	n.cluster = newCluster(n)
+	go n.listenAndServe()
+	n.waitForGate(time.Second)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	for _, typ := range []packet.Type{packet.Handshake, packet.HandshakeAck} {
+		data, _ := codec.Encode(typ, nil)
+		if _, err := conn.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for len(n.agents()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := n.ShutdownContext(ctx); err != nil {
+		t.Fatalf("the node should be drained, got %v", err)
+	}
+	if err := n.ShutdownContext(ctx); err != ErrNodeShuttingDown {
+		t.Fatalf("expect %v, got %v", ErrNodeShuttingDown, err)
+	}
+	if _, err := net.Dial("tcp", addr); err == nil {
+		t.Fatal("the gate should stop accepting connections")
+	}
+
+	data, _ := ioutil.ReadAll(conn)
+	packets, err := codec.NewDecoder().Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kick *packet.Packet
+	for _, p := range packets {
+		if p.Type == packet.Kick {
+			kick = p
+		}
+	}
+	if kick == nil {
+		t.Fatalf("expect the kick packet, got %d packets", len(packets))
+	}
+	var k Kick
+	if err := json.Unmarshal(kick.Data, &k); err != nil {
+		t.Fatal(err)
+	}
+	if k.Reason != KickReasonRestarting || k.Redirect != "127.0.0.1:3250" {
+		t.Fatalf("unexpected kick %+v", k)
+	}
+	select {
+	case reason := <-closed:
+		if reason != session.CloseServerShutdown {
+			t.Fatalf("expect %s, got %s", session.CloseServerShutdown, reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnClosed not called")
+	}
+}
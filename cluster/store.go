@@ -0,0 +1,52 @@
+package cluster
+
+import (
+	"time"
+
+	"github.com/nano-kit/go-nano/session"
+)
+
+// attachStore attaches the session store of current node to the client
+// session, only the sessions connected to the gate are persisted, backends
+// receive the values by session data synchronization
+func (n *Node) attachStore(s *session.Session) {
+	if n.SessionStore == nil {
+		return
+	}
+	s.AttachStore(n.SessionStore, n.SessionFlushInterval <= 0, n.SessionStoreKeys...)
+}
+
+// flushSession saves the changed values of the session to the session store
+func (n *Node) flushSession(s *session.Session) {
+	if n.SessionStore == nil {
+		return
+	}
+	if err := s.Flush(); err != nil {
+		logger.Error("flush session failed", "sid", s.ID(), "uid", s.UID(), "err", err)
+	}
+}
+
+// flushSessions saves the changed values of all client sessions
+func (n *Node) flushSessions() {
+	for _, s := range n.Sessions() {
+		if _, ok := s.NetworkEntity().(*agent); ok {
+			n.flushSession(s)
+		}
+	}
+}
+
+// flushPeriodically flushes the sessions in its own goroutine until stop is
+// closed, the store I/O does not block the scheduler
+func (n *Node) flushPeriodically(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			n.flushSessions()
+		case <-stop:
+			return
+		}
+	}
+}
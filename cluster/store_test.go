@@ -0,0 +1,43 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nano-kit/go-nano/session"
+)
+
+func TestFlushPeriodically(t *testing.T) {
+	store := session.NewMemoryStore()
+	n := newTestNode(t, Options{SessionStore: store, SessionFlushInterval: time.Millisecond}, nil)
+	a, client := newTestAgent(n)
+	defer client.Close()
+	n.attachStore(a.session)
+	n.storeSession(a.session)
+	a.session.Bind("u1")
+	a.session.Set("level", 10)
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		n.flushPeriodically(time.Millisecond, stop)
+		close(done)
+	}()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if data, _ := store.Load("u1"); string(data["level"]) == "10" {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if data, _ := store.Load("u1"); string(data["level"]) != "10" {
+		t.Fatalf("session should be flushed, got %v", data)
+	}
+
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("flushing should be stopped")
+	}
+}
@@ -0,0 +1,176 @@
+package cluster
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/nano-kit/go-nano/auth"
+	"github.com/nano-kit/go-nano/session"
+)
+
+// encodeSessionData encodes the values of the declared keys, which are
+// synchronized between the gate session and the backend sessions.
+func encodeSessionData(s *session.Session, keys []string) map[string][]byte {
+	if len(keys) == 0 {
+		return nil
+	}
+	data := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		v := s.Value(key)
+		if v == nil {
+			continue
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			logger.Error("encode session data failed", "sid", s.ID(), "key", key, "err", err)
+			continue
+		}
+		data[key] = b
+	}
+	return data
+}
+
+// decodeSessionData stores the synchronized values to session s, the declared
+// keys that are absent in data are removed. The value will be decoded to the
+// type of the existing value if the session has one.
+func decodeSessionData(s *session.Session, keys []string, data map[string][]byte) {
+	for _, key := range keys {
+		b, found := data[key]
+		if !found {
+			s.Remove(key)
+			continue
+		}
+
+		var v interface{}
+		if old := s.Value(key); old != nil {
+			ptr := reflect.New(reflect.TypeOf(old))
+			if err := json.Unmarshal(b, ptr.Interface()); err == nil {
+				v = ptr.Elem().Interface()
+			}
+		}
+		if v == nil {
+			if err := json.Unmarshal(b, &v); err != nil {
+				logger.Error("decode session data failed", "sid", s.ID(), "key", key, "err", err)
+				continue
+			}
+		}
+		s.Set(key, v)
+	}
+}
+
+// encodeIdentity encodes the claims and the expiry of the identity
+// authenticated by the gate, the claims are nil if the session is not
+// authenticated
+func encodeIdentity(s *session.Session) ([]byte, int64) {
+	id := auth.IdentityOf(s)
+	if id == nil {
+		return nil, 0
+	}
+	claims, err := json.Marshal(id.Claims)
+	if err != nil {
+		logger.Error("encode identity failed", "sid", s.ID(), "uid", id.UID, "err", err)
+		return nil, 0
+	}
+	var expiresAt int64
+	if !id.ExpiresAt.IsZero() {
+		expiresAt = id.ExpiresAt.UnixNano()
+	}
+	return claims, expiresAt
+}
+
+// gateState is the UID, identity and synchronized session data of the gate
+// session, which are sent by the gate with every forwarded message
+type gateState struct {
+	uid       string
+	data      map[string][]byte
+	claims    []byte // nil if the gate session is not authenticated
+	expiresAt int64
+}
+
+// receiveSessionData merges the state sent by the gate to the backend session.
+// It is called by the handler task, so it never races with the handlers of
+// the session. The UID and the keys changed by the backend since the last
+// sync are kept, which are synchronized to the gate later; the others are
+// overwritten by the gate state, which becomes the new baseline of the sync.
+func (n *Node) receiveSessionData(a *acceptor, gate *gateState) {
+	s := a.session
+
+	a.syncMu.Lock()
+	syncedUID, syncedData := a.syncedUID, a.syncedData
+	a.syncMu.Unlock()
+
+	current := encodeSessionData(s, n.SyncKeys)
+	keys := make([]string, 0, len(n.SyncKeys))
+	for _, key := range n.SyncKeys {
+		if bytes.Equal(current[key], syncedData[key]) {
+			keys = append(keys, key)
+		}
+	}
+	decodeSessionData(s, keys, gate.data)
+
+	if uid := s.UID(); uid == syncedUID && uid != gate.uid {
+		if gate.uid == "" {
+			s.Unbind()
+		} else {
+			a.mirrorUID(gate.uid)
+			if err := s.Bind(gate.uid); err != nil {
+				logger.Error("bind session to the gate uid failed", "sid", s.ID(), "uid", gate.uid, "err", err)
+			}
+		}
+	}
+
+	baseline := make(map[string][]byte, len(n.SyncKeys))
+	for _, key := range n.SyncKeys {
+		if b, found := gate.data[key]; found {
+			baseline[key] = b
+		}
+	}
+	a.syncMu.Lock()
+	a.syncedUID, a.syncedData = gate.uid, baseline
+	a.syncMu.Unlock()
+
+	receiveIdentity(a, gate)
+}
+
+// receiveIdentity restores the identity authenticated by the gate to the
+// backend session, so that auth.IdentityOf works in the backend handlers.
+// The identity is decoded only if it is changed.
+func receiveIdentity(a *acceptor, gate *gateState) {
+	s := a.session
+	if gate.claims == nil {
+		if a.claims != nil {
+			auth.SetIdentity(s, nil)
+			a.claims, a.expiresAt = nil, 0
+		}
+		return
+	}
+	if id := auth.IdentityOf(s); id != nil && id.UID == gate.uid &&
+		bytes.Equal(a.claims, gate.claims) && a.expiresAt == gate.expiresAt {
+		return
+	}
+
+	id := &auth.Identity{UID: gate.uid}
+	if err := json.Unmarshal(gate.claims, &id.Claims); err != nil {
+		logger.Error("decode identity failed", "sid", s.ID(), "uid", gate.uid, "err", err)
+		return
+	}
+	if gate.expiresAt != 0 {
+		id.ExpiresAt = time.Unix(0, gate.expiresAt)
+	}
+	auth.SetIdentity(s, id)
+	a.claims, a.expiresAt = gate.claims, gate.expiresAt
+}
+
+func sameSessionData(a, b map[string][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, found := b[k]; !found || !bytes.Equal(v, w) {
+			return false
+		}
+	}
+	return true
+}
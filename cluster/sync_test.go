@@ -0,0 +1,196 @@
+package cluster
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nano-kit/go-nano/auth"
+	"github.com/nano-kit/go-nano/cluster/clusterpb"
+	"github.com/nano-kit/go-nano/service"
+	"github.com/nano-kit/go-nano/session"
+	"google.golang.org/grpc"
+)
+
+// gateRecorder records the calls to the gate member in order
+type gateRecorder struct {
+	clusterpb.MemberClient
+
+	mu    sync.Mutex
+	calls []string
+}
+
+func (g *gateRecorder) record(call string) {
+	g.mu.Lock()
+	g.calls = append(g.calls, call)
+	g.mu.Unlock()
+}
+
+func (g *gateRecorder) recorded() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.calls...)
+}
+
+func (g *gateRecorder) HandleResponse(context.Context, *clusterpb.ResponseMessage, ...grpc.CallOption) (*clusterpb.MemberHandleResponse, error) {
+	g.record("response")
+	return &clusterpb.MemberHandleResponse{}, nil
+}
+
+func (g *gateRecorder) SyncSession(_ context.Context, req *clusterpb.SyncSessionRequest, _ ...grpc.CallOption) (*clusterpb.SyncSessionResponse, error) {
+	if req.Uid != "" {
+		g.record("sync:" + req.Uid + ":" + string(req.Metadata["room"]))
+		return &clusterpb.SyncSessionResponse{}, nil
+	}
+	g.record("sync:" + string(req.Metadata["room"]))
+	return &clusterpb.SyncSessionResponse{}, nil
+}
+
+func TestSessionDataSync(t *testing.T) {
+	keys := []string{"level", "room", "vip"}
+
+	gate := session.New(nil)
+	gate.Set("level", 10)
+	gate.Set("room", "lobby")
+	gate.Set("private", "not synchronized")
+
+	backend := session.New(nil)
+	backend.Set("vip", true)
+	backend.Set("level", 1)
+
+	data := encodeSessionData(gate, keys)
+	if len(data) != 2 {
+		t.Fatalf("expect 2 synchronized keys, got %d", len(data))
+	}
+	decodeSessionData(backend, keys, data)
+
+	if backend.Int("level") != 10 {
+		t.Fatalf("level should keep int type, got %T", backend.Value("level"))
+	}
+	if backend.String("room") != "lobby" {
+		t.Fatalf("unexpected room %v", backend.Value("room"))
+	}
+	if backend.HasKey("vip") {
+		t.Fatal("vip should be removed")
+	}
+	if backend.HasKey("private") {
+		t.Fatal("private should not be synchronized")
+	}
+	if !sameSessionData(data, encodeSessionData(backend, keys)) {
+		t.Fatal("session data should be identical after sync")
+	}
+}
+
+func TestSessionSyncBeforeResponse(t *testing.T) {
+	n := &Node{sessions: map[service.SID]*session.Session{}}
+	n.SyncKeys = []string{"room"}
+	gate := &gateRecorder{}
+	ac := &acceptor{sid: 1, gateClient: gate, node: n}
+	s := session.New(ac)
+	ac.session = s
+	n.receiveSessionData(ac, &gateState{})
+
+	// the response is sent after the changes of session
+	s.Set("room", "lobby")
+	if err := ac.ResponseMid(1, []byte("ok")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ac.ResponseMid(2, []byte("ok")); err != nil {
+		t.Fatal(err)
+	}
+	if calls := gate.recorded(); len(calls) != 3 || calls[0] != `sync:"lobby"` || calls[1] != "response" || calls[2] != "response" {
+		t.Fatalf("unexpected calls %v", calls)
+	}
+
+	// the changes without response are flushed asynchronously
+	s.Set("room", "arena")
+	go ac.flushSession()
+	deadline := time.Now().Add(time.Second)
+	for len(gate.recorded()) < 4 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if calls := gate.recorded(); len(calls) != 4 || calls[3] != `sync:"arena"` {
+		t.Fatalf("unexpected calls %v", calls)
+	}
+
+	// the data sent by the gate is known by the gate
+	n.receiveSessionData(ac, &gateState{data: map[string][]byte{"room": []byte(`"hall"`)}})
+	if synced, err := ac.syncSession(); synced || err != nil {
+		t.Fatalf("expect nothing synchronized, got %v, %v", synced, err)
+	}
+}
+
+func TestSessionReceiveKeepsBackendChanges(t *testing.T) {
+	n := &Node{sessions: map[service.SID]*session.Session{}}
+	n.SyncKeys = []string{"room", "level"}
+	gate := &gateRecorder{}
+	ac := &acceptor{sid: 1, gateClient: gate, node: n}
+	s := session.New(ac)
+	ac.session = s
+	n.receiveSessionData(ac, &gateState{uid: "u1", data: map[string][]byte{"room": []byte(`"lobby"`), "level": []byte("1")}})
+	if s.UID() != "u1" || s.String("room") != "lobby" {
+		t.Fatalf("unexpected session %v %v", s.UID(), s.State())
+	}
+
+	// an earlier handler changed the room and the UID, which are not
+	// synchronized to the gate yet when the next message arrives
+	s.Set("room", "arena")
+	s.Unbind()
+	n.receiveSessionData(ac, &gateState{uid: "u1", data: map[string][]byte{"room": []byte(`"lobby"`), "level": []byte("2")}})
+	if s.String("room") != "arena" || s.UID() != "" {
+		t.Fatalf("backend changes are overwritten: %v %v", s.UID(), s.State())
+	}
+	if s.Int("level") != 2 {
+		t.Fatalf("gate changes are not applied: %v", s.State())
+	}
+
+	// the backend changes are still synchronized, the empty UID included
+	if synced, err := ac.syncSession(); !synced || err != nil {
+		t.Fatalf("expect changes synchronized, got %v, %v", synced, err)
+	}
+	if calls := gate.recorded(); len(calls) != 1 || calls[0] != `sync:"arena"` {
+		t.Fatalf("unexpected calls %v", calls)
+	}
+}
+
+func TestSessionSyncUnbind(t *testing.T) {
+	n := &Node{sessions: map[service.SID]*session.Session{}}
+	s := session.NewWith(1, nil)
+	s.Bind("u1")
+	n.sessions[s.ID()] = s
+
+	if _, err := n.SyncSession(context.Background(), &clusterpb.SyncSessionRequest{SessionId: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if s.UID() != "" {
+		t.Fatalf("expect the gate session unbound, got %q", s.UID())
+	}
+}
+
+func TestSessionReceiveIdentity(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+	a := auth.AuthenticatorFunc(func(token string) (*auth.Identity, error) {
+		return &auth.Identity{UID: "u1", Claims: map[string]interface{}{"role": token}, ExpiresAt: expiresAt}, nil
+	})
+	gate := session.New(nil)
+	if _, err := auth.Login(gate, a, "admin"); err != nil {
+		t.Fatal(err)
+	}
+	claims, exp := encodeIdentity(gate)
+
+	n := &Node{sessions: map[service.SID]*session.Session{}}
+	ac := &acceptor{sid: 1, gateClient: &gateRecorder{}, node: n}
+	ac.session = session.New(ac)
+	n.receiveSessionData(ac, &gateState{uid: gate.UID(), claims: claims, expiresAt: exp})
+	id := auth.IdentityOf(ac.session)
+	if id == nil || id.UID != "u1" || id.Claims["role"] != "admin" || !id.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("unexpected identity %+v", id)
+	}
+
+	// the identity is removed after the gate session is unauthenticated
+	n.receiveSessionData(ac, &gateState{})
+	if id := auth.IdentityOf(ac.session); id != nil {
+		t.Fatalf("expect the identity removed, got %+v", id)
+	}
+}
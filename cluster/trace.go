@@ -0,0 +1,55 @@
+package cluster
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/nano-kit/go-nano/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+// withTraceparent attaches the trace context to the outgoing gRPC metadata
+func withTraceparent(ctx context.Context, traceparent string) context.Context {
+	if traceparent == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, trace.HeaderName, traceparent)
+}
+
+// traceparentOf returns the trace context in the incoming gRPC metadata
+func traceparentOf(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(trace.HeaderName); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// continueSpan starts a span of the trace propagated from other nodes, nil is
+// returned if there is no trace context
+func continueSpan(tracer *trace.Tracer, traceparent, name string) *trace.Span {
+	if traceparent == "" {
+		return nil
+	}
+	return tracer.StartFrom(traceparent, name)
+}
+
+// traceContext holds the trace context of the handler being executed, it is
+// set by the handler task and read by the messages sent from any goroutine
+type traceContext struct {
+	v atomic.Value
+}
+
+// set replaces the trace context, an empty string resets it
+func (c *traceContext) set(traceparent string) {
+	c.v.Store(traceparent)
+}
+
+// get returns the trace context, empty if no handler is being executed
+func (c *traceContext) get() string {
+	traceparent, _ := c.v.Load().(string)
+	return traceparent
+}
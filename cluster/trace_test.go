@@ -0,0 +1,60 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nano-kit/go-nano/internal/message"
+	"github.com/nano-kit/go-nano/pipeline"
+	"github.com/nano-kit/go-nano/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestTraceparentMetadata(t *testing.T) {
+	const tp = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	md, _ := metadata.FromOutgoingContext(withTraceparent(context.Background(), tp))
+	if got := traceparentOf(metadata.NewIncomingContext(context.Background(), md)); got != tp {
+		t.Fatalf("expect %s, got %s", tp, got)
+	}
+	if got := traceparentOf(context.Background()); got != "" {
+		t.Fatalf("expect empty traceparent, got %s", got)
+	}
+	if continueSpan(trace.New(nil), "", "gate.push") != nil {
+		t.Fatal("the span without trace context should not be started")
+	}
+}
+
+func TestGateTrace(t *testing.T) {
+	exporter := trace.NewMemoryExporter()
+	pipe := pipeline.New()
+	var a *agent
+	var traced string
+	pipeline.Middlewares(pipe.Inbound()).Use("Room.*", func(c *pipeline.Context) error {
+		traced = a.trace.get()
+		return c.Next()
+	})
+	n := newTestNode(t, Options{Tracer: trace.New(exporter)}, pipe)
+	h := n.handler
+	a, _ = newTestAgent(n)
+
+	h.processMessage(a, &message.Message{Type: message.Notify, Route: "Room.Join", Data: []byte("{}")})
+	deadline := time.Now().Add(time.Second)
+	for len(exporter.Find("handler")) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	gates, handlers := exporter.Find("gate"), exporter.Find("handler")
+	if len(gates) != 1 || len(handlers) != 1 {
+		t.Fatalf("expect the gate and handler spans, got %d and %d", len(gates), len(handlers))
+	}
+	if handlers[0].Context.TraceID != gates[0].Context.TraceID || handlers[0].Parent != gates[0].Context.SpanID {
+		t.Fatal("the handler span should be the child of the gate span")
+	}
+	if traced != handlers[0].Traceparent() {
+		t.Fatal("the messages sent by the handler should be traced as children of the handler span")
+	}
+	if a.trace.get() != "" {
+		t.Fatal("the trace context should be reset after the handler returns")
+	}
+}
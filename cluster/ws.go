@@ -23,6 +23,8 @@ package cluster
 import (
 	"io"
 	"net"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -31,42 +33,40 @@ import (
 // wsConn is an adapter to t.Conn, which implements all t.Conn
 // interface base on *websocket.Conn
 type wsConn struct {
-	conn   *websocket.Conn
-	typ    int // message type
-	reader io.Reader
+	conn    *websocket.Conn
+	reader  io.Reader // reader of current message, nil before the first read
+	deflate bool      // the permessage-deflate extension is negotiated
 }
 
-// newWSConn return an initialized *wsConn
-func newWSConn(conn *websocket.Conn) (*wsConn, error) {
-	c := &wsConn{conn: conn}
-
-	t, r, err := conn.NextReader()
-	if err != nil {
-		return nil, err
-	}
-
-	c.typ = t
-	c.reader = r
-
-	return c, nil
+// newWSConn return an initialized *wsConn, the first message is read by Read
+// so that it is covered by the read deadlines of the connection
+func newWSConn(conn *websocket.Conn, deflate bool) *wsConn {
+	return &wsConn{conn: conn, deflate: deflate}
 }
 
 // Read reads data from the connection.
 // Read can be made to time out and return an Error with Timeout() == true
 // after a fixed time limit; see SetDeadline and SetReadDeadline.
 func (c *wsConn) Read(b []byte) (int, error) {
-	n, err := c.reader.Read(b)
-	if err != nil && err != io.EOF {
-		return n, err
-	} else if err == io.EOF {
-		_, r, err := c.conn.NextReader()
-		if err != nil {
-			return 0, err
+	for {
+		if c.reader == nil {
+			_, r, err := c.conn.NextReader()
+			if err != nil {
+				return 0, err
+			}
+			c.reader = r
 		}
-		c.reader = r
-	}
 
-	return n, nil
+		n, err := c.reader.Read(b)
+		if err == io.EOF {
+			c.reader = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
 }
 
 // Write writes data to the connection.
@@ -135,3 +135,17 @@ func (c *wsConn) SetReadDeadline(t time.Time) error {
 func (c *wsConn) SetWriteDeadline(t time.Time) error {
 	return c.conn.SetWriteDeadline(t)
 }
+
+// offersDeflate reports whether the WebSocket client offers the
+// permessage-deflate extension, which is accepted by the upgrader with
+// EnableCompression
+func offersDeflate(header http.Header) bool {
+	for _, value := range header["Sec-Websocket-Extensions"] {
+		for _, ext := range strings.Split(value, ",") {
+			if name := strings.TrimSpace(strings.Split(ext, ";")[0]); strings.EqualFold(name, "permessage-deflate") {
+				return true
+			}
+		}
+	}
+	return false
+}
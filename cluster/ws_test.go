@@ -0,0 +1,50 @@
+package cluster
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWSHandshakeTimeout(t *testing.T) {
+	opts := NewOptions()
+	opts.WSPath = "/nano"
+	opts.HandshakeTimeout = 50 * time.Millisecond
+	n := newTestNode(t, opts, nil)
+	admission, err := newAdmission(AdmissionOptions{MaxConnections: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.admission = admission
+	n.cluster = newCluster(n)
+	n.setupWSHandler()
+	server := httptest.NewServer(n.ServeMux)
+	defer server.Close()
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/nano", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// the silent client is closed after the handshake timeout
+	client.SetReadDeadline(time.Now().Add(time.Second))
+	if _, _, err := client.ReadMessage(); err == nil || isTimeout(err) {
+		t.Fatalf("expect the connection closed by the server, got %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for n.admission.connections() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the admission slot should be released")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	again, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/nano", nil)
+	if err != nil {
+		t.Fatalf("expect the connection admitted again, got %v", err)
+	}
+	again.Close()
+}
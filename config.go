@@ -0,0 +1,493 @@
+package nano
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nano-kit/go-nano/auth"
+	"github.com/nano-kit/go-nano/cluster"
+	"github.com/nano-kit/go-nano/internal/codec"
+	"github.com/nano-kit/go-nano/logging"
+	"github.com/nano-kit/go-nano/ratelimit"
+	jsonserializer "github.com/nano-kit/go-nano/serialize/json"
+	"github.com/nano-kit/go-nano/serialize/protobuf"
+	"github.com/nano-kit/go-nano/trace"
+)
+
+// ErrUnknownConfigFormat indicates the format of configuration file is not registered
+var ErrUnknownConfigFormat = errors.New("unknown configuration format")
+
+type (
+	// Config is the configuration file of a node, which maps to the options.
+	// The durations are written as "30s", "1m" and so on. The options taking
+	// code, such as the components, pipeline, session store and lifecycle,
+	// can not be configured and should be appended to Config.Options.
+	Config struct {
+		Master           bool     `json:"master"`
+		RegistryAddr     string   `json:"registryAddr"`
+		RegisterInterval Duration `json:"registerInterval"`
+		GateAddr         string   `json:"gateAddr"`
+		GateID           uint16   `json:"gateID"`
+		Label            string   `json:"label"`
+
+		IsWebsocket   bool       `json:"isWebsocket"`
+		WSPath        string     `json:"wsPath"`
+		WSCompression bool       `json:"wsCompression"`
+		TLS           *TLSConfig `json:"tls"`
+		Origins       []string   `json:"origins"` // the allowed `Origin` of WebSocket, "*" allows all
+
+		Serializer string            `json:"serializer"` // "json" or "protobuf"
+		Dictionary map[string]uint16 `json:"dictionary"`
+		Debug      bool              `json:"debug"`
+		LogLevels  map[string]string `json:"logLevels"` // the level of packages, "" is the default level
+
+		Heartbeat       Duration         `json:"heartbeat"`
+		ReadIdleTimeout Duration         `json:"readIdleTimeout"`
+		WriteTimeout    Duration         `json:"writeTimeout"`
+		Compression     int              `json:"compression"` // the threshold of compressing message data
+		MaxPacketSize   int              `json:"maxPacketSize"`
+		MaxMessageSize  int              `json:"maxMessageSize"` // the limit of reassembling fragments, negative to reject fragments
+		RateLimit       *RateLimitConfig `json:"rateLimit"`
+		Admission       *AdmissionConfig `json:"admission"`
+		Shutdown        *ShutdownConfig  `json:"shutdown"`
+
+		Auth                 *AuthConfig         `json:"auth"`
+		SessionSync          []string            `json:"sessionSync"`
+		BindPolicy           *cluster.BindPolicy `json:"bindPolicy"`
+		SessionFlushInterval Duration            `json:"sessionFlushInterval"`
+
+		MonitorAddr  string             `json:"monitorAddr"` // the first free port after the service address by default
+		MonitorAuth  *cluster.BasicAuth `json:"monitorAuth"`
+		MonitorAdmin bool               `json:"monitorAdmin"` // serve the session admin endpoints without monitorAuth
+		Tracing      *TracingConfig     `json:"tracing"`
+	}
+
+	// TLSConfig is the certificate and key of WebSocket and the monitor
+	TLSConfig struct {
+		Certificate string `json:"certificate"`
+		Key         string `json:"key"`
+	}
+
+	// RateLimitConfig is the configuration of ratelimit.Config
+	RateLimitConfig struct {
+		Global     ratelimit.Limit            `json:"global"`
+		UID        ratelimit.Limit            `json:"uid"`
+		Session    ratelimit.Limit            `json:"session"`
+		Routes     map[string]ratelimit.Limit `json:"routes"`
+		Action     string                     `json:"action"`   // "drop" or "respond"
+		Response   json.RawMessage            `json:"response"` // the response of limited requests
+		KickAfter  int                        `json:"kickAfter"`
+		KickWindow Duration                   `json:"kickWindow"`
+	}
+
+	// AdmissionConfig is the configuration of cluster.AdmissionOptions
+	AdmissionConfig struct {
+		MaxConnections   int             `json:"maxConnections"`
+		MaxConnsPerIP    int             `json:"maxConnsPerIP"`
+		AcceptRate       ratelimit.Limit `json:"acceptRate"`
+		AllowCIDRs       []string        `json:"allowCIDRs"`
+		DenyCIDRs        []string        `json:"denyCIDRs"`
+		HandshakeTimeout Duration        `json:"handshakeTimeout"`
+	}
+
+	// ShutdownConfig is the configuration of cluster.ShutdownOptions
+	ShutdownConfig struct {
+		Timeout  Duration `json:"timeout"`
+		Redirect string   `json:"redirect"`
+	}
+
+	// AuthConfig is the configuration of the JWT authenticator
+	AuthConfig struct {
+		JWTKey    string          `json:"jwtKey"`
+		UIDClaim  string          `json:"uidClaim"`
+		Issuer    string          `json:"issuer"`
+		Audience  string          `json:"audience"`
+		Leeway    Duration        `json:"leeway"`
+		Whitelist []string        `json:"whitelist"`
+		Response  json.RawMessage `json:"response"` // the response of the requests rejected before authentication
+	}
+
+	// TracingConfig is the configuration of the OTLP exporter of spans
+	TracingConfig struct {
+		Endpoint    string `json:"endpoint"`
+		ServiceName string `json:"serviceName"`
+	}
+
+	// Duration is the time.Duration written as "30s" in configuration files
+	Duration time.Duration
+
+	// ConfigError is the invalid value of a configuration key, the key is
+	// the name of environment variable if the configuration is read from
+	// the environment
+	ConfigError struct {
+		Key string
+		Err error
+	}
+)
+
+func (e *ConfigError) Error() string {
+	return e.Key + ": " + e.Err.Error()
+}
+
+// Unwrap returns the cause of the error
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// configFormats converts the configuration files to JSON by the extension
+var configFormats = struct {
+	sync.RWMutex
+	toJSON map[string]func([]byte) ([]byte, error)
+}{toJSON: map[string]func([]byte) ([]byte, error){
+	".json": func(data []byte) ([]byte, error) { return data, nil },
+}}
+
+// RegisterConfigFormat registers the format of configuration files with the
+// extension, e.g. ".yaml", the function converts the file content to JSON
+func RegisterConfigFormat(ext string, toJSON func([]byte) ([]byte, error)) {
+	configFormats.Lock()
+	configFormats.toJSON[strings.ToLower(ext)] = toJSON
+	configFormats.Unlock()
+}
+
+// LoadOptions reads the configuration file and returns the options of it,
+// the file is also reloaded on SIGHUP, see WithConfigFile
+func LoadOptions(path string) ([]Option, error) {
+	c, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return append(c.Options(), WithConfigFile(path)), nil
+}
+
+// LoadConfig reads the configuration file, the format is decided by the
+// extension. Only JSON is built in, YAML and TOML are not shipped to keep
+// nano free of their dependencies, they can be registered by
+// RegisterConfigFormat with a converter to JSON, e.g. sigs.k8s.io/yaml.YAMLToJSON.
+func LoadConfig(path string) (*Config, error) {
+	configFormats.RLock()
+	toJSON, found := configFormats.toJSON[strings.ToLower(filepath.Ext(path))]
+	configFormats.RUnlock()
+	if !found {
+		return nil, fmt.Errorf("%s: %w", path, ErrUnknownConfigFormat)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = toJSON(data); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	config, err := ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+// ParseConfig parses the configuration in JSON, the unknown keys and the
+// invalid values are reported by ConfigError
+func ParseConfig(data []byte) (*Config, error) {
+	var tree interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	if err := checkValue(tree, configType, ""); err != nil {
+		return nil, err
+	}
+
+	config := &Config{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(config); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate checks the values of the configuration
+func (c *Config) Validate() error {
+	invalid := func(key string, format string, args ...interface{}) error {
+		return &ConfigError{Key: key, Err: fmt.Errorf(format, args...)}
+	}
+
+	for _, addr := range []struct{ key, value string }{
+		{"registryAddr", c.RegistryAddr},
+		{"gateAddr", c.GateAddr},
+		{"monitorAddr", c.MonitorAddr},
+	} {
+		if addr.value == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(addr.value); err != nil {
+			return invalid(addr.key, "%v", err)
+		}
+	}
+	if c.Master && c.RegistryAddr != "" {
+		return invalid("registryAddr", "should be empty for the master")
+	}
+	if c.TLS != nil && (c.TLS.Certificate == "" || c.TLS.Key == "") {
+		return invalid("tls", "both certificate and key are required")
+	}
+	if c.Serializer != "" && c.Serializer != "json" && c.Serializer != "protobuf" {
+		return invalid("serializer", "unknown serializer %q", c.Serializer)
+	}
+	if c.Compression < 0 {
+		return invalid("compression", "negative threshold %d", c.Compression)
+	}
+	if c.MaxPacketSize < 0 || c.MaxPacketSize > codec.MaxLength {
+		return invalid("maxPacketSize", "%d is out of range [0, %d]", c.MaxPacketSize, codec.MaxLength)
+	}
+	if c.MaxMessageSize > codec.MaxLength {
+		return invalid("maxMessageSize", "%d is larger than %d", c.MaxMessageSize, codec.MaxLength)
+	}
+	for pkg, name := range c.LogLevels {
+		if _, err := logging.ParseLevel(name); err != nil {
+			return invalid("logLevels."+pkg, "%v %q", err, name)
+		}
+	}
+	if c.RateLimit != nil {
+		if _, err := c.RateLimit.action(); err != nil {
+			return invalid("rateLimit.action", "%v", err)
+		}
+	}
+	if c.Admission != nil {
+		for _, cidrs := range []struct {
+			key    string
+			values []string
+		}{
+			{"admission.allowCIDRs", c.Admission.AllowCIDRs},
+			{"admission.denyCIDRs", c.Admission.DenyCIDRs},
+		} {
+			for i, cidr := range cidrs.values {
+				if _, err := cluster.ParseCIDR(cidr); err != nil {
+					return invalid(fmt.Sprintf("%s[%d]", cidrs.key, i), "%v", err)
+				}
+			}
+		}
+	}
+	if c.Auth != nil && c.Auth.JWTKey == "" {
+		return invalid("auth.jwtKey", "is required")
+	}
+	if c.Tracing != nil && c.Tracing.Endpoint == "" {
+		return invalid("tracing.endpoint", "is required")
+	}
+	return nil
+}
+
+// Options returns the options of the configuration
+func (c *Config) Options() []Option {
+	opts := c.nodeOptions()
+	opts = append(opts, c.componentOptions()...)
+	if c.GateID > 0 {
+		opts = append(opts, WithGateID(c.GateID))
+	}
+	if c.Debug {
+		opts = append(opts, WithDebugMode())
+	}
+	for pkg, name := range c.LogLevels {
+		level, _ := logging.ParseLevel(name)
+		opts = append(opts, WithLogLevel(pkg, level))
+	}
+	if len(c.Dictionary) > 0 {
+		opts = append(opts, WithDictionary(c.Dictionary))
+	}
+	return opts
+}
+
+// nodeOptions returns the options which only set the values of
+// cluster.Options, the process-wide settings are excluded
+func (c *Config) nodeOptions() []Option {
+	var opts []Option
+	if c.Master {
+		opts = append(opts, WithMaster())
+	}
+	if c.RegistryAddr != "" {
+		if c.RegisterInterval > 0 {
+			opts = append(opts, WithRegistryAddr(c.RegistryAddr, time.Duration(c.RegisterInterval)))
+		} else {
+			opts = append(opts, WithRegistryAddr(c.RegistryAddr))
+		}
+	}
+	if c.GateAddr != "" {
+		opts = append(opts, WithGateAddr(c.GateAddr))
+	}
+	if c.Label != "" {
+		opts = append(opts, WithLabel(c.Label))
+	}
+	if c.IsWebsocket {
+		opts = append(opts, WithIsWebsocket(true))
+	}
+	if c.WSPath != "" {
+		opts = append(opts, WithWSPath(c.WSPath))
+	}
+	if c.WSCompression {
+		opts = append(opts, WithWSCompression())
+	}
+	if c.TLS != nil {
+		opts = append(opts, WithTSLConfig(c.TLS.Certificate, c.TLS.Key))
+	}
+	if c.Heartbeat > 0 {
+		opts = append(opts, WithHeartbeatInterval(time.Duration(c.Heartbeat)))
+	}
+	if c.ReadIdleTimeout > 0 || c.WriteTimeout > 0 {
+		readIdle, write := time.Duration(c.ReadIdleTimeout), time.Duration(c.WriteTimeout)
+		opts = append(opts, func(opt *cluster.Options) {
+			// the timeout missing from the configuration keeps its value
+			if readIdle > 0 {
+				opt.ReadIdleTimeout = readIdle
+			}
+			if write > 0 {
+				opt.WriteTimeout = write
+			}
+		})
+	}
+	if c.Compression > 0 {
+		opts = append(opts, WithCompression(c.Compression))
+	}
+	if c.MaxPacketSize != 0 || c.MaxMessageSize != 0 {
+		opts = append(opts, WithPacketLimits(c.MaxPacketSize, c.MaxMessageSize))
+	}
+	if c.RateLimit != nil {
+		opts = append(opts, WithRateLimit(c.RateLimit.config()))
+	}
+	if a := c.Admission; a != nil {
+		opts = append(opts, WithAdmission(cluster.AdmissionOptions{
+			MaxConnections:   a.MaxConnections,
+			MaxConnsPerIP:    a.MaxConnsPerIP,
+			AcceptRate:       a.AcceptRate,
+			AllowCIDRs:       a.AllowCIDRs,
+			DenyCIDRs:        a.DenyCIDRs,
+			HandshakeTimeout: time.Duration(a.HandshakeTimeout),
+		}))
+	}
+	if s := c.Shutdown; s != nil {
+		opts = append(opts, WithShutdown(time.Duration(s.Timeout), s.Redirect))
+	}
+	if len(c.SessionSync) > 0 {
+		opts = append(opts, WithSessionSync(c.SessionSync...))
+	}
+	if c.BindPolicy != nil {
+		opts = append(opts, WithBindPolicy(*c.BindPolicy))
+	}
+	if c.SessionFlushInterval > 0 {
+		opts = append(opts, WithSessionFlushInterval(time.Duration(c.SessionFlushInterval)))
+	}
+	if c.MonitorAddr != "" {
+		opts = append(opts, WithMonitorAddr(c.MonitorAddr))
+	}
+	if a := c.MonitorAuth; a != nil {
+		opts = append(opts, WithMonitorAuth(a.Username, a.Password))
+	}
+	if c.MonitorAdmin {
+		opts = append(opts, WithMonitorAdmin())
+	}
+	return opts
+}
+
+// componentOptions returns the options which construct the serializer,
+// authenticator, origin checker and tracer
+func (c *Config) componentOptions() []Option {
+	var opts []Option
+	switch c.Serializer {
+	case "json":
+		opts = append(opts, WithSerializer(jsonserializer.NewSerializer()))
+	case "protobuf":
+		opts = append(opts, WithSerializer(protobuf.NewSerializer()))
+	}
+	if len(c.Origins) > 0 {
+		opts = append(opts, WithCheckOriginFunc(allowOrigins(c.Origins)))
+	}
+	if a := c.Auth; a != nil {
+		jwtOpts := []auth.JWTOption{auth.WithIssuer(a.Issuer), auth.WithAudience(a.Audience), auth.WithLeeway(time.Duration(a.Leeway))}
+		if a.UIDClaim != "" {
+			jwtOpts = append(jwtOpts, auth.WithUIDClaim(a.UIDClaim))
+		}
+		opts = append(opts, WithAuthenticator(auth.NewJWT([]byte(a.JWTKey), jwtOpts...), a.Whitelist...))
+		if len(a.Response) > 0 {
+			opts = append(opts, WithAuthResponse([]byte(a.Response)))
+		}
+	}
+	if t := c.Tracing; t != nil {
+		opts = append(opts, WithTracer(trace.New(trace.NewOTLPExporter(t.Endpoint, t.ServiceName))))
+	}
+	return opts
+}
+
+// allowOrigins returns the function accepting the listed origins
+func allowOrigins(origins []string) func(*http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		for _, o := range origins {
+			if o == "*" || o == origin {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func (r *RateLimitConfig) action() (ratelimit.Action, error) {
+	switch r.Action {
+	case "", "drop":
+		return ratelimit.Drop, nil
+	case "respond":
+		return ratelimit.Respond, nil
+	}
+	return ratelimit.Drop, fmt.Errorf("invalid action %q", r.Action)
+}
+
+func (r *RateLimitConfig) config() ratelimit.Config {
+	action, _ := r.action()
+	config := ratelimit.Config{
+		Global:     r.Global,
+		UID:        r.UID,
+		Session:    r.Session,
+		Routes:     r.Routes,
+		Action:     action,
+		KickAfter:  r.KickAfter,
+		KickWindow: time.Duration(r.KickWindow),
+	}
+	if len(r.Response) > 0 {
+		config.Response = []byte(r.Response)
+	}
+	return config
+}
+
+// MarshalJSON implements the json.Marshaler interface
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, the number is
+// taken as nanoseconds
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		var n int64
+		if err := json.Unmarshal(data, &n); err != nil {
+			return fmt.Errorf("invalid duration %s", data)
+		}
+		*d = Duration(n)
+		return nil
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
@@ -0,0 +1,328 @@
+package nano
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nano-kit/go-nano/cluster"
+	"github.com/nano-kit/go-nano/internal/env"
+	"github.com/nano-kit/go-nano/logging"
+	"github.com/nano-kit/go-nano/ratelimit"
+)
+
+func writeConfig(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nano-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := writeConfig(t, dir, "node.json", `{
+		"label": "gate-1",
+		"heartbeat": "15s",
+		"writeTimeout": "5s",
+		"rateLimit": {"session": {"rate": 10, "burst": 20}, "action": "respond", "response": {"code": 429}}
+	}`)
+	c, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opt := cluster.NewOptions()
+	for _, option := range c.Options() {
+		option(&opt)
+	}
+	if opt.Label != "gate-1" || opt.Heartbeat != 15*time.Second || opt.WriteTimeout != 5*time.Second {
+		t.Fatalf("unexpected options %+v", opt)
+	}
+	if opt.RateLimit == nil || opt.RateLimit.Session != (ratelimit.Limit{Rate: 10, Burst: 20}) ||
+		opt.RateLimit.Action != ratelimit.Respond || string(opt.RateLimit.Response.([]byte)) != `{"code": 429}` {
+		t.Fatalf("unexpected rate limit %+v", opt.RateLimit)
+	}
+
+	for content, key := range map[string]string{
+		`{"lable": "gate-1"}`:                                   "lable",
+		`{"heartbeat": "15"}`:                                   "heartbeat",
+		`{"gateID": 70000}`:                                     "gateID",
+		`{"rateLimit": {"session": {"burst": "many"}}}`:         "rateLimit.session.burst",
+		`{"admission": {"allowCIDRs": ["10.0.0.0/8", "10.0"]}}`: "admission.allowCIDRs[1]",
+		`{"logLevels": {"cluster": "verbose"}}`:                 "logLevels.cluster",
+		`{"rateLimit": {"action": "disconnect"}}`:               "rateLimit.action",
+		`{"tls": {"certificate": "cert.pem"}}`:                  "tls",
+		`{"serializer": "xml"}`:                                 "serializer",
+		`{"compression": -1}`:                                   "compression",
+		`{"maxPacketSize": 16777216}`:                           "maxPacketSize",
+		`{"maxMessageSize": 16777216}`:                          "maxMessageSize",
+		`{"monitorAddr": "3252"}`:                               "monitorAddr",
+	} {
+		_, err := LoadConfig(writeConfig(t, dir, "bad.json", content))
+		var ce *ConfigError
+		if !errors.As(err, &ce) || ce.Key != key {
+			t.Fatalf("%s: expect the error of key %q, got %v", content, key, err)
+		}
+	}
+
+	if _, err := LoadConfig(writeConfig(t, dir, "node.ini", "")); err == nil || !strings.Contains(err.Error(), ErrUnknownConfigFormat.Error()) {
+		t.Fatalf("expect %v, got %v", ErrUnknownConfigFormat, err)
+	}
+}
+
+func TestReload(t *testing.T) {
+	defer logging.ResetLevel("cluster")
+
+	dir, err := ioutil.TempDir("", "nano-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := writeConfig(t, dir, "node.json", `{"label": "gate-1", "heartbeat": "5s"}`)
+	c, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(append(c.Options(), WithServiceAddr(freeAddr(t)), WithConfigFile(path))...)
+	if _, err := s.Reload(c); err != ErrServerNotStarted {
+		t.Fatalf("expect %v, got %v", ErrServerNotStarted, err)
+	}
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop(context.Background())
+
+	writeConfig(t, dir, "node.json", `{
+		"label": "gate-2",
+		"heartbeat": "9s",
+		"logLevels": {"cluster": "debug"},
+		"rateLimit": {"global": {"rate": 100, "burst": 100}}
+	}`)
+	report, err := s.Node().Reloader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report.Applied, []string{"heartbeat", "rateLimit", "logLevels"}) ||
+		!reflect.DeepEqual(report.RestartRequired, []string{"label"}) {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if hb := handshakeHeartbeat(t, s.Node().GateAddr); hb != 9 {
+		t.Fatalf("expect heartbeat 9, got %v", hb)
+	}
+	if logging.LevelOf("cluster") != logging.LevelDebug {
+		t.Fatal("the log level should be reloaded")
+	}
+
+	// nothing is changed by reloading the same file
+	report, err = s.ReloadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Applied) != 0 || !reflect.DeepEqual(report.RestartRequired, []string{"label"}) {
+		t.Fatalf("unexpected report %+v", report)
+	}
+}
+
+func TestReloadKeepsInitialSettings(t *testing.T) {
+	defer logging.ResetLevel("session")
+
+	s := NewServer(WithServiceAddr(freeAddr(t)),
+		WithHeartbeatInterval(7*time.Second),
+		WithConnTimeouts(time.Minute, 2*time.Second),
+		WithLogLevel("session", logging.LevelDebug))
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop(context.Background())
+
+	// the settings missing from the configuration are kept
+	report, err := s.Reload(&Config{ReadIdleTimeout: Duration(30 * time.Second)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := s.Options()
+	if !reflect.DeepEqual(report.Applied, []string{"readIdleTimeout"}) || opts.ReadIdleTimeout != 30*time.Second ||
+		opts.WriteTimeout != 2*time.Second || opts.Heartbeat != 7*time.Second {
+		t.Fatalf("unexpected report %+v and options %+v", report, opts)
+	}
+	if logging.LevelOf("session") != logging.LevelDebug {
+		t.Fatal("the log level set in code should be kept")
+	}
+
+	// the setting removed from the configuration is restored
+	if report, err = s.Reload(&Config{}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report.Applied, []string{"readIdleTimeout"}) || s.Options().ReadIdleTimeout != time.Minute {
+		t.Fatalf("unexpected report %+v and options %+v", report, s.Options())
+	}
+
+	// the debug mode removed from the configuration is turned off
+	if report, err = s.Reload(&Config{Debug: true}); err != nil || !env.Debug {
+		t.Fatalf("debug mode is not applied: %+v, %v", report, err)
+	}
+	if report, err = s.Reload(&Config{}); err != nil {
+		t.Fatal(err)
+	}
+	if env.Debug || !reflect.DeepEqual(report.Applied, []string{"debug", "logLevels"}) {
+		t.Fatalf("debug mode is not reset: %+v", report)
+	}
+
+	// the process-wide settings are not reloaded with several servers running
+	other := NewServer(WithServiceAddr(freeAddr(t)))
+	if err := other.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Reload(&Config{Debug: true}); err != ErrMultipleServers || env.Debug {
+		t.Fatalf("expect %v, got %v", ErrMultipleServers, err)
+	}
+	if err := other.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Reload(&Config{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nano-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	opts, err := LoadOptions(writeConfig(t, dir, "gate.json", `{
+		"registryAddr": "127.0.0.1:3250",
+		"registerInterval": "5s",
+		"gateAddr": ":3251",
+		"isWebsocket": true,
+		"wsPath": "/nano",
+		"origins": ["https://example.com"],
+		"serializer": "json",
+		"compression": 1024,
+		"maxPacketSize": 16384,
+		"maxMessageSize": -1,
+		"wsCompression": true,
+		"admission": {"maxConnections": 100, "acceptRate": {"rate": 50, "burst": 10}, "handshakeTimeout": "3s", "denyCIDRs": ["10.0.0.1"]},
+		"shutdown": {"timeout": "20s", "redirect": "127.0.0.1:3261"},
+		"auth": {"jwtKey": "secret", "whitelist": ["Login.*"]},
+		"sessionSync": ["room"],
+		"bindPolicy": {"maxSessions": 1},
+		"monitorAddr": "127.0.0.1:3252",
+		"monitorAuth": {"username": "ops", "password": "secret"}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opt := cluster.NewOptions()
+	for _, option := range opts {
+		option(&opt)
+	}
+	if opt.RegistryAddr != "127.0.0.1:3250" || opt.RegisterInterval != 5*time.Second || opt.GateAddr != ":3251" ||
+		!opt.IsWebsocket || opt.WSPath != "/nano" || opt.Serializer == nil || opt.ConfigFile == "" ||
+		opt.CompressThreshold != 1024 || !opt.WSCompression || opt.MaxPacketSize != 16384 || opt.MaxMessageSize != -1 {
+		t.Fatalf("unexpected options %+v", opt)
+	}
+	if opt.MaxConnections != 100 || opt.AcceptRate.Burst != 10 || opt.HandshakeTimeout != 3*time.Second || len(opt.DenyCIDRs) != 1 ||
+		opt.ShutdownTimeout != 20*time.Second || opt.ShutdownRedirect != "127.0.0.1:3261" {
+		t.Fatalf("unexpected admission or shutdown options %+v", opt)
+	}
+	if opt.Authenticator == nil || !opt.AuthWhitelist.Allow("Login.Guest") || len(opt.SyncKeys) != 1 ||
+		opt.BindPolicy.MaxSessions != 1 || opt.MonitorAddr != "127.0.0.1:3252" || opt.MonitorAuth.Username != "ops" {
+		t.Fatalf("unexpected session options %+v", opt)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/nano", nil)
+	r.Header.Set("Origin", "https://evil.com")
+	if opt.CheckOrigin(r) {
+		t.Fatal("the origin should be rejected")
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	env := map[string]string{
+		"TEST_GATE_ADDR":                   ":3251",
+		"TEST_GATE_ID":                     "3",
+		"TEST_IS_WEBSOCKET":                "true",
+		"TEST_HEARTBEAT":                   "20s",
+		"TEST_LOG_LEVELS":                  "cluster=debug, session=warn",
+		"TEST_DICTIONARY":                  "Room.Join=1,Room.Leave=2",
+		"TEST_ADMISSION_MAX_CONNS_PER_IP":  "10",
+		"TEST_ADMISSION_ACCEPT_RATE_RATE":  "2.5",
+		"TEST_ADMISSION_DENY_CIDRS":        "10.0.0.0/8,192.168.0.0/16",
+		"TEST_RATE_LIMIT_ROUTES":           `{"Room.*": {"rate": 1, "burst": 2}}`,
+		"TEST_RATE_LIMIT_ACTION":           "respond",
+		"TEST_TLS":                         `{"certificate": "cert.pem", "key": "key.pem"}`,
+		"TEST_SESSION_FLUSH_INTERVAL":      "1m",
+		"TEST_MONITOR_AUTH_USERNAME":       "ops",
+		"TEST_MONITOR_AUTH_PASSWORD":       "secret",
+		"TEST_TRACING_ENDPOINT":            "http://localhost:4318",
+		"TEST_TRACING_SERVICE_NAME":        "gate",
+		"TEST_RATE_LIMIT_SESSION_BURST":    "20",
+		"TEST_RATE_LIMIT_SESSION_RATE":     "10",
+		"TEST_ADMISSION_HANDSHAKE_TIMEOUT": "2s",
+	}
+	setenv := func(key, value string) {
+		os.Setenv(key, value)
+		t.Cleanup(func() { os.Unsetenv(key) })
+	}
+	for k, v := range env {
+		setenv(k, v)
+	}
+
+	c, err := ConfigFromEnv("TEST")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.GateAddr != ":3251" || c.GateID != 3 || !c.IsWebsocket || c.Heartbeat != Duration(20*time.Second) ||
+		c.LogLevels["session"] != "warn" || c.Dictionary["Room.Leave"] != 2 || c.SessionFlushInterval != Duration(time.Minute) {
+		t.Fatalf("unexpected config %+v", c)
+	}
+	if c.Admission.MaxConnsPerIP != 10 || c.Admission.AcceptRate.Rate != 2.5 || len(c.Admission.DenyCIDRs) != 2 ||
+		c.Admission.HandshakeTimeout != Duration(2*time.Second) {
+		t.Fatalf("unexpected admission %+v", c.Admission)
+	}
+	if c.RateLimit.Routes["Room.*"].Burst != 2 || c.RateLimit.Session.Rate != 10 || c.RateLimit.Action != "respond" {
+		t.Fatalf("unexpected rate limit %+v", c.RateLimit)
+	}
+	if c.TLS.Key != "key.pem" || c.MonitorAuth.Password != "secret" || c.Tracing.ServiceName != "gate" {
+		t.Fatalf("unexpected config %+v", c)
+	}
+
+	for name, value := range map[string]string{
+		"TEST_GATE_ID":               "gate-1",
+		"TEST_HEARTBEAT":             "20",
+		"TEST_ADMISSION_DENY_CIDRS":  "10.0",
+		"TEST_LOG_LEVELS":            "cluster",
+		"TEST_RATE_LIMIT_ROUTES":     `{"Room.*": {"rate": "fast"}}`,
+		"TEST_TLS":                   `{"certificate": "cert.pem"}`,
+		"TEST_MONITOR_AUTH_USERNAME": "ops",
+	} {
+		if name == "TEST_MONITOR_AUTH_USERNAME" {
+			setenv("TEST_GATE_ADDR", "3251")
+			name = "TEST_GATE_ADDR"
+		} else {
+			setenv(name, value)
+		}
+		_, err := ConfigFromEnv("TEST")
+		var ce *ConfigError
+		if !errors.As(err, &ce) || ce.Key != name {
+			t.Fatalf("%s=%s: expect the error of %s, got %v", name, value, name, err)
+		}
+		setenv(name, env[name])
+	}
+}
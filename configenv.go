@@ -0,0 +1,440 @@
+package nano
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	configType   = reflect.TypeOf(Config{})
+	durationType = reflect.TypeOf(Duration(0))
+	rawType      = reflect.TypeOf(json.RawMessage(nil))
+)
+
+// OptionsFromEnv returns the options of the configuration read from the
+// environment variables, see ConfigFromEnv
+func OptionsFromEnv(prefix string) ([]Option, error) {
+	c, err := ConfigFromEnv(prefix)
+	if err != nil {
+		return nil, err
+	}
+	return c.Options(), nil
+}
+
+// ConfigFromEnv reads the configuration from the environment variables named
+// by the prefix and the keys in upper snake case, e.g. NANO_GATE_ADDR for the
+// key "gateAddr" and NANO_ADMISSION_MAX_CONNECTIONS for "admission.maxConnections".
+// The lists are separated by commas, the maps are written as "k1=v1,k2=v2",
+// and JSON is accepted for the lists, maps and objects.
+func ConfigFromEnv(prefix string) (*Config, error) {
+	tree, err := envTree(prefix, configType)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(tree)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		var ce *ConfigError
+		if errors.As(err, &ce) {
+			return nil, &ConfigError{Key: envKey(prefix, ce.Key), Err: ce.Err}
+		}
+		return nil, err
+	}
+	return config, nil
+}
+
+type configField struct {
+	key string
+	typ reflect.Type
+}
+
+// configFields returns the fields of the struct keyed by the JSON names
+func configFields(t reflect.Type) []configField {
+	var fields []configField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		key := f.Name
+		if tag := strings.Split(f.Tag.Get("json"), ",")[0]; tag == "-" {
+			continue
+		} else if tag != "" {
+			key = tag
+		}
+		fields = append(fields, configField{key: key, typ: f.Type})
+	}
+	return fields
+}
+
+// findField matches the key as encoding/json does, the exact name first
+func findField(fields []configField, key string) (configField, bool) {
+	for _, f := range fields {
+		if f.key == key {
+			return f, true
+		}
+	}
+	for _, f := range fields {
+		if strings.EqualFold(f.key, key) {
+			return f, true
+		}
+	}
+	return configField{}, false
+}
+
+// isObject decides whether the type is configured by nested keys
+func isObject(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
+
+func joinKey(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// checkValue checks the decoded JSON value against the type of the key, so
+// that the unknown keys and mismatched values are reported with the keys
+func checkValue(v interface{}, t reflect.Type, key string) error {
+	if v == nil {
+		return nil
+	}
+	mismatch := func(expect string) error {
+		return &ConfigError{Key: key, Err: fmt.Errorf("expect %s, got %s", expect, jsonKind(v))}
+	}
+
+	switch t {
+	case durationType:
+		switch x := v.(type) {
+		case string:
+			if _, err := time.ParseDuration(x); err != nil {
+				return &ConfigError{Key: key, Err: err}
+			}
+			return nil
+		case float64:
+			return nil
+		}
+		return mismatch("duration")
+	case rawType:
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return checkValue(v, t.Elem(), key)
+
+	case reflect.Struct:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return mismatch("object")
+		}
+		fields := configFields(t)
+		for _, k := range sortedKeys(m) {
+			f, found := findField(fields, k)
+			if !found {
+				return &ConfigError{Key: joinKey(key, k), Err: errors.New("unknown key")}
+			}
+			if err := checkValue(m[k], f.typ, joinKey(key, k)); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return mismatch("object")
+		}
+		for _, k := range sortedKeys(m) {
+			if err := checkValue(m[k], t.Elem(), joinKey(key, k)); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Slice:
+		s, ok := v.([]interface{})
+		if !ok {
+			return mismatch("list")
+		}
+		for i, e := range s {
+			if err := checkValue(e, t.Elem(), fmt.Sprintf("%s[%d]", key, i)); err != nil {
+				return err
+			}
+		}
+
+	case reflect.String:
+		if _, ok := v.(string); !ok {
+			return mismatch("string")
+		}
+
+	case reflect.Bool:
+		if _, ok := v.(bool); !ok {
+			return mismatch("boolean")
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := v.(float64)
+		if !ok || n != math.Trunc(n) {
+			return mismatch("integer")
+		}
+		if reflect.New(t).Elem().OverflowInt(int64(n)) {
+			return &ConfigError{Key: key, Err: fmt.Errorf("%v is out of range", n)}
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := v.(float64)
+		if !ok || n != math.Trunc(n) {
+			return mismatch("integer")
+		}
+		if n < 0 || reflect.New(t).Elem().OverflowUint(uint64(n)) {
+			return &ConfigError{Key: key, Err: fmt.Errorf("%v is out of range", n)}
+		}
+
+	case reflect.Float32, reflect.Float64:
+		if _, ok := v.(float64); !ok {
+			return mismatch("number")
+		}
+	}
+	return nil
+}
+
+func jsonKind(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return strconv.Quote(x)
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprint(v)
+}
+
+// envName returns the environment variable of the key, e.g. "maxConnsPerIP"
+// is MAX_CONNS_PER_IP
+func envName(prefix, key string) string {
+	var b strings.Builder
+	b.WriteString(prefix)
+	if prefix != "" {
+		b.WriteByte('_')
+	}
+	runes := []rune(key)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// envKey returns the environment variable configuring the key, the keys of
+// lists and maps are configured by the variable of the whole value
+func envKey(prefix, key string) string {
+	t := configType
+	name := prefix
+	for _, segment := range strings.Split(key, ".") {
+		if i := strings.IndexByte(segment, '['); i >= 0 {
+			segment = segment[:i]
+		}
+		f, found := findField(configFields(t), segment)
+		if !found {
+			break
+		}
+		name = envName(name, f.key)
+		if !isObject(f.typ) {
+			break
+		}
+		t = f.typ
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+	}
+	return name
+}
+
+// envTree reads the environment variables of the struct type into the JSON
+// tree, the variables of nested structs are prefixed by the keys
+func envTree(prefix string, t reflect.Type) (map[string]interface{}, error) {
+	tree := map[string]interface{}{}
+	for _, f := range configFields(t) {
+		name := envName(prefix, f.key)
+		if isObject(f.typ) {
+			elem := f.typ
+			for elem.Kind() == reflect.Ptr {
+				elem = elem.Elem()
+			}
+			if value, found := os.LookupEnv(name); found {
+				v, err := envJSON(name, value, f.typ)
+				if err != nil {
+					return nil, err
+				}
+				tree[f.key] = v
+				continue
+			}
+			sub, err := envTree(name, elem)
+			if err != nil {
+				return nil, err
+			}
+			if len(sub) > 0 {
+				tree[f.key] = sub
+			}
+			continue
+		}
+
+		value, found := os.LookupEnv(name)
+		if !found {
+			continue
+		}
+		v, err := envValue(name, value, f.typ)
+		if err != nil {
+			return nil, err
+		}
+		tree[f.key] = v
+	}
+	return tree, nil
+}
+
+// envJSON parses the variable written in JSON
+func envJSON(name, value string, t reflect.Type) (interface{}, error) {
+	var v interface{}
+	if err := json.Unmarshal([]byte(value), &v); err != nil {
+		return nil, &ConfigError{Key: name, Err: err}
+	}
+	if err := checkValue(v, t, ""); err != nil {
+		// the variable is the key, the key within the value is in the error
+		var ce *ConfigError
+		if errors.As(err, &ce) && ce.Key != "" {
+			return nil, &ConfigError{Key: name, Err: fmt.Errorf("%s: %v", ce.Key, ce.Err)}
+		}
+		if errors.As(err, &ce) {
+			return nil, &ConfigError{Key: name, Err: ce.Err}
+		}
+		return nil, err
+	}
+	return v, nil
+}
+
+// envValue parses the variable according to the type of the key
+func envValue(name, value string, t reflect.Type) (interface{}, error) {
+	invalid := func(err error) (interface{}, error) {
+		if ne, ok := err.(*strconv.NumError); ok {
+			err = ne.Err
+		}
+		return nil, &ConfigError{Key: name, Err: fmt.Errorf("%v %q", err, value)}
+	}
+
+	switch t {
+	case durationType:
+		if _, err := time.ParseDuration(value); err != nil {
+			return nil, &ConfigError{Key: name, Err: err}
+		}
+		return value, nil
+	case rawType:
+		return envJSON(name, value, t)
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return value, nil
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return invalid(err)
+		}
+		return b, nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, t.Bits())
+		if err != nil {
+			return invalid(err)
+		}
+		return n, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, t.Bits())
+		if err != nil {
+			return invalid(err)
+		}
+		return n, nil
+
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, t.Bits())
+		if err != nil {
+			return invalid(err)
+		}
+		return n, nil
+
+	case reflect.Slice:
+		if strings.HasPrefix(strings.TrimSpace(value), "[") {
+			return envJSON(name, value, t)
+		}
+		list := []interface{}{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			v, err := envValue(name, item, t.Elem())
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+
+	case reflect.Map:
+		if strings.HasPrefix(strings.TrimSpace(value), "{") || isObject(t.Elem()) {
+			return envJSON(name, value, t)
+		}
+		m := map[string]interface{}{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			kv := strings.SplitN(item, "=", 2)
+			if len(kv) != 2 {
+				return nil, &ConfigError{Key: name, Err: fmt.Errorf("expect key=value, got %q", item)}
+			}
+			v, err := envValue(name, strings.TrimSpace(kv[1]), t.Elem())
+			if err != nil {
+				return nil, err
+			}
+			m[strings.TrimSpace(kv[0])] = v
+		}
+		return m, nil
+	}
+	return nil, &ConfigError{Key: name, Err: fmt.Errorf("unsupported type %s", t)}
+}
@@ -29,3 +29,11 @@ var (
 	ErrMemberNotFound     = errors.New("member not found in the group")
 	ErrSessionDuplication = errors.New("session already existed in the current group")
 )
+
+// Errors of the server lifecycle
+var (
+	ErrNotRunning       = errors.New("nano is not running")
+	ErrServerStarted    = errors.New("server has been started")
+	ErrServerNotStarted = errors.New("server is not started")
+	ErrMultipleServers  = errors.New("several servers are running")
+)
@@ -30,42 +30,79 @@ import (
 // Codec constants.
 const (
 	HeadLength    = 4
-	MaxPacketSize = 64 * 1024
+	MaxPacketSize = 64 * 1024 // the default maximum packet size of decoder
+	MaxLength     = 1<<24 - 1 // the maximum packet length encoded in 3 bytes
 )
 
-// ErrPacketSizeExceeded is the error used for encode/decode.
-var ErrPacketSizeExceeded = errors.New("codec: packet size exceeded")
+// Errors that could be occurred in packet codec
+var (
+	ErrPacketSizeExceeded  = errors.New("codec: packet size exceeded")
+	ErrMessageSizeExceeded = errors.New("codec: reassembled message size exceeded")
+)
 
 // A Decoder reads and decodes network data slice
 type Decoder struct {
 	buf  *bytes.Buffer
 	size int  // last packet length
 	typ  byte // last packet type
+
+	maxPacketSize  int    // the maximum size of packets
+	maxMessageSize int    // the maximum size of the Data packet reassembled from fragments
+	fragments      []byte // the fragments received before the Data packet
 }
 
-// NewDecoder returns a new decoder that used for decode network bytes slice.
+// NewDecoder returns a new decoder that used for decode network bytes slice,
+// the packets larger than MaxPacketSize and the fragments are rejected.
 func NewDecoder() *Decoder {
+	return NewDecoderWithLimits(MaxPacketSize, 0)
+}
+
+// NewDecoderWithLimits returns a new decoder which rejects the packets larger
+// than maxPacketSize, and reassembles the fragments into the Data packet up
+// to maxMessageSize
+func NewDecoderWithLimits(maxPacketSize, maxMessageSize int) *Decoder {
 	return &Decoder{
-		buf:  bytes.NewBuffer(nil),
-		size: -1,
+		buf:            bytes.NewBuffer(nil),
+		size:           -1,
+		maxPacketSize:  maxPacketSize,
+		maxMessageSize: maxMessageSize,
 	}
 }
 
 func (c *Decoder) forward() error {
 	header := c.buf.Next(HeadLength)
 	c.typ = header[0]
-	if c.typ < packet.Handshake || c.typ > packet.Kick {
+	if c.typ < packet.Handshake || c.typ > packet.Fragment {
 		return packet.ErrWrongPacketType
 	}
 	c.size = bytesToInt(header[1:])
 
 	// packet length limitation
-	if c.size > MaxPacketSize {
+	if c.size > c.maxPacketSize {
 		return ErrPacketSizeExceeded
 	}
 	return nil
 }
 
+// reassemble collects the fragments, the packet is nil until the Data packet
+// completing the fragments is received
+func (c *Decoder) reassemble(p *packet.Packet) (*packet.Packet, error) {
+	if p.Type != packet.Fragment && (p.Type != packet.Data || c.fragments == nil) {
+		return p, nil
+	}
+	if len(c.fragments)+len(p.Data) > c.maxMessageSize {
+		return nil, ErrMessageSizeExceeded
+	}
+	c.fragments = append(c.fragments, p.Data...)
+	if p.Type == packet.Fragment {
+		return nil, nil
+	}
+
+	p.Data, c.fragments = c.fragments, nil
+	p.Length = len(p.Data)
+	return p, nil
+}
+
 // Decode decode the network bytes slice to packet.Packet(s)
 // TODO(Warning): shared slice
 func (c *Decoder) Decode(data []byte) ([]*packet.Packet, error) {
@@ -89,7 +126,12 @@ func (c *Decoder) Decode(data []byte) ([]*packet.Packet, error) {
 
 	for c.size <= c.buf.Len() {
 		p := &packet.Packet{Type: packet.Type(c.typ), Length: c.size, Data: c.buf.Next(c.size)}
-		packets = append(packets, p)
+		if p, err = c.reassemble(p); err != nil {
+			return nil, err
+		}
+		if p != nil {
+			packets = append(packets, p)
+		}
 
 		// more packet
 		if c.buf.Len() < HeadLength {
@@ -114,9 +156,12 @@ func (c *Decoder) Decode(data []byte) ([]*packet.Packet, error) {
 // --------|------------------------|--------
 // 1 byte packet type, 3 bytes packet data length(big end), and data segment
 func Encode(typ packet.Type, data []byte) ([]byte, error) {
-	if typ < packet.Handshake || typ > packet.Kick {
+	if typ < packet.Handshake || typ > packet.Fragment {
 		return nil, packet.ErrWrongPacketType
 	}
+	if len(data) > MaxLength {
+		return nil, ErrPacketSizeExceeded
+	}
 
 	p := &packet.Packet{Type: typ, Length: len(data)}
 	buf := make([]byte, p.Length+HeadLength)
@@ -130,9 +175,12 @@ func Encode(typ packet.Type, data []byte) ([]byte, error) {
 
 // EncodeHeader create a packet.Packet header from the raw bytes slice and then encode to network bytes slice
 func EncodeHeader(typ packet.Type, length int) ([]byte, error) {
-	if typ < packet.Handshake || typ > packet.Kick {
+	if typ < packet.Handshake || typ > packet.Fragment {
 		return nil, packet.ErrWrongPacketType
 	}
+	if length > MaxLength {
+		return nil, ErrPacketSizeExceeded
+	}
 
 	buf := make([]byte, HeadLength)
 	buf[0] = byte(typ)
@@ -140,6 +188,52 @@ func EncodeHeader(typ packet.Type, length int) ([]byte, error) {
 	return buf, nil
 }
 
+// EncodeFragments splits the body of a Data packet, which is the concatenation
+// of parts, into the packets whose bodies are not larger than size. The last
+// one is the Data packet and the others are Fragment packets. The returned
+// slices are the packet headers followed by the bodies sharing the parts.
+func EncodeFragments(size int, parts ...[]byte) ([][]byte, error) {
+	if size <= 0 || size > MaxLength {
+		return nil, ErrPacketSizeExceeded
+	}
+
+	parts = append([][]byte(nil), parts...)
+	total := 0
+	for _, part := range parts {
+		total += len(part)
+	}
+	var buffers [][]byte
+	for remain := total; ; {
+		n := size
+		typ := packet.Type(packet.Fragment)
+		if remain <= size {
+			n, typ = remain, packet.Data
+		}
+		header, err := EncodeHeader(typ, n)
+		if err != nil {
+			return nil, err
+		}
+		buffers = append(buffers, header)
+
+		remain -= n
+		for n > 0 {
+			chunk := parts[0]
+			if len(chunk) > n {
+				chunk = chunk[:n]
+			}
+			buffers = append(buffers, chunk)
+			parts[0] = parts[0][len(chunk):]
+			if len(parts[0]) == 0 {
+				parts = parts[1:]
+			}
+			n -= len(chunk)
+		}
+		if typ == packet.Data {
+			return buffers, nil
+		}
+	}
+}
+
 // Decode packet data length byte to int(Big end)
 func bytesToInt(b []byte) int {
 	result := 0
@@ -1,6 +1,7 @@
 package codec
 
 import (
+	"bytes"
 	"reflect"
 	"testing"
 
@@ -50,8 +51,8 @@ func TestPack(t *testing.T) {
 		t.Error("should err")
 	}
 
-	_ = &Packet{Type: Type(6), Data: data, Length: len(data)}
-	if _, err = Encode(Type(6), data); err == nil {
+	_ = &Packet{Type: Type(7), Data: data, Length: len(data)}
+	if _, err = Encode(Type(7), data); err == nil {
 		t.Error("should err")
 	}
 
@@ -74,6 +75,61 @@ func TestPack(t *testing.T) {
 	}
 }
 
+func TestFragments(t *testing.T) {
+	header, body := []byte("header"), bytes.Repeat([]byte("0123456789"), 10)
+	buffers, err := EncodeFragments(16, header, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(header) != 6 || len(body) != 100 {
+		t.Fatal("the parts should not be modified")
+	}
+	data := bytes.Join(buffers, nil)
+
+	// a heartbeat is allowed between the fragments
+	hb, _ := Encode(Heartbeat, nil)
+	data = append(data[:HeadLength+16:HeadLength+16], append(hb, data[HeadLength+16:]...)...)
+
+	d := NewDecoderWithLimits(16, 106)
+	var packets []*Packet
+	for i := 0; i < len(data); i += 7 {
+		end := i + 7
+		if end > len(data) {
+			end = len(data)
+		}
+		ps, err := d.Decode(data[i:end])
+		if err != nil {
+			t.Fatal(err)
+		}
+		packets = append(packets, ps...)
+	}
+	expect := append(append([]byte{}, header...), body...)
+	if len(packets) != 2 || packets[0].Type != Heartbeat || packets[1].Type != Data ||
+		packets[1].Length != len(expect) || !bytes.Equal(packets[1].Data, expect) {
+		t.Fatalf("unexpected packets %v", packets)
+	}
+
+	// the reassembled message is limited
+	if _, err := NewDecoderWithLimits(16, 105).Decode(data); err != ErrMessageSizeExceeded {
+		t.Fatalf("expect %v, got %v", ErrMessageSizeExceeded, err)
+	}
+	if _, err := NewDecoder().Decode(data); err != ErrMessageSizeExceeded {
+		t.Fatalf("expect %v, got %v", ErrMessageSizeExceeded, err)
+	}
+	if _, err := NewDecoderWithLimits(15, 106).Decode(data); err != ErrPacketSizeExceeded {
+		t.Fatalf("expect %v, got %v", ErrPacketSizeExceeded, err)
+	}
+
+	// the message not larger than the size is a Data packet
+	buffers, err = EncodeFragments(106, header, body)
+	if err != nil || len(buffers) != 3 || buffers[0][0] != Data {
+		t.Fatalf("unexpected packets %v, %v", buffers, err)
+	}
+	if _, err := EncodeHeader(Data, MaxLength+1); err != ErrPacketSizeExceeded {
+		t.Fatalf("expect %v, got %v", ErrPacketSizeExceeded, err)
+	}
+}
+
 func BenchmarkDecoder_Decode(b *testing.B) {
 	data := []byte("hello world")
 	pp1, err := Encode(Handshake, data)
@@ -0,0 +1,168 @@
+package codec
+
+import (
+	"bufio"
+	"io"
+	"math/bits"
+	"sync"
+
+	"github.com/nano-kit/go-nano/internal/packet"
+)
+
+// readBufferSize is the size of the buffered reader of connections
+const readBufferSize = 4096
+
+// minBodyShift is the capacity of the smallest pooled body, 512 bytes
+const minBodyShift = 9
+
+var (
+	// bodyPools pool the packet bodies by the capacity, which is a power of 2
+	// from 512 bytes to MaxLength+1, the bodies are boxed in *[]byte
+	bodyPools [25 - minBodyShift]sync.Pool
+
+	// boxes are the empty *[]byte, which box the bodies put into the pools
+	// without allocation
+	boxes = sync.Pool{New: func() interface{} { return new([]byte) }}
+)
+
+// bodyPool returns the index of the pool whose bodies are large enough for n
+// bytes, n should be positive
+func bodyPool(n int) int {
+	i := bits.Len(uint(n-1)) - minBodyShift
+	if i < 0 {
+		return 0
+	}
+	return i
+}
+
+// getBody returns a body of n bytes from the pool, the bodies larger than
+// MaxLength+1 are allocated without pooling
+func getBody(n int) []byte {
+	if n <= 0 {
+		return nil
+	}
+	i := bodyPool(n)
+	if i >= len(bodyPools) {
+		return make([]byte, n)
+	}
+	if v := bodyPools[i].Get(); v != nil {
+		box := v.(*[]byte)
+		b := *box
+		*box = nil
+		boxes.Put(box)
+		return b[:n]
+	}
+	return make([]byte, n, 1<<(i+minBodyShift))
+}
+
+// putBody puts the body back to the pool, the bodies not allocated by getBody
+// are dropped
+func putBody(b []byte) {
+	c := cap(b)
+	if c < 1<<minBodyShift || c&(c-1) != 0 {
+		return
+	}
+	i := bodyPool(c)
+	if i >= len(bodyPools) {
+		return
+	}
+	box := boxes.Get().(*[]byte)
+	*box = b[:0]
+	bodyPools[i].Put(box)
+}
+
+// Release returns the body of the packet read by Reader to the pool, and the
+// body must not be used by anyone after released. The body of a packet read
+// by Reader is owned by the caller, which may keep it instead of releasing.
+// Never release the packets decoded by Decoder, whose bodies share its buffer.
+func Release(p *packet.Packet) {
+	putBody(p.Data)
+	p.Data = nil
+}
+
+// A Reader reads packets from a connection through a buffered reader. Unlike
+// Decoder, the body of each packet is read into its own buffer taken from a
+// pool without copying, see Release.
+type Reader struct {
+	r      *bufio.Reader
+	header [HeadLength]byte
+
+	maxPacketSize  int    // the maximum size of packets
+	maxMessageSize int    // the maximum size of the Data packet reassembled from fragments
+	fragments      []byte // the fragments received before the Data packet
+	fragmented     bool   // the fragments are being reassembled
+}
+
+// NewReader returns a reader which rejects the packets larger than
+// maxPacketSize, and reassembles the fragments into the Data packet up to
+// maxMessageSize
+func NewReader(r io.Reader, maxPacketSize, maxMessageSize int) *Reader {
+	return &Reader{
+		r:              bufio.NewReaderSize(r, readBufferSize),
+		maxPacketSize:  maxPacketSize,
+		maxMessageSize: maxMessageSize,
+	}
+}
+
+// Read reads the next packet into p, which can be reused after the body is
+// released or kept. The fragments are reassembled, so that p is never a
+// Fragment packet.
+func (r *Reader) Read(p *packet.Packet) error {
+	for {
+		if _, err := io.ReadFull(r.r, r.header[:]); err != nil {
+			return err
+		}
+		typ := packet.Type(r.header[0])
+		if typ < packet.Handshake || typ > packet.Fragment {
+			return packet.ErrWrongPacketType
+		}
+		size := bytesToInt(r.header[1:])
+		if size > r.maxPacketSize {
+			return ErrPacketSizeExceeded
+		}
+
+		body := getBody(size)
+		if _, err := io.ReadFull(r.r, body); err != nil {
+			putBody(body)
+			return err
+		}
+		p.Type, p.Length, p.Data = typ, size, body
+
+		if typ != packet.Fragment && (typ != packet.Data || !r.fragmented) {
+			return nil
+		}
+		if err := r.reassemble(p); err != nil {
+			return err
+		}
+		if typ == packet.Data {
+			return nil
+		}
+	}
+}
+
+// reassemble appends the body of p to the fragments, and moves the fragments
+// to the body of p if it is the Data packet completing the fragments
+func (r *Reader) reassemble(p *packet.Packet) error {
+	n := len(r.fragments) + len(p.Data)
+	if n > r.maxMessageSize {
+		Release(p)
+		putBody(r.fragments)
+		r.fragments, r.fragmented = nil, false
+		return ErrMessageSizeExceeded
+	}
+	if n > cap(r.fragments) {
+		b := getBody(n)[:len(r.fragments)]
+		copy(b, r.fragments)
+		putBody(r.fragments)
+		r.fragments = b
+	}
+	r.fragments = append(r.fragments, p.Data...)
+	Release(p)
+	r.fragmented = true
+
+	if p.Type == packet.Data {
+		p.Data, p.Length = r.fragments, len(r.fragments)
+		r.fragments, r.fragmented = nil, false
+	}
+	return nil
+}
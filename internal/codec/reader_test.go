@@ -0,0 +1,178 @@
+package codec
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	. "github.com/nano-kit/go-nano/internal/packet"
+)
+
+// repeatReader reads the data repeatedly
+type repeatReader struct {
+	data []byte
+	off  int
+}
+
+func (r *repeatReader) Read(b []byte) (int, error) {
+	n := copy(b, r.data[r.off:])
+	r.off = (r.off + n) % len(r.data)
+	return n, nil
+}
+
+// stream encodes n Data packets of the body size
+func stream(b testing.TB, n, size int) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < n; i++ {
+		p, err := Encode(Data, bytes.Repeat([]byte{byte(i)}, size))
+		if err != nil {
+			b.Fatal(err)
+		}
+		buf.Write(p)
+	}
+	return buf.Bytes()
+}
+
+func TestReader(t *testing.T) {
+	var data []byte
+	hb, _ := Encode(Heartbeat, nil)
+	data = append(data, hb...)
+	data = append(data, stream(t, 3, 1000)...)
+	fragments, err := EncodeFragments(16, bytes.Repeat([]byte("fragment"), 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = append(data, bytes.Join(fragments, nil)...)
+
+	r := NewReader(bytes.NewReader(data), 1000, 80)
+	var p Packet
+	if err := r.Read(&p); err != nil || p.Type != Heartbeat || p.Length != 0 || p.Data != nil {
+		t.Fatalf("unexpected packet %v, %v", p, err)
+	}
+	var bodies [][]byte
+	for i := 0; i < 3; i++ {
+		if err := r.Read(&p); err != nil {
+			t.Fatal(err)
+		}
+		if p.Type != Data || p.Length != 1000 || !bytes.Equal(p.Data, bytes.Repeat([]byte{byte(i)}, 1000)) {
+			t.Fatalf("unexpected packet %v", p)
+		}
+		bodies = append(bodies, p.Data)
+	}
+	// the bodies are owned by the caller
+	for i, body := range bodies {
+		if !bytes.Equal(body, bytes.Repeat([]byte{byte(i)}, 1000)) {
+			t.Fatalf("the body %d is overwritten", i)
+		}
+	}
+
+	if err := r.Read(&p); err != nil || p.Type != Data || !bytes.Equal(p.Data, bytes.Repeat([]byte("fragment"), 10)) {
+		t.Fatalf("unexpected packet %v, %v", p, err)
+	}
+	Release(&p)
+	if p.Data != nil {
+		t.Fatal("the body should be cleared after released")
+	}
+	if err := r.Read(&p); err != io.EOF {
+		t.Fatalf("expect %v, got %v", io.EOF, err)
+	}
+
+	for _, tc := range []struct {
+		reader *Reader
+		expect error
+	}{
+		{NewReader(bytes.NewReader(data), 999, 80), ErrPacketSizeExceeded},
+		{NewReader(bytes.NewReader(data[HeadLength+1:]), 1000, 80), ErrWrongPacketType},
+		{NewReader(bytes.NewReader(data[:len(data)-1]), 1000, 80), io.ErrUnexpectedEOF},
+		{NewReader(bytes.NewReader(data), 1000, 79), ErrMessageSizeExceeded},
+	} {
+		var err error
+		for err == nil {
+			err = tc.reader.Read(&p)
+		}
+		if err != tc.expect {
+			t.Fatalf("expect %v, got %v", tc.expect, err)
+		}
+	}
+}
+
+func TestBodyPool(t *testing.T) {
+	for _, n := range []int{1, 512, 513, 4096, MaxLength} {
+		b := getBody(n)
+		if len(b) != n || cap(b)&(cap(b)-1) != 0 || cap(b) < 512 {
+			t.Fatalf("unexpected body of %d bytes: len=%d cap=%d", n, len(b), cap(b))
+		}
+		putBody(b)
+	}
+	// the bodies larger than the pools are allocated and dropped
+	if b := getBody(MaxLength + 2); len(b) != MaxLength+2 {
+		t.Fatalf("unexpected body of %d bytes: len=%d", MaxLength+2, len(b))
+	} else {
+		putBody(b)
+	}
+
+	// the bodies not from the pool are dropped
+	putBody(make([]byte, 1000))
+	if b := getBody(1000); cap(b) != 1024 {
+		t.Fatalf("expect the body of capacity 1024, got %d", cap(b))
+	}
+}
+
+// BenchmarkDecoder_Stream decodes the stream as the handler reading a 2048
+// bytes buffer, the bodies are copied since they share the decoder buffer
+func BenchmarkDecoder_Stream(b *testing.B) {
+	r := &repeatReader{data: stream(b, 16, 256)}
+	d := NewDecoder()
+	buf := make([]byte, 2048)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for count := 0; count < b.N; {
+		n, _ := r.Read(buf)
+		packets, err := d.Decode(buf[:n])
+		if err != nil {
+			b.Fatal(err)
+		}
+		for _, p := range packets {
+			body := make([]byte, len(p.Data))
+			copy(body, p.Data)
+		}
+		count += len(packets)
+	}
+}
+
+// BenchmarkReader_Read reads the same stream as BenchmarkDecoder_Stream, the
+// bodies are owned and released after use
+func BenchmarkReader_Read(b *testing.B) {
+	r := NewReader(&repeatReader{data: stream(b, 16, 256)}, MaxPacketSize, 0)
+	var p Packet
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := r.Read(&p); err != nil {
+			b.Fatal(err)
+		}
+		Release(&p)
+	}
+}
+
+// BenchmarkReader_Fragments reads the messages of 64KiB in 16KiB fragments
+func BenchmarkReader_Fragments(b *testing.B) {
+	fragments, err := EncodeFragments(16<<10, bytes.Repeat([]byte{1}, 64<<10))
+	if err != nil {
+		b.Fatal(err)
+	}
+	r := NewReader(&repeatReader{data: bytes.Join(fragments, nil)}, MaxPacketSize, 64<<10)
+	var p Packet
+
+	b.ReportAllocs()
+	b.SetBytes(64 << 10)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := r.Read(&p); err != nil {
+			b.Fatal(err)
+		}
+		Release(&p)
+	}
+}
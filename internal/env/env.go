@@ -32,7 +32,6 @@ import (
 
 var (
 	Wd                 string             // working path
-	GateID             uint16             // gate id
 	Die                chan bool          // wait for end application
 	Heartbeat          time.Duration      // Heartbeat internal
 	Debug              bool               // enable Debug
@@ -21,8 +21,10 @@
 package log
 
 import (
-	"log"
+	"fmt"
 	"os"
+
+	"github.com/nano-kit/go-nano/logging"
 )
 
 // Logger represents  the log interface
@@ -33,8 +35,37 @@ type Logger interface {
 	Fatalf(format string, v ...interface{})
 }
 
+// leveled writes the free-form logs to the "nano" logger of package logging
+// at the info level, the fatal logs are written at the error level
+type leveled struct {
+	logger logging.Logger
+}
+
 func init() {
-	SetLogger(log.New(os.Stderr, "[nano] ", log.LstdFlags|log.Lshortfile|log.Lmicroseconds|log.Lmsgprefix))
+	SetLogger(leveled{logger: logging.Get("nano")})
+}
+
+func (l leveled) Println(v ...interface{}) {
+	l.logger.Info(sprintln(v...))
+}
+
+func (l leveled) Fatalln(v ...interface{}) {
+	l.logger.Error(sprintln(v...))
+	os.Exit(1)
+}
+
+func (l leveled) Printf(format string, v ...interface{}) {
+	l.logger.Info(fmt.Sprintf(format, v...))
+}
+
+func (l leveled) Fatalf(format string, v ...interface{}) {
+	l.logger.Error(fmt.Sprintf(format, v...))
+	os.Exit(1)
+}
+
+func sprintln(v ...interface{}) string {
+	s := fmt.Sprintln(v...)
+	return s[:len(s)-1]
 }
 
 var (
@@ -0,0 +1,63 @@
+package message
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+// CompressionDeflate is the name of the DEFLATE (RFC 1951) compression of
+// message data, which is negotiated in handshake
+const CompressionDeflate = "deflate"
+
+var (
+	deflaters = sync.Pool{New: func() interface{} {
+		w, _ := flate.NewWriter(ioutil.Discard, flate.DefaultCompression)
+		return w
+	}}
+	inflaters sync.Pool
+)
+
+// Deflate compresses the data with DEFLATE
+func Deflate(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := deflaters.Get().(*flate.Writer)
+	defer deflaters.Put(w)
+
+	w.Reset(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Inflate decompresses the data compressed by Deflate, ErrInflatedSizeExceeded
+// is returned if the decompressed data is larger than limit, which prevents a
+// small message from exhausting the memory
+func Inflate(data []byte, limit int) ([]byte, error) {
+	var r io.ReadCloser
+	if v := inflaters.Get(); v != nil {
+		r = v.(io.ReadCloser)
+		if err := r.(flate.Resetter).Reset(bytes.NewReader(data), nil); err != nil {
+			return nil, err
+		}
+	} else {
+		r = flate.NewReader(bytes.NewReader(data))
+	}
+	defer inflaters.Put(r)
+
+	buf := bytes.NewBuffer(make([]byte, 0, 4*len(data)))
+	n, err := buf.ReadFrom(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return nil, ErrInvalidMessage
+	}
+	if n > int64(limit) {
+		return nil, ErrInflatedSizeExceeded
+	}
+	return buf.Bytes(), nil
+}
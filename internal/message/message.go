@@ -45,6 +45,7 @@ const (
 	msgTypeMask          = 0x07
 	msgRouteLengthMask   = 0xFF
 	msgHeadLength        = 0x02
+	msgDataCompressMask  = 0x10
 )
 
 var types = map[Type]string{
@@ -58,10 +59,15 @@ func (t Type) String() string {
 	return types[t]
 }
 
-var (
-	routes = make(map[string]uint16) // route map to code
-	codes  = make(map[uint16]string) // code map to route
-)
+// RouteDict is the dictionary compressing the routes to codes, each node has
+// its own dictionary. A nil RouteDict compresses no routes.
+type RouteDict struct {
+	routes map[string]uint16 // route map to code
+	codes  map[uint16]string // code map to route
+}
+
+// defaultDict is used by the package level codec functions
+var defaultDict = NewRouteDict(nil)
 
 // Errors that could be occurred in message codec
 var (
@@ -69,6 +75,9 @@ var (
 	ErrInvalidMessage    = errors.New("invalid message")
 	ErrRouteInfoNotFound = errors.New("route info not found in dictionary")
 	ErrWrongMessage      = errors.New("wrong message")
+
+	ErrInflatedSizeExceeded     = errors.New("inflated message data size exceeded")
+	ErrCompressionNotNegotiated = errors.New("message data compression not negotiated")
 )
 
 // Message represents a unmarshaled message or a message which to be marshaled
@@ -78,6 +87,10 @@ type Message struct {
 	Route      string // route for locating service
 	Data       []byte // payload
 	compressed bool   // is message compressed
+
+	DataCompressed bool // Data is compressed by Deflate, decoded messages are always decompressed
+
+	Traceparent string // trace context propagated between nodes, not encoded
 }
 
 // New returns a new message instance
@@ -90,12 +103,12 @@ func (m *Message) String() string {
 	return fmt.Sprintf("%s %s (%dbytes)", types[m.Type], m.Route, len(m.Data))
 }
 
-// Encode marshals message to binary format.
+// Encode marshals message to binary format with the default dictionary.
 func (m *Message) Encode() ([]byte, error) {
 	return Encode(m)
 }
 
-// EncodeHeader marshals message header to binary format.
+// EncodeHeader marshals message header to binary format with the default dictionary.
 func (m *Message) EncodeHeader() ([]byte, error) {
 	return EncodeHeader(m)
 }
@@ -121,9 +134,21 @@ func invalidType(t Type) bool {
 // | push     |----011-|<route>             |
 // ------------------------------------------
 // The figure above indicates that the bit does not affect the type of message.
+// The 5th bit is set if the data is compressed, see Deflate.
 // See ref: https://github.com/nano-kit/go-nano/blob/master/docs/communication_protocol.md
 func Encode(m *Message) ([]byte, error) {
-	buf, err := EncodeHeader(m)
+	return defaultDict.Encode(m)
+}
+
+// EncodeHeader marshals message header to binary format with the default dictionary.
+func EncodeHeader(m *Message) ([]byte, error) {
+	return defaultDict.EncodeHeader(m)
+}
+
+// Encode marshals message to binary format, the route found in the
+// dictionary is compressed, see the package level Encode.
+func (d *RouteDict) Encode(m *Message) ([]byte, error) {
+	buf, err := d.EncodeHeader(m)
 	if err != nil {
 		return nil, err
 	}
@@ -132,7 +157,7 @@ func Encode(m *Message) ([]byte, error) {
 }
 
 // EncodeHeader marshals message header to binary format.
-func EncodeHeader(m *Message) ([]byte, error) {
+func (d *RouteDict) EncodeHeader(m *Message) ([]byte, error) {
 	if invalidType(m.Type) {
 		return nil, ErrWrongMessageType
 	}
@@ -140,10 +165,17 @@ func EncodeHeader(m *Message) ([]byte, error) {
 	var buf []byte
 	flag := byte(m.Type) << 1
 
-	code, compressed := routes[m.Route]
+	var code uint16
+	var compressed bool
+	if d != nil {
+		code, compressed = d.routes[m.Route]
+	}
 	if compressed {
 		flag |= msgRouteCompressMask
 	}
+	if m.DataCompressed {
+		flag |= msgDataCompressMask
+	}
 	buf = append(buf, flag)
 
 	if m.Type == Request || m.Type == Response {
@@ -174,9 +206,23 @@ func EncodeHeader(m *Message) ([]byte, error) {
 	return buf, nil
 }
 
-// Decode unmarshal the bytes slice to a message
+// Decode unmarshal the bytes slice to a message, the message with compressed
+// data is rejected with ErrCompressionNotNegotiated
 // See ref: https://github.com/nano-kit/go-nano/blob/master/docs/communication_protocol.md
 func Decode(data []byte) (*Message, error) {
+	return defaultDict.DecodeInflated(data, 0)
+}
+
+// DecodeInflated unmarshal the bytes slice to a message, the compressed data is
+// decompressed up to maxInflatedSize bytes, and it is rejected with
+// ErrCompressionNotNegotiated if maxInflatedSize is not positive
+func DecodeInflated(data []byte, maxInflatedSize int) (*Message, error) {
+	return defaultDict.DecodeInflated(data, maxInflatedSize)
+}
+
+// DecodeInflated unmarshal the bytes slice to a message, the compressed route
+// is looked up in the dictionary, see the package level DecodeInflated.
+func (d *RouteDict) DecodeInflated(data []byte, maxInflatedSize int) (*Message, error) {
 	if len(data) < msgHeadLength {
 		return nil, ErrInvalidMessage
 	}
@@ -213,7 +259,11 @@ func Decode(data []byte) (*Message, error) {
 		if flag&msgRouteCompressMask == 1 {
 			m.compressed = true
 			code := binary.BigEndian.Uint16(data[offset:(offset + 2)])
-			route, ok := codes[code]
+			var route string
+			var ok bool
+			if d != nil {
+				route, ok = d.codes[code]
+			}
 			if !ok {
 				return nil, ErrRouteInfoNotFound
 			}
@@ -234,27 +284,72 @@ func Decode(data []byte) (*Message, error) {
 	if offset > len(data) {
 		return nil, ErrWrongMessage
 	}
+	if flag&msgDataCompressMask != 0 {
+		if maxInflatedSize <= 0 {
+			return nil, ErrCompressionNotNegotiated
+		}
+		d, err := Inflate(data[offset:], maxInflatedSize)
+		if err != nil {
+			return nil, err
+		}
+		m.Data = d
+		return m, nil
+	}
 	m.Data = data[offset:]
 	return m, nil
 }
 
-// SetDictionary set routes map which be used to compress route.
-// TODO(warning): set dictionary in runtime would be a dangerous operation!!!!!!
-func SetDictionary(dict map[string]uint16) {
+// NewRouteDict returns a dictionary of the routes map
+func NewRouteDict(dict map[string]uint16) *RouteDict {
+	d := &RouteDict{
+		routes: make(map[string]uint16),
+		codes:  make(map[uint16]string),
+	}
+	d.Set(dict)
+	return d
+}
+
+// Routes returns a copy of the routes map
+func (d *RouteDict) Routes() map[string]uint16 {
+	if d == nil {
+		return map[string]uint16{}
+	}
+	dict := make(map[string]uint16, len(d.routes))
+	for route, code := range d.routes {
+		dict[route] = code
+	}
+	return dict
+}
+
+// Set updates the routes map, the dictionary should not be set while it is
+// used by the codec.
+func (d *RouteDict) Set(dict map[string]uint16) {
 	for route, code := range dict {
 		r := strings.TrimSpace(route)
 
 		// duplication check
-		if _, ok := routes[r]; ok {
+		if _, ok := d.routes[r]; ok {
 			log.Printf("duplicated route(route: %s, code: %d)", r, code)
 		}
 
-		if _, ok := codes[code]; ok {
+		if _, ok := d.codes[code]; ok {
 			log.Printf("duplicated route(route: %s, code: %d)", r, code)
 		}
 
 		// update map, using last value when key duplicated
-		routes[r] = code
-		codes[code] = r
+		d.routes[r] = code
+		d.codes[code] = r
 	}
 }
+
+// Dictionary returns a copy of the routes map of the default dictionary
+func Dictionary() map[string]uint16 {
+	return defaultDict.Routes()
+}
+
+// SetDictionary set routes map of the default dictionary which be used to
+// compress route.
+// TODO(warning): set dictionary in runtime would be a dangerous operation!!!!!!
+func SetDictionary(dict map[string]uint16) {
+	defaultDict.Set(dict)
+}
@@ -1,6 +1,7 @@
 package message
 
 import (
+	"bytes"
 	"reflect"
 	"testing"
 )
@@ -162,3 +163,73 @@ func TestEncode(t *testing.T) {
 		t.Error("not equal")
 	}
 }
+
+func TestEncodeCompressedData(t *testing.T) {
+	data := bytes.Repeat([]byte(`{"item": "sword", "count": 1}`), 100)
+	deflated, err := Deflate(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deflated) >= len(data) {
+		t.Fatalf("expect the data compressed, got %d bytes from %d bytes", len(deflated), len(data))
+	}
+
+	m := &Message{Type: Response, ID: 7, Data: deflated, DataCompressed: true}
+	em, err := m.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dm, err := DecodeInflated(em, len(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dm.Type != Response || dm.ID != 7 || dm.DataCompressed || !bytes.Equal(dm.Data, data) {
+		t.Fatalf("unexpected decoded message %+v", dm)
+	}
+
+	// the compressed data is rejected unless compression is negotiated
+	if _, err := Decode(em); err != ErrCompressionNotNegotiated {
+		t.Fatalf("expect %v, got %v", ErrCompressionNotNegotiated, err)
+	}
+
+	// the decompressed data is limited
+	if _, err := DecodeInflated(em, len(data)-1); err != ErrInflatedSizeExceeded {
+		t.Fatalf("expect %v, got %v", ErrInflatedSizeExceeded, err)
+	}
+
+	em[len(em)-1] ^= 0xFF
+	if _, err := DecodeInflated(em, len(data)); err != ErrInvalidMessage {
+		t.Fatalf("expect %v, got %v", ErrInvalidMessage, err)
+	}
+}
+
+func TestRouteDict(t *testing.T) {
+	d := NewRouteDict(map[string]uint16{"dict.route": 200})
+	m := &Message{Type: Notify, Route: "dict.route", Data: []byte(`hello`)}
+	em, err := d.Encode(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if em[0]&msgRouteCompressMask == 0 {
+		t.Fatal("expect the route compressed")
+	}
+	dm, err := d.DecodeInflated(em, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dm.Route != "dict.route" || string(dm.Data) != "hello" {
+		t.Fatalf("unexpected decoded message %+v", dm)
+	}
+
+	// the dictionaries are independent
+	if _, err := Decode(em); err != ErrRouteInfoNotFound {
+		t.Fatalf("expect %v, got %v", ErrRouteInfoNotFound, err)
+	}
+	if _, found := Dictionary()["dict.route"]; found {
+		t.Fatal("the route should not be set to the default dictionary")
+	}
+	var empty *RouteDict
+	if em, err = empty.Encode(m); err != nil || em[0]&msgRouteCompressMask != 0 {
+		t.Fatalf("expect the route not compressed, got %v", err)
+	}
+}
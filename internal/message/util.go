@@ -20,14 +20,22 @@
 
 package message
 
-import "github.com/nano-kit/go-nano/internal/env"
+import (
+	"github.com/nano-kit/go-nano/internal/env"
+	"github.com/nano-kit/go-nano/serialize"
+)
 
-// Serialize serializes the message
+// Serialize serializes the message with the serializer of current process
 func Serialize(v interface{}) ([]byte, error) {
+	return SerializeWith(env.Serializer, v)
+}
+
+// SerializeWith serializes the message with the serializer
+func SerializeWith(serializer serialize.Serializer, v interface{}) ([]byte, error) {
 	if data, ok := v.([]byte); ok {
 		return data, nil
 	}
-	data, err := env.Serializer.Marshal(v)
+	data, err := serializer.Marshal(v)
 	if err != nil {
 		return nil, err
 	}
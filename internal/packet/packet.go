@@ -44,6 +44,10 @@ const (
 
 	// Kick represents a kick off packet
 	Kick = 0x05 // disconnect message from server
+
+	// Fragment represents a part of the body of the following Data packet,
+	// which is sent in fragments since it exceeds the maximum packet size
+	Fragment = 0x06
 )
 
 // ErrWrongPacketType represents a wrong packet type.
@@ -0,0 +1,145 @@
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// SugaredLogger is implemented by the structured loggers accepting the
+// alternating keys and values, e.g. *zap.SugaredLogger
+type SugaredLogger interface {
+	Debugw(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+// Printer is implemented by the loggers writing the formatted text, e.g.
+// *log.Logger and the logger installed by nano.WithLogger
+type Printer interface {
+	Printf(format string, v ...interface{})
+}
+
+type (
+	stdHandler struct {
+		logger Printer
+	}
+
+	sugaredHandler struct {
+		logger SugaredLogger
+	}
+)
+
+func defaultHandler() Handler {
+	return NewStdHandler(log.New(os.Stderr, "[nano] ", log.LstdFlags|log.Lmicroseconds|log.Lmsgprefix))
+}
+
+// NewStdHandler returns a handler writing the records in the logfmt-like
+// text to the logger of the standard library, e.g.
+//
+//	INFO agent.go:120 session closed pkg=cluster sid=1 reason="read error"
+func NewStdHandler(l *log.Logger) Handler {
+	return &stdHandler{logger: l}
+}
+
+// NewPrinterHandler returns a handler writing the records in the same text as
+// NewStdHandler to the printer
+func NewPrinterHandler(l Printer) Handler {
+	return &stdHandler{logger: l}
+}
+
+func (h *stdHandler) Log(level Level, pkg, msg string, kv []interface{}) {
+	var buf bytes.Buffer
+	buf.WriteString(strings.ToUpper(level.String()))
+	if file, line := caller(); file != "" {
+		buf.WriteString(" " + filepath.Base(file) + ":" + strconv.Itoa(line))
+	}
+	buf.WriteString(" " + msg)
+	if pkg != "" {
+		buf.WriteString(" pkg=" + pkg)
+	}
+	for i := 0; i < len(kv); i += 2 {
+		buf.WriteString(" " + fmt.Sprint(kv[i]) + "=")
+		if i+1 < len(kv) {
+			buf.WriteString(formatValue(kv[i+1]))
+		} else {
+			buf.WriteString("<missing>")
+		}
+	}
+	h.logger.Printf("%s", buf.String())
+}
+
+// NewSugaredHandler returns a handler writing the records to the structured
+// logger, the package is added as the "pkg" field
+func NewSugaredHandler(l SugaredLogger) Handler {
+	return &sugaredHandler{logger: l}
+}
+
+func (h *sugaredHandler) Log(level Level, pkg, msg string, kv []interface{}) {
+	if pkg != "" {
+		kv = append([]interface{}{"pkg", pkg}, kv...)
+	}
+	switch level {
+	case LevelDebug:
+		h.logger.Debugw(msg, kv...)
+	case LevelInfo:
+		h.logger.Infow(msg, kv...)
+	case LevelWarn:
+		h.logger.Warnw(msg, kv...)
+	default:
+		h.logger.Errorw(msg, kv...)
+	}
+}
+
+func formatValue(v interface{}) string {
+	var s string
+	switch x := v.(type) {
+	case string:
+		s = x
+	case error:
+		s = x.Error()
+	case fmt.Stringer:
+		s = x.String()
+	default:
+		s = fmt.Sprint(v)
+	}
+	if s == "" || strings.ContainsAny(s, " =\"\n") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// loggingPackages are the packages skipped when finding the caller
+var loggingPackages = []string{
+	"github.com/nano-kit/go-nano/logging.",
+	"github.com/nano-kit/go-nano/internal/log.",
+}
+
+// caller returns the location of the code writing the log
+func caller() (string, int) {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		internal := false
+		for _, pkg := range loggingPackages {
+			if strings.HasPrefix(frame.Function, pkg) && !strings.HasSuffix(frame.File, "_test.go") {
+				internal = true
+				break
+			}
+		}
+		if !internal {
+			return frame.File, frame.Line
+		}
+		if !more {
+			return "", 0
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// Package logging is the leveled and structured logging of nano. The loggers
+// are named by package, whose levels can be changed at runtime, and the
+// records are written by a Handler, which adapts the standard library or the
+// structured logger of the application.
+package logging
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Level is the severity of a log record
+type Level int32
+
+// Log levels
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// ErrInvalidLevel indicates the level name is unknown
+var ErrInvalidLevel = errors.New("invalid log level")
+
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+func (l Level) String() string {
+	if name, found := levelNames[l]; found {
+		return name
+	}
+	return "unknown"
+}
+
+// ParseLevel returns the level of the name, e.g. "debug"
+func ParseLevel(name string) (Level, error) {
+	for l, n := range levelNames {
+		if strings.EqualFold(n, name) {
+			return l, nil
+		}
+	}
+	return LevelInfo, ErrInvalidLevel
+}
+
+type (
+	// Handler writes the log records, kv is the alternating keys and values
+	Handler interface {
+		Log(level Level, pkg, msg string, kv []interface{})
+	}
+
+	// HandlerFunc adapts a function to the Handler interface
+	HandlerFunc func(level Level, pkg, msg string, kv []interface{})
+
+	// Logger is the leveled and structured logger, the arguments following
+	// the message are alternating keys and values, e.g. "sid", 1, "uid", "u1"
+	Logger interface {
+		Debug(msg string, kv ...interface{})
+		Info(msg string, kv ...interface{})
+		Warn(msg string, kv ...interface{})
+		Error(msg string, kv ...interface{})
+
+		// With returns a logger which adds the fields to all records
+		With(kv ...interface{}) Logger
+
+		// Enabled decides whether the records of the level are written,
+		// which avoids building the fields of the discarded records
+		Enabled(level Level) bool
+	}
+
+	logger struct {
+		pkg    string
+		fields []interface{}
+	}
+)
+
+// Log implements the Handler interface
+func (f HandlerFunc) Log(level Level, pkg, msg string, kv []interface{}) {
+	f(level, pkg, msg, kv)
+}
+
+var config = struct {
+	sync.RWMutex
+	handler Handler
+	level   Level            // the default level
+	levels  map[string]Level // the levels of packages
+}{handler: defaultHandler(), level: LevelInfo, levels: map[string]Level{}}
+
+// SetHandler replaces the handler of all loggers, nil is ignored
+func SetHandler(h Handler) {
+	if h == nil {
+		return
+	}
+	config.Lock()
+	config.handler = h
+	config.Unlock()
+}
+
+// SetLevel sets the level of the package, the default level of the packages
+// without their own levels is set if pkg is empty
+func SetLevel(pkg string, level Level) {
+	config.Lock()
+	defer config.Unlock()
+
+	if pkg == "" {
+		config.level = level
+		return
+	}
+	config.levels[pkg] = level
+}
+
+// ResetLevel removes the level of the package, which uses the default level
+func ResetLevel(pkg string) {
+	config.Lock()
+	delete(config.levels, pkg)
+	config.Unlock()
+}
+
+// LevelOf returns the level of the package
+func LevelOf(pkg string) Level {
+	config.RLock()
+	defer config.RUnlock()
+
+	if level, found := config.levels[pkg]; found {
+		return level
+	}
+	return config.level
+}
+
+// Levels returns the level names of the packages, the default level is keyed
+// by an empty string
+func Levels() map[string]string {
+	config.RLock()
+	defer config.RUnlock()
+
+	result := map[string]string{"": config.level.String()}
+	for pkg, level := range config.levels {
+		result[pkg] = level.String()
+	}
+	return result
+}
+
+// Packages returns the names of the packages which have their own levels
+func Packages() []string {
+	config.RLock()
+	defer config.RUnlock()
+
+	result := make([]string, 0, len(config.levels))
+	for pkg := range config.levels {
+		result = append(result, pkg)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Get returns the logger of the package
+func Get(pkg string) Logger {
+	return &logger{pkg: pkg}
+}
+
+func (l *logger) Debug(msg string, kv ...interface{}) { l.log(LevelDebug, msg, kv) }
+func (l *logger) Info(msg string, kv ...interface{})  { l.log(LevelInfo, msg, kv) }
+func (l *logger) Warn(msg string, kv ...interface{})  { l.log(LevelWarn, msg, kv) }
+func (l *logger) Error(msg string, kv ...interface{}) { l.log(LevelError, msg, kv) }
+
+func (l *logger) With(kv ...interface{}) Logger {
+	fields := make([]interface{}, 0, len(l.fields)+len(kv))
+	fields = append(fields, l.fields...)
+	fields = append(fields, kv...)
+	return &logger{pkg: l.pkg, fields: fields}
+}
+
+func (l *logger) Enabled(level Level) bool {
+	return level >= LevelOf(l.pkg)
+}
+
+func (l *logger) log(level Level, msg string, kv []interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	if len(l.fields) > 0 {
+		kv = append(append(make([]interface{}, 0, len(l.fields)+len(kv)), l.fields...), kv...)
+	}
+
+	config.RLock()
+	h := config.handler
+	config.RUnlock()
+	h.Log(level, l.pkg, msg, kv)
+}
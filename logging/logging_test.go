@@ -0,0 +1,140 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+)
+
+type record struct {
+	level Level
+	pkg   string
+	msg   string
+	kv    []interface{}
+}
+
+func capture(t *testing.T) *[]record {
+	var records []record
+	SetHandler(HandlerFunc(func(level Level, pkg, msg string, kv []interface{}) {
+		records = append(records, record{level, pkg, msg, kv})
+	}))
+	t.Cleanup(func() {
+		SetHandler(defaultHandler())
+		SetLevel("", LevelInfo)
+		for _, pkg := range Packages() {
+			ResetLevel(pkg)
+		}
+	})
+	return &records
+}
+
+func TestLevels(t *testing.T) {
+	records := capture(t)
+	logger := Get("cluster")
+
+	logger.Debug("hidden")
+	logger.Info("shown", "sid", 1)
+	if len(*records) != 1 || (*records)[0].msg != "shown" || (*records)[0].pkg != "cluster" {
+		t.Fatalf("unexpected records %+v", *records)
+	}
+
+	SetLevel("cluster", LevelDebug)
+	logger.Debug("debug")
+	Get("session").Debug("hidden")
+	if len(*records) != 2 || (*records)[1].level != LevelDebug {
+		t.Fatalf("unexpected records %+v", *records)
+	}
+
+	SetLevel("", LevelError)
+	ResetLevel("cluster")
+	logger.Warn("hidden")
+	logger.Error("error")
+	if len(*records) != 3 || (*records)[2].msg != "error" {
+		t.Fatalf("unexpected records %+v", *records)
+	}
+	if levels := Levels(); levels[""] != "error" || len(levels) != 1 {
+		t.Fatalf("unexpected levels %v", levels)
+	}
+
+	if level, err := ParseLevel("WARN"); err != nil || level != LevelWarn {
+		t.Fatalf("unexpected level %v, %v", level, err)
+	}
+	if _, err := ParseLevel("verbose"); err != ErrInvalidLevel {
+		t.Fatalf("expect %v, got %v", ErrInvalidLevel, err)
+	}
+}
+
+func TestWith(t *testing.T) {
+	records := capture(t)
+	base := Get("session").With("sid", 1)
+	base.With("uid", "u1").Info("bound", "route", "Room.Join")
+	base.Info("unbound")
+
+	got := (*records)[0].kv
+	if len(got) != 6 || got[0] != "sid" || got[2] != "uid" || got[4] != "route" {
+		t.Fatalf("unexpected fields %v", got)
+	}
+	if len((*records)[1].kv) != 2 {
+		t.Fatalf("the fields of parent should not be changed: %v", (*records)[1].kv)
+	}
+}
+
+func TestStdHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewStdHandler(log.New(&buf, "", 0))
+	h.Log(LevelWarn, "cluster", "session closed", []interface{}{"sid", 1, "reason", "read error", "err", errors.New("EOF"), "odd"})
+
+	line := buf.String()
+	if !strings.HasPrefix(line, "WARN logging_test.go:") {
+		t.Fatalf("unexpected prefix %q", line)
+	}
+	if !strings.HasSuffix(line, ` session closed pkg=cluster sid=1 reason="read error" err=EOF odd=<missing>`+"\n") {
+		t.Fatalf("unexpected line %q", line)
+	}
+}
+
+type sugared struct {
+	calls []string
+}
+
+func (s *sugared) Debugw(msg string, kv ...interface{}) { s.calls = append(s.calls, "debug "+msg) }
+func (s *sugared) Infow(msg string, kv ...interface{})  { s.calls = append(s.calls, "info "+msg) }
+func (s *sugared) Warnw(msg string, kv ...interface{})  { s.calls = append(s.calls, "warn "+msg) }
+func (s *sugared) Errorw(msg string, kv ...interface{}) {
+	s.calls = append(s.calls, "error "+msg+" "+kv[0].(string)+"="+kv[1].(string))
+}
+
+func TestSugaredHandler(t *testing.T) {
+	s := &sugared{}
+	SetHandler(NewSugaredHandler(s))
+	defer SetHandler(defaultHandler())
+
+	Get("cluster").Info("started")
+	Get("cluster").Error("failed")
+	if len(s.calls) != 2 || s.calls[0] != "info started" || s.calls[1] != "error failed pkg=cluster" {
+		t.Fatalf("unexpected calls %v", s.calls)
+	}
+}
+
+type printer struct {
+	lines []string
+}
+
+func (p *printer) Printf(format string, v ...interface{}) {
+	p.lines = append(p.lines, fmt.Sprintf(format, v...))
+}
+
+func TestPrinterHandler(t *testing.T) {
+	p := &printer{}
+	SetHandler(NewPrinterHandler(p))
+	defer SetHandler(defaultHandler())
+
+	Get("cluster").Warn("session closed", "sid", 1)
+	if len(p.lines) != 1 || !strings.HasPrefix(p.lines[0], "WARN logging_test.go:") ||
+		!strings.HasSuffix(p.lines[0], " session closed pkg=cluster sid=1") {
+		t.Fatalf("unexpected lines %q", p.lines)
+	}
+}
@@ -0,0 +1,236 @@
+// Package metrics implements the counters, gauges and histograms exposed in
+// the Prometheus text format, without depending on the Prometheus client.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the content type of the Prometheus text format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefBuckets are the default histogram buckets in seconds, which are suitable
+// for the latency of handlers and RPCs
+var DefBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5}
+
+// labelSep separates the label values in the key of a series
+const labelSep = "\xff"
+
+type (
+	collector interface {
+		name() string
+		write(w *bufio.Writer)
+	}
+
+	desc struct {
+		fqName string
+		help   string
+		typ    string
+		labels []string
+	}
+
+	// Registry is a set of metrics which are written in the Prometheus text
+	// format, it implements http.Handler to serve the /metrics endpoint.
+	Registry struct {
+		mu         sync.RWMutex
+		collectors map[string]collector
+	}
+)
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{collectors: map[string]collector{}}
+}
+
+// register adds the collector, the registered one is returned if there is a
+// collector with the same name
+func (r *Registry) register(c collector) collector {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if exist, found := r.collectors[c.name()]; found {
+		return exist
+	}
+	r.collectors[c.name()] = c
+	return c
+}
+
+// replace adds the collector, the collector with the same name is replaced
+func (r *Registry) replace(c collector) {
+	r.mu.Lock()
+	r.collectors[c.name()] = c
+	r.mu.Unlock()
+}
+
+// Counter registers a counter partitioned by the labels
+func (r *Registry) Counter(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{desc: desc{name, help, "counter", labels}, series: map[string]*Counter{}}
+	return r.register(c).(*CounterVec)
+}
+
+// Gauge registers a gauge partitioned by the labels
+func (r *Registry) Gauge(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{desc: desc{name, help, "gauge", labels}, series: map[string]*Gauge{}}
+	return r.register(g).(*GaugeVec)
+}
+
+// Histogram registers a histogram partitioned by the labels, DefBuckets is
+// used if buckets is empty
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	h := &HistogramVec{desc: desc{name, help, "histogram", labels}, buckets: buckets, series: map[string]*Histogram{}}
+	return r.register(h).(*HistogramVec)
+}
+
+// GaugeFunc registers a gauge whose value is collected from fn when the
+// metrics are written, it replaces the fn registered with the same name
+func (r *Registry) GaugeFunc(name, help string, fn func() float64) {
+	r.replace(&funcCollector{desc: desc{name, help, "gauge", nil}, fn: func() map[string]float64 {
+		return map[string]float64{"": fn()}
+	}})
+}
+
+// CounterFunc registers a counter whose values by the label are collected
+// from fn when the metrics are written, it replaces the fn registered with the
+// same name. It exports the counters maintained somewhere else.
+func (r *Registry) CounterFunc(name, help, label string, fn func() map[string]int64) {
+	r.replace(&funcCollector{desc: desc{name, help, "counter", []string{label}}, fn: func() map[string]float64 {
+		values := fn()
+		result := make(map[string]float64, len(values))
+		for k, v := range values {
+			result[k] = float64(v)
+		}
+		return result
+	}})
+}
+
+// WriteTo writes all metrics in the Prometheus text format
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.RLock()
+	collectors := make([]collector, 0, len(r.collectors))
+	for _, c := range r.collectors {
+		collectors = append(collectors, c)
+	}
+	r.mu.RUnlock()
+	sort.Slice(collectors, func(i, j int) bool {
+		return collectors[i].name() < collectors[j].name()
+	})
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// ServeHTTP serves the metrics in the Prometheus text format
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	r.WriteTo(w)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (d *desc) name() string {
+	return d.fqName
+}
+
+func (d *desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", d.fqName, len(d.labels), len(values)))
+	}
+	return strings.Join(values, labelSep)
+}
+
+func (d *desc) writeHeader(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.fqName, escapeHelp(d.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.fqName, d.typ)
+}
+
+// writeSample writes a sample of the series, extra is the additional label
+// such as the "le" of histogram buckets
+func (d *desc) writeSample(w *bufio.Writer, suffix, key string, extra []string, v float64) {
+	w.WriteString(d.fqName)
+	w.WriteString(suffix)
+
+	var pairs []string
+	if len(d.labels) > 0 {
+		for i, value := range strings.Split(key, labelSep) {
+			pairs = append(pairs, d.labels[i]+`="`+escapeLabel(value)+`"`)
+		}
+	}
+	if len(extra) == 2 {
+		pairs = append(pairs, extra[0]+`="`+extra[1]+`"`)
+	}
+	if len(pairs) > 0 {
+		w.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+	w.WriteString(" " + formatFloat(v) + "\n")
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+// funcCollector collects the values from a function
+type funcCollector struct {
+	desc
+	fn func() map[string]float64
+}
+
+func (f *funcCollector) write(w *bufio.Writer) {
+	values := f.fn()
+	f.writeHeader(w)
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		f.writeSample(w, "", k, nil, values[k])
+	}
+}
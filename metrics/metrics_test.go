@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteTo(t *testing.T) {
+	r := NewRegistry()
+	c := r.Counter("test_messages_total", "Messages by route.", "route")
+	c.With("Room.Join").Inc()
+	c.With("Room.Join").Add(2)
+	c.With(`a"b`).Inc()
+	if r.Counter("test_messages_total", "", "route") != c {
+		t.Fatal("the registered counter should be returned")
+	}
+	if values := c.Values(); len(values) != 2 || values["Room.Join"] != 3 {
+		t.Fatalf("unexpected values %v", values)
+	}
+
+	g := r.Gauge("test_queue", "Queue length.")
+	g.With().Set(5)
+	g.With().Add(-2)
+
+	h := r.Histogram("test_latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+	h.With("Room.Join").Observe(0.05)
+	h.With("Room.Join").Observe(0.5)
+	h.With("Room.Join").Observe(3)
+
+	r.GaugeFunc("test_members", "Members.", func() float64 { return 1 })
+	r.GaugeFunc("test_members", "Members.", func() float64 { return 3 })
+	r.CounterFunc("test_closed_total", "Closed by reason.", "reason", func() map[string]int64 {
+		return map[string]int64{"kicked": 2}
+	})
+
+	var buf bytes.Buffer
+	if _, err := r.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	expect := `# HELP test_closed_total Closed by reason.
+# TYPE test_closed_total counter
+test_closed_total{reason="kicked"} 2
+# HELP test_latency_seconds Latency.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{route="Room.Join",le="0.1"} 1
+test_latency_seconds_bucket{route="Room.Join",le="1"} 2
+test_latency_seconds_bucket{route="Room.Join",le="+Inf"} 3
+test_latency_seconds_sum{route="Room.Join"} 3.55
+test_latency_seconds_count{route="Room.Join"} 3
+# HELP test_members Members.
+# TYPE test_members gauge
+test_members 3
+# HELP test_messages_total Messages by route.
+# TYPE test_messages_total counter
+test_messages_total{route="Room.Join"} 3
+test_messages_total{route="a\"b"} 1
+# HELP test_queue Queue length.
+# TYPE test_queue gauge
+test_queue 3
+`
+	if buf.String() != expect {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("test_total", "Test.").With().Inc()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Header().Get("Content-Type") != ContentType {
+		t.Fatalf("unexpected content type %q", w.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(w.Body.String(), "test_total 1\n") {
+		t.Fatalf("unexpected body %q", w.Body.String())
+	}
+}
+
+func TestLabelValues(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("mismatched label values should panic")
+		}
+	}()
+	NewRegistry().Counter("test_total", "Test.", "route").With()
+}
@@ -0,0 +1,233 @@
+package metrics
+
+import (
+	"bufio"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// Counter is a monotonically increasing value
+	Counter struct {
+		bits uint64 // float64 bits
+	}
+
+	// CounterVec is a set of counters partitioned by the label values
+	CounterVec struct {
+		desc
+		mu     sync.RWMutex
+		series map[string]*Counter
+	}
+
+	// Gauge is a value that can go up and down
+	Gauge struct {
+		bits uint64 // float64 bits
+	}
+
+	// GaugeVec is a set of gauges partitioned by the label values
+	GaugeVec struct {
+		desc
+		mu     sync.RWMutex
+		series map[string]*Gauge
+	}
+
+	// Histogram counts the observations in the buckets
+	Histogram struct {
+		mu      sync.Mutex
+		buckets []float64
+		counts  []uint64 // not cumulative, the last one is +Inf
+		sum     float64
+		count   uint64
+	}
+
+	// HistogramVec is a set of histograms partitioned by the label values
+	HistogramVec struct {
+		desc
+		buckets []float64
+		mu      sync.RWMutex
+		series  map[string]*Histogram
+	}
+)
+
+func addFloat(bits *uint64, delta float64) {
+	for {
+		old := atomic.LoadUint64(bits)
+		v := math.Float64bits(math.Float64frombits(old) + delta)
+		if atomic.CompareAndSwapUint64(bits, old, v) {
+			return
+		}
+	}
+}
+
+// Inc increases the counter by 1
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add increases the counter by v, v must not be negative
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+	addFloat(&c.bits, v)
+}
+
+// Value returns the value of the counter
+func (c *Counter) Value() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&c.bits))
+}
+
+// With returns the counter of the label values
+func (v *CounterVec) With(values ...string) *Counter {
+	key := v.key(values)
+	v.mu.RLock()
+	c, found := v.series[key]
+	v.mu.RUnlock()
+	if found {
+		return c
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if c, found = v.series[key]; !found {
+		c = &Counter{}
+		v.series[key] = c
+	}
+	return c
+}
+
+// Values returns the value of each counter by its label values, which are
+// joined with "\xff" if there are several labels
+func (v *CounterVec) Values() map[string]float64 {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	result := make(map[string]float64, len(v.series))
+	for k, c := range v.series {
+		result[k] = c.Value()
+	}
+	return result
+}
+
+func (v *CounterVec) write(w *bufio.Writer) {
+	v.writeHeader(w)
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v.writeSample(w, "", k, nil, v.series[k].Value())
+	}
+}
+
+// Set sets the gauge to v
+func (g *Gauge) Set(v float64) {
+	atomic.StoreUint64(&g.bits, math.Float64bits(v))
+}
+
+// Add adds v to the gauge, v can be negative
+func (g *Gauge) Add(v float64) {
+	addFloat(&g.bits, v)
+}
+
+// Value returns the value of the gauge
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&g.bits))
+}
+
+// With returns the gauge of the label values
+func (v *GaugeVec) With(values ...string) *Gauge {
+	key := v.key(values)
+	v.mu.RLock()
+	g, found := v.series[key]
+	v.mu.RUnlock()
+	if found {
+		return g
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if g, found = v.series[key]; !found {
+		g = &Gauge{}
+		v.series[key] = g
+	}
+	return g
+}
+
+func (v *GaugeVec) write(w *bufio.Writer) {
+	v.writeHeader(w)
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v.writeSample(w, "", k, nil, v.series[k].Value())
+	}
+}
+
+// Observe adds an observation to the histogram
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v)
+	h.mu.Lock()
+	h.counts[i]++
+	h.sum += v
+	h.count++
+	h.mu.Unlock()
+}
+
+// ObserveSince observes the seconds elapsed since start
+func (h *Histogram) ObserveSince(start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+// With returns the histogram of the label values
+func (v *HistogramVec) With(values ...string) *Histogram {
+	key := v.key(values)
+	v.mu.RLock()
+	h, found := v.series[key]
+	v.mu.RUnlock()
+	if found {
+		return h
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if h, found = v.series[key]; !found {
+		h = &Histogram{buckets: v.buckets, counts: make([]uint64, len(v.buckets)+1)}
+		v.series[key] = h
+	}
+	return h
+}
+
+func (v *HistogramVec) write(w *bufio.Writer) {
+	v.writeHeader(w)
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		h := v.series[k]
+		h.mu.Lock()
+		var cumulative uint64
+		for i, upper := range v.buckets {
+			cumulative += h.counts[i]
+			v.writeSample(w, "_bucket", k, []string{"le", formatFloat(upper)}, float64(cumulative))
+		}
+		v.writeSample(w, "_bucket", k, []string{"le", "+Inf"}, float64(h.count))
+		v.writeSample(w, "_sum", k, nil, h.sum)
+		v.writeSample(w, "_count", k, nil, float64(h.count))
+		h.mu.Unlock()
+	}
+}
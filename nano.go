@@ -21,6 +21,7 @@
 package nano
 
 import (
+	"context"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -32,11 +33,24 @@ import (
 	"github.com/nano-kit/go-nano/cluster"
 	"github.com/nano-kit/go-nano/internal/env"
 	"github.com/nano-kit/go-nano/internal/log"
+	"github.com/nano-kit/go-nano/logging"
 	"github.com/nano-kit/go-nano/scheduler"
+	"github.com/nano-kit/go-nano/session"
 )
 
 var running int32
 
+// logger writes the structured logs of package nano
+var logger = logging.Get("nano")
+
+// shutdownRequest asks Listen to shut down gracefully with the context
+type shutdownRequest struct {
+	ctx  context.Context
+	done chan error
+}
+
+var shutdownRequests = make(chan shutdownRequest)
+
 // VERSION returns current nano version
 var VERSION = "0.5.0"
 
@@ -68,30 +82,18 @@ func Listen(addr string, opts ...Option) {
 		env.Wd, _ = filepath.Abs(wd)
 	}
 
-	opt := cluster.NewOptions()
-	for _, option := range opts {
-		option(&opt)
-	}
-
-	// Use listen address as gate address in non-cluster mode
-	if !opt.IsMaster && opt.RegistryAddr == "" && opt.GateAddr == "" {
+	opts = append(opts[:len(opts):len(opts)], WithServiceAddr(addr), withListenDefaults())
+	server := NewServer(opts...)
+	if opt := server.Options(); !opt.IsMaster && opt.RegistryAddr == "" {
 		log.Print("current server running in singleton mode")
-		opt.GateAddr = addr
 	}
-
-	// Set the retry interval to 3 secondes if doesn't set by user
-	if opt.RegisterInterval == 0 {
-		opt.RegisterInterval = time.Second * 3
-	}
-
-	node := &cluster.Node{
-		Options:     opt,
-		ServiceAddr: addr,
-	}
-	err := node.Startup()
+	server.installDefaults()
+	err := server.Start(context.Background())
 	if err != nil {
 		log.Fatalf("node startup failure: %v", err)
 	}
+	node := server.Node()
+	cluster.SetDefaultNode(node)
 
 	if node.GateAddr != "" {
 		log.Printf("start nano gate server %s, gate address %s, service address %s",
@@ -104,18 +106,80 @@ func Listen(addr string, opts ...Option) {
 	sg := make(chan os.Signal, 1)
 	signal.Notify(sg, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM)
 
-	select {
-	case <-env.Die:
-		log.Print("the app will shutdown in a few seconds")
-	case s := <-sg:
-		log.Print("nano server got signal", s)
+	// reload the configuration file on SIGHUP
+	hup := make(chan os.Signal, 1)
+	if path := server.Options().ConfigFile; path != "" {
+		signal.Notify(hup, syscall.SIGHUP)
+		defer signal.Stop(hup)
+	}
+
+	var req *shutdownRequest
+	for {
+		select {
+		case <-hup:
+			if _, err := server.ReloadFile(server.Options().ConfigFile); err != nil {
+				log.Printf("reload configuration failed: %v", err)
+			}
+			continue
+		case <-env.Die:
+			log.Print("the app will shutdown in a few seconds")
+		case s := <-sg:
+			log.Print("nano server got signal", s)
+		case r := <-shutdownRequests:
+			log.Print("nano server is shutting down gracefully")
+			req = &r
+		}
+		break
 	}
 
 	log.Print("nano server is stopping...")
 
-	node.Shutdown()
+	if req != nil {
+		err = node.ShutdownContext(req.ctx)
+	} else {
+		node.Shutdown()
+	}
 	scheduler.Close()
 	atomic.StoreInt32(&running, 0)
+	if req != nil {
+		req.done <- err
+	}
+}
+
+// withListenDefaults makes the node started by Listen run the handlers in
+// scheduler.Default, so that the tasks of the package level functions of
+// scheduler are serialized with the handlers, and call the handlers of
+// session.Lifetime after the sessions are closed
+func withListenDefaults() Option {
+	return func(opt *cluster.Options) {
+		if opt.Scheduler == nil {
+			opt.Scheduler = scheduler.Default()
+		}
+		if opt.Lifecycle == nil {
+			opt.Lifecycle = session.NewLifecycle()
+		}
+		opt.Lifecycle.OnClosed(func(s *session.Session, _ session.CloseReason) {
+			session.Lifetime.Close(s)
+		})
+	}
+}
+
+// ShutdownContext shuts down nano gracefully. The gate stops accepting new
+// connections, the clients are kicked with the reason "server restarting"
+// and the redirect address, and the in-flight messages are drained until ctx
+// is done, then the sessions and components are closed.
+func ShutdownContext(ctx context.Context) error {
+	if atomic.LoadInt32(&running) == 0 {
+		return ErrNotRunning
+	}
+
+	done := make(chan error, 1)
+	select {
+	case shutdownRequests <- shutdownRequest{ctx: ctx, done: done}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return <-done
 }
 
 // Shutdown send a signal to let 'nano' shutdown itself.
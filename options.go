@@ -4,13 +4,18 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/nano-kit/go-nano/auth"
 	"github.com/nano-kit/go-nano/cluster"
 	"github.com/nano-kit/go-nano/component"
 	"github.com/nano-kit/go-nano/internal/env"
 	"github.com/nano-kit/go-nano/internal/log"
-	"github.com/nano-kit/go-nano/internal/message"
+	"github.com/nano-kit/go-nano/logging"
 	"github.com/nano-kit/go-nano/pipeline"
+	"github.com/nano-kit/go-nano/ratelimit"
+	"github.com/nano-kit/go-nano/scheduler"
 	"github.com/nano-kit/go-nano/serialize"
+	"github.com/nano-kit/go-nano/session"
+	"github.com/nano-kit/go-nano/trace"
 	"google.golang.org/grpc"
 )
 
@@ -42,10 +47,20 @@ func WithGateAddr(addr string) Option {
 	}
 }
 
-// WithGateID sets the gate server ID, which by default is 0.
+// WithServiceAddr sets the service address of the node, which the members
+// of the cluster connect to. It is also the gate address in non-cluster mode
+// if WithGateAddr is not set.
+func WithServiceAddr(addr string) Option {
+	return func(opt *cluster.Options) {
+		opt.ServiceAddr = addr
+	}
+}
+
+// WithGateID sets the gate server ID, which by default is 0. The session IDs
+// generated by the gates with distinct IDs are unique in the cluster.
 func WithGateID(index uint16) Option {
-	return func(_ *cluster.Options) {
-		env.GateID = index
+	return func(opt *cluster.Options) {
+		opt.GateID = index
 	}
 }
 
@@ -56,10 +71,14 @@ func WithMaster() Option {
 	}
 }
 
-// WithGrpcOptions sets the grpc dial options
+// WithGrpcOptions sets the grpc dial options, which are appended to the
+// default options of the process
 func WithGrpcOptions(opts ...grpc.DialOption) Option {
-	return func(_ *cluster.Options) {
-		env.GrpcOptions = append(env.GrpcOptions, opts...)
+	return func(opt *cluster.Options) {
+		if len(opt.GrpcOptions) == 0 {
+			opt.GrpcOptions = append([]grpc.DialOption(nil), env.GrpcOptions...)
+		}
+		opt.GrpcOptions = append(opt.GrpcOptions, opts...)
 	}
 }
 
@@ -72,8 +91,8 @@ func WithComponents(components *component.Components) Option {
 
 // WithHeartbeatInterval sets Heartbeat time interval
 func WithHeartbeatInterval(d time.Duration) Option {
-	return func(_ *cluster.Options) {
-		env.Heartbeat = d
+	return func(opt *cluster.Options) {
+		opt.Heartbeat = d
 	}
 }
 
@@ -84,17 +103,37 @@ func WithCheckOriginFunc(fn func(*http.Request) bool) Option {
 	}
 }
 
-// WithDebugMode let 'nano' to run under Debug mode.
+// WithDebugMode let 'nano' to run under Debug mode, which also lowers the
+// default log level to debug.
 func WithDebugMode() Option {
 	return func(_ *cluster.Options) {
 		env.Debug = true
+		logging.SetLevel("", logging.LevelDebug)
 	}
 }
 
-// WithDictionary sets routes map
+// WithDictionary sets routes map of the node, which is merged with the routes
+// set before
 func WithDictionary(dict map[string]uint16) Option {
-	return func(_ *cluster.Options) {
-		message.SetDictionary(dict)
+	return func(opt *cluster.Options) {
+		merged := make(map[string]uint16, len(opt.Dictionary)+len(dict))
+		for route, code := range opt.Dictionary {
+			merged[route] = code
+		}
+		for route, code := range dict {
+			merged[route] = code
+		}
+		opt.Dictionary = merged
+	}
+}
+
+// WithScheduler sets the scheduler running the handlers and the session
+// lifecycle callbacks, the node starts its own scheduler by default. The
+// node started by Listen uses scheduler.Default, on which the package level
+// functions of scheduler run the tasks.
+func WithScheduler(s *scheduler.Scheduler) Option {
+	return func(opt *cluster.Options) {
+		opt.Scheduler = s
 	}
 }
 
@@ -106,10 +145,11 @@ func WithWSPath(path string) Option {
 }
 
 // WithSerializer customizes application serializer, which automatically Marshal
-// and UnMarshal handler payload
+// and UnMarshal handler payload. The serializer of the server started by
+// Listen is also used by Group.
 func WithSerializer(serializer serialize.Serializer) Option {
 	return func(opt *cluster.Options) {
-		env.Serializer = serializer
+		opt.Serializer = serializer
 	}
 }
 
@@ -135,17 +175,62 @@ func WithTSLConfig(certificate, key string) Option {
 	}
 }
 
-// WithLogger overrides the default logger
+// WithLogger overrides the default logger, which also writes the leveled
+// and structured logs unless WithLogHandler is given after it
 func WithLogger(l log.Logger) Option {
 	return func(opt *cluster.Options) {
+		if l == nil {
+			return
+		}
 		log.SetLogger(l)
+		logging.SetHandler(logging.NewPrinterHandler(l))
+	}
+}
+
+// WithMonitorAddr sets the listen address of the node monitor, the first free
+// port after the service address is used if it is not specified
+func WithMonitorAddr(addr string) Option {
+	return func(opt *cluster.Options) {
+		opt.MonitorAddr = addr
+	}
+}
+
+// WithMonitorAuth protects the node monitor with HTTP basic authentication
+func WithMonitorAuth(username, password string) Option {
+	return func(opt *cluster.Options) {
+		opt.MonitorAuth = &cluster.BasicAuth{Username: username, Password: password}
+	}
+}
+
+// WithMonitorAdmin serves the endpoints which kick, push to and close the
+// sessions, change the log levels and reload the configuration even if the
+// node monitor is not protected by WithMonitorAuth
+func WithMonitorAdmin() Option {
+	return func(opt *cluster.Options) {
+		opt.MonitorAdmin = true
+	}
+}
+
+// WithLogHandler sets the handler writing the leveled and structured logs,
+// e.g. logging.NewSugaredHandler(zapLogger.Sugar())
+func WithLogHandler(h logging.Handler) Option {
+	return func(opt *cluster.Options) {
+		logging.SetHandler(h)
+	}
+}
+
+// WithLogLevel sets the log level of the package, the default level is set
+// if pkg is empty
+func WithLogLevel(pkg string, level logging.Level) Option {
+	return func(opt *cluster.Options) {
+		logging.SetLevel(pkg, level)
 	}
 }
 
 // WithHandshakeValidator sets the function that Verify `handshake` data
 func WithHandshakeValidator(fn func([]byte) error) Option {
 	return func(opt *cluster.Options) {
-		env.HandshakeValidator = fn
+		opt.HandshakeValidator = fn
 	}
 }
 
@@ -155,3 +240,143 @@ func WithHTTPHandler(pattern string, handler http.Handler) Option {
 		opt.ServeMux.Handle(pattern, handler)
 	}
 }
+
+// WithAuthenticator sets the authenticator which verifies the token carried by
+// the handshake request, only the routes in whitelist can be called before the
+// session is authenticated. Pattern "Service.*" matches all routes of a service.
+func WithAuthenticator(a auth.Authenticator, whitelist ...string) Option {
+	return func(opt *cluster.Options) {
+		opt.Authenticator = a
+		opt.AuthWhitelist = append(opt.AuthWhitelist, whitelist...)
+	}
+}
+
+// WithAuthResponse sets the response of the requests rejected before
+// authentication, which is serialized unless it is a []byte
+func WithAuthResponse(v interface{}) Option {
+	return func(opt *cluster.Options) {
+		opt.AuthResponse = v
+	}
+}
+
+// WithSessionSync sets the session data keys which are synchronized between the
+// gate session and backend sessions, the UID is always synchronized. Values are
+// encoded as JSON, so they must be serializable.
+func WithSessionSync(keys ...string) Option {
+	return func(opt *cluster.Options) {
+		opt.SyncKeys = append(opt.SyncKeys, keys...)
+	}
+}
+
+// WithBindPolicy limits the number of sessions which can be bound to a UID
+// across the cluster, e.g. cluster.KickPrevious enforces single login. The
+// policy should be set to all nodes in the cluster.
+func WithBindPolicy(policy cluster.BindPolicy) Option {
+	return func(opt *cluster.Options) {
+		opt.BindPolicy = &policy
+	}
+}
+
+// WithSessionRegistry sets the session directory which records the UID bindings,
+// by default the bindings are recorded in the memory of master node
+func WithSessionRegistry(registry cluster.SessionRegistry) Option {
+	return func(opt *cluster.Options) {
+		opt.SessionRegistry = registry
+	}
+}
+
+// WithSessionStore persists the values of the keys in client sessions to the
+// store, all values are persisted if no keys specified. The values are loaded
+// when an UID is bound and saved in background whenever they are changed.
+// Values are encoded as JSON, so they must be serializable.
+func WithSessionStore(store session.Store, keys ...string) Option {
+	return func(opt *cluster.Options) {
+		opt.SessionStore = store
+		opt.SessionStoreKeys = append(opt.SessionStoreKeys, keys...)
+	}
+}
+
+// WithSessionFlushInterval saves the changed session values periodically
+// instead of on every change
+func WithSessionFlushInterval(d time.Duration) Option {
+	return func(opt *cluster.Options) {
+		opt.SessionFlushInterval = d
+	}
+}
+
+// WithLifecycle sets the session lifecycle callbacks of current node
+func WithLifecycle(lc *session.Lifecycle) Option {
+	return func(opt *cluster.Options) {
+		opt.Lifecycle = lc
+	}
+}
+
+// WithRateLimit limits the message rate of client sessions on the gate
+func WithRateLimit(config ratelimit.Config) Option {
+	return func(opt *cluster.Options) {
+		opt.RateLimit = &config
+	}
+}
+
+// WithAdmission sets the admission control of client connections, such as the
+// maximum connections, the per-IP limit and the handshake timeout
+func WithAdmission(opts cluster.AdmissionOptions) Option {
+	return func(opt *cluster.Options) {
+		opt.AdmissionOptions = opts
+	}
+}
+
+// WithConnTimeouts sets the read idle timeout and the write timeout of client
+// connections, the session is closed when any of them expires, zero disables
+// the timeout
+func WithConnTimeouts(readIdle, write time.Duration) Option {
+	return func(opt *cluster.Options) {
+		opt.ReadIdleTimeout = readIdle
+		opt.WriteTimeout = write
+	}
+}
+
+// WithCompression compresses the message data not smaller than threshold bytes
+// with DEFLATE for the clients which negotiate compression in handshake
+func WithCompression(threshold int) Option {
+	return func(opt *cluster.Options) {
+		opt.CompressThreshold = threshold
+	}
+}
+
+// WithPacketLimits sets the maximum packet size and the maximum message
+// reassembled from the fragments of a session. The larger messages are sent
+// in fragments to the clients which negotiate fragments in handshake. Zero
+// keeps the default limit, a negative maxMessageSize rejects fragments.
+func WithPacketLimits(maxPacketSize, maxMessageSize int) Option {
+	return func(opt *cluster.Options) {
+		opt.MaxPacketSize = maxPacketSize
+		opt.MaxMessageSize = maxMessageSize
+	}
+}
+
+// WithWSCompression negotiates the permessage-deflate extension with WebSocket
+// clients, which compresses all the frames of the connection. The compression
+// of WithCompression is not negotiated with the clients using the extension.
+func WithWSCompression() Option {
+	return func(opt *cluster.Options) {
+		opt.WSCompression = true
+	}
+}
+
+// WithShutdown sets the drain timeout of the graceful shutdown triggered by
+// signals and the address the kicked clients should reconnect to
+func WithShutdown(timeout time.Duration, redirect string) Option {
+	return func(opt *cluster.Options) {
+		opt.ShutdownTimeout = timeout
+		opt.ShutdownRedirect = redirect
+	}
+}
+
+// WithTracer records the spans of messages passing through current node, the
+// trace context is propagated to the other nodes in gRPC metadata
+func WithTracer(tracer *trace.Tracer) Option {
+	return func(opt *cluster.Options) {
+		opt.Tracer = tracer
+	}
+}
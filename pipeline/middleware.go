@@ -0,0 +1,132 @@
+package pipeline
+
+import (
+	"strings"
+
+	"github.com/nano-kit/go-nano/component"
+	"github.com/nano-kit/go-nano/internal/message"
+	"github.com/nano-kit/go-nano/session"
+)
+
+type (
+	// Middleware is an around-style processing function, it calls ctx.Next()
+	// to run the remaining middlewares and the handler, or returns without
+	// calling ctx.Next() to short-circuit the message.
+	Middleware func(ctx *Context) error
+
+	// Context is the state of a message passing through the middlewares
+	Context struct {
+		Session *session.Session
+		Message *message.Message
+		Service *component.Service // the service of the handler, nil if not resolved
+		Handler *component.Handler // the handler will be called, nil if not resolved
+		Data    interface{}        // the decoded argument of the handler
+
+		middlewares []Middleware
+		final       func() error
+		responded   bool
+	}
+
+	// MiddlewareChannel is a Channel supporting middlewares, the channels
+	// of the pipeline created by New implement it
+	MiddlewareChannel interface {
+		Channel
+		Use(pattern string, mw Middleware)
+		Execute(ctx *Context, final func() error) error
+	}
+
+	scopedMiddleware struct {
+		pattern string
+		fn      Middleware
+	}
+)
+
+// NewContext returns a context of the message sent by session s
+func NewContext(s *session.Session, msg *message.Message) *Context {
+	return &Context{Session: s, Message: msg}
+}
+
+// Route returns the route of the message
+func (c *Context) Route() string {
+	return c.Message.Route
+}
+
+// Next runs the next middleware, the handler will be called after the
+// last middleware calls Next, the error of the handler is returned.
+func (c *Context) Next() error {
+	if len(c.middlewares) > 0 {
+		mw := c.middlewares[0]
+		c.middlewares = c.middlewares[1:]
+		return mw(c)
+	}
+	if final := c.final; final != nil {
+		c.final = nil
+		return final()
+	}
+	return nil
+}
+
+// Respond sends v as the response of the request message, the middleware
+// should return without calling Next to skip the handler.
+func (c *Context) Respond(v interface{}) error {
+	c.responded = true
+	return c.Session.ResponseMID(c.Message.ID, v)
+}
+
+// Responded decides whether a middleware has responded the message
+func (c *Context) Responded() bool {
+	return c.responded
+}
+
+// Match decides whether the route matches the pattern, the pattern is "*"
+// for all routes, "Service.*" for all handlers of the service, or the exact
+// route.
+func Match(pattern, route string) bool {
+	if pattern == "*" {
+		return true
+	}
+	if strings.HasSuffix(pattern, ".*") {
+		return strings.HasPrefix(route, pattern[:len(pattern)-1])
+	}
+	return pattern == route
+}
+
+// Middlewares returns the middleware support of channel c, it returns nil if
+// c is implemented without middlewares
+func Middlewares(c Channel) MiddlewareChannel {
+	mc, _ := c.(MiddlewareChannel)
+	return mc
+}
+
+// Execute runs the middlewares of channel c matching the route of the message,
+// final is called directly if c does not support middlewares
+func Execute(c Channel, ctx *Context, final func() error) error {
+	if mc := Middlewares(c); mc != nil {
+		return mc.Execute(ctx, final)
+	}
+	return final()
+}
+
+// Use appends a middleware for the routes matching the pattern
+func (p *pipelineChannel) Use(pattern string, mw Middleware) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.middlewares = append(p.middlewares, scopedMiddleware{pattern: pattern, fn: mw})
+}
+
+// Execute runs the middlewares matching the route of the message, final is
+// called by the last middleware
+func (p *pipelineChannel) Execute(ctx *Context, final func() error) error {
+	p.mu.RLock()
+	var matched []Middleware
+	for _, m := range p.middlewares {
+		if Match(m.pattern, ctx.Route()) {
+			matched = append(matched, m.fn)
+		}
+	}
+	p.mu.RUnlock()
+
+	ctx.middlewares = matched
+	ctx.final = final
+	return ctx.Next()
+}
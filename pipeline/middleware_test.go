@@ -0,0 +1,113 @@
+package pipeline
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/nano-kit/go-nano/internal/message"
+	"github.com/nano-kit/go-nano/mock"
+	"github.com/nano-kit/go-nano/session"
+)
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern, route string
+		match          bool
+	}{
+		{"*", "Room.Join", true},
+		{"Room.*", "Room.Join", true},
+		{"Room.*", "RoomManager.Join", false},
+		{"Room.Join", "Room.Join", true},
+		{"Room.Join", "Room.Leave", false},
+	}
+	for _, c := range cases {
+		if Match(c.pattern, c.route) != c.match {
+			t.Fatalf("Match(%q, %q) should be %v", c.pattern, c.route, c.match)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var calls []string
+	p := New()
+	in := Middlewares(p.Inbound())
+	in.Use("*", func(ctx *Context) error {
+		calls = append(calls, "before")
+		err := ctx.Next()
+		calls = append(calls, "after")
+		return err
+	})
+	in.Use("Lobby.*", func(ctx *Context) error {
+		calls = append(calls, "lobby")
+		return ctx.Next()
+	})
+	in.Use("Room.*", func(ctx *Context) error {
+		return ctx.Respond([]byte("full"))
+	})
+
+	entity := mock.NewNetworkEntity()
+	s := session.New(entity)
+	handlerErr := errors.New("handler error")
+	handler := func() error {
+		calls = append(calls, "handler")
+		return handlerErr
+	}
+
+	ctx := NewContext(s, &message.Message{Type: message.Request, ID: 1, Route: "Lobby.Join"})
+	if err := in.Execute(ctx, handler); err != handlerErr {
+		t.Fatalf("expect handler error, got %v", err)
+	}
+	if !reflect.DeepEqual(calls, []string{"before", "lobby", "handler", "after"}) {
+		t.Fatalf("unexpected calls %v", calls)
+	}
+
+	calls = nil
+	ctx = NewContext(s, &message.Message{Type: message.Request, ID: 2, Route: "Room.Join"})
+	if err := in.Execute(ctx, handler); err != nil {
+		t.Fatal(err)
+	}
+	if !ctx.Responded() || !reflect.DeepEqual(calls, []string{"before", "after"}) {
+		t.Fatalf("handler should be skipped, calls %v", calls)
+	}
+	if string(entity.FindResponseByMID(2).([]byte)) != "full" {
+		t.Fatalf("unexpected response %v", entity.FindResponseByMID(2))
+	}
+}
+
+type legacyPipeline struct{ in, out Channel }
+
+func (p legacyPipeline) Inbound() Channel  { return p.in }
+func (p legacyPipeline) Outbound() Channel { return p.out }
+
+func TestStageOf(t *testing.T) {
+	p := New()
+	if StageOf(p, BackendIngress) != p.Inbound() || StageOf(p, GateEgress) != p.Outbound() {
+		t.Fatal("Inbound and Outbound should be the backend ingress and gate egress")
+	}
+	if StageOf(p, GateIngress) == nil || StageOf(p, BackendEgress) == nil {
+		t.Fatal("all stages should be supported")
+	}
+
+	legacy := legacyPipeline{in: New().Inbound(), out: New().Outbound()}
+	if StageOf(legacy, BackendIngress) != legacy.in || StageOf(legacy, GateIngress) != nil {
+		t.Fatal("unexpected stages of legacy pipeline")
+	}
+	if StageOf(nil, GateIngress) != nil {
+		t.Fatal("nil pipeline has no stages")
+	}
+}
+
+type legacyChannel struct{ Channel }
+
+func TestExecuteWithoutMiddlewares(t *testing.T) {
+	c := legacyChannel{New().Inbound()}
+	if Middlewares(c) != nil {
+		t.Fatal("legacy channel does not support middlewares")
+	}
+	called := false
+	ctx := NewContext(session.New(mock.NewNetworkEntity()), &message.Message{Route: "Room.Join"})
+	if err := Execute(c, ctx, func() error { called = true; return nil }); err != nil || !called {
+		t.Fatalf("final should be called directly, got %v, %v", called, err)
+	}
+}
@@ -20,8 +20,13 @@ type (
 		Inbound() Channel
 	}
 
+	// Stage identifies where the messages pass through a channel
+	Stage int
+
 	pipeline struct {
 		outbound, inbound *pipelineChannel
+		gateIngress       *pipelineChannel
+		backendEgress     *pipelineChannel
 	}
 
 	// Channel is a container of pipeline processing functions
@@ -32,22 +37,74 @@ type (
 	}
 
 	pipelineChannel struct {
-		mu       sync.RWMutex
-		handlers []Func
+		mu          sync.RWMutex
+		handlers    []Func
+		middlewares []scopedMiddleware
 	}
 )
 
+// Pipeline stages, the messages of a client pass through the stages in order
+const (
+	// GateIngress runs on the gate for every message from clients before routing
+	GateIngress Stage = iota
+	// BackendIngress runs on the node which handles the message, it is the
+	// Inbound channel
+	BackendIngress
+	// BackendEgress runs on the backend for the responses and pushes sent to
+	// the gate
+	BackendEgress
+	// GateEgress runs on the gate for every message written to clients,
+	// including the pushes arriving from backends, it is the Outbound channel
+	GateEgress
+)
+
 // New creates a pipeline
 func New() Pipeline {
 	return &pipeline{
-		outbound: &pipelineChannel{},
-		inbound:  &pipelineChannel{},
+		outbound:      &pipelineChannel{},
+		inbound:       &pipelineChannel{},
+		gateIngress:   &pipelineChannel{},
+		backendEgress: &pipelineChannel{},
 	}
 }
 
 func (p *pipeline) Outbound() Channel { return p.outbound }
 func (p *pipeline) Inbound() Channel  { return p.inbound }
 
+// Stage returns the channel of the stage
+func (p *pipeline) Stage(st Stage) Channel {
+	switch st {
+	case GateIngress:
+		return p.gateIngress
+	case BackendIngress:
+		return p.inbound
+	case BackendEgress:
+		return p.backendEgress
+	case GateEgress:
+		return p.outbound
+	}
+	return nil
+}
+
+// StageOf returns the channel of the stage in pipeline p, the pipeline which
+// only implements the Pipeline interface supports BackendIngress and GateEgress.
+// It returns nil if the stage is not supported.
+func StageOf(p Pipeline, st Stage) Channel {
+	if p == nil {
+		return nil
+	}
+	if s, ok := p.(interface{ Stage(Stage) Channel }); ok {
+		return s.Stage(st)
+	}
+	switch st {
+	case BackendIngress:
+		return p.Inbound()
+	case GateEgress:
+		return p.Outbound()
+	}
+	return nil
+}
+
 // PushFront push a function to the front of the pipeline
 func (p *pipelineChannel) PushFront(h Func) {
 	p.mu.Lock()
@@ -0,0 +1,15 @@
+package nano
+
+import "github.com/nano-kit/go-nano/cluster"
+
+// PushToUID pushes the message to all sessions bound to uid, the sessions
+// can be connected to any gate in the cluster.
+func PushToUID(uid, route string, v interface{}) error {
+	return cluster.PushToUID(uid, route, v)
+}
+
+// PushToUIDs pushes the message to all sessions bound to the uids, the
+// uids which are not bound to any session are ignored.
+func PushToUIDs(uids []string, route string, v interface{}) error {
+	return cluster.PushToUIDs(uids, route, v)
+}
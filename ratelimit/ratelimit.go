@@ -0,0 +1,309 @@
+// Package ratelimit implements the token bucket rate limiter which protects
+// the gate from the clients flooding messages.
+package ratelimit
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nano-kit/go-nano/pipeline"
+)
+
+// DefaultResponse is the response of the limited request if Config.Response
+// is not specified, it is passed to the client without serialization
+var DefaultResponse = []byte(`{"code":429,"error":"rate limit exceeded"}`)
+
+// Action is what to do with the message exceeding the rate limit
+type Action int
+
+const (
+	// Drop discards the message silently
+	Drop Action = iota
+	// Respond responds the request with Config.Response, the notify is dropped
+	Respond
+)
+
+// Result is the decision of the limiter
+type Result int
+
+const (
+	// Allowed represents the message is allowed
+	Allowed Result = iota
+	// Limited represents the message exceeds the rate limit
+	Limited
+	// Kick represents the session exceeds the rate limit repeatedly and should be closed
+	Kick
+)
+
+// Limit is the rate and burst of a token bucket, zero Rate means unlimited
+type Limit struct {
+	Rate  float64 // tokens per second
+	Burst int     // maximum tokens, at least 1
+}
+
+// Config configures the rate limiter
+type Config struct {
+	Global  Limit            // all messages received by the node
+	UID     Limit            // messages of each UID, shared by the sessions of the UID
+	Session Limit            // messages of each session
+	Routes  map[string]Limit // messages of each session on the routes matching the pattern, e.g. "Room.*"
+
+	Action   Action      // what to do with the limited message
+	Response interface{} // the response of the limited request when Action is Respond
+
+	KickAfter  int           // kick the session after the number of violations in KickWindow, zero to disable
+	KickWindow time.Duration // defaults to 10 seconds
+}
+
+// Bucket is a token bucket
+type Bucket struct {
+	mu     sync.Mutex
+	limit  Limit
+	tokens float64
+	last   time.Time
+}
+
+// NewBucket returns a full token bucket
+func NewBucket(limit Limit) *Bucket {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	return &Bucket{limit: limit, tokens: float64(limit.Burst)}
+}
+
+// Allow takes a token from the bucket if there is one
+func (b *Bucket) Allow() bool {
+	return b.AllowAt(time.Now())
+}
+
+// AllowAt takes a token from the bucket at the time now
+func (b *Bucket) AllowAt(now time.Time) bool {
+	if b.limit.Rate <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.refill(now) {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// availableAt reports whether there is a token at the time now without
+// taking it
+func (b *Bucket) availableAt(now time.Time) bool {
+	if b.limit.Rate <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.refill(now)
+}
+
+// take takes a token reported by availableAt
+func (b *Bucket) take() {
+	if b.limit.Rate <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	b.tokens--
+	b.mu.Unlock()
+}
+
+// refill adds the tokens generated since last time, it returns whether there
+// is a token
+func (b *Bucket) refill(now time.Time) bool {
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.limit.Rate
+		if max := float64(b.limit.Burst); b.tokens > max {
+			b.tokens = max
+		}
+	}
+	b.last = now
+	return b.tokens >= 1
+}
+
+type (
+	sessionState struct {
+		uid        string
+		bucket     *Bucket
+		routes     map[string]*Bucket
+		violations int
+		windowEnd  time.Time
+	}
+
+	uidState struct {
+		bucket *Bucket
+		refs   int
+	}
+
+	// Limiter applies the rate limits of Config to the messages of sessions
+	Limiter struct {
+		config   Config
+		patterns []string // route patterns, the most specific first
+
+		mu       sync.Mutex
+		global   *Bucket
+		sessions map[int64]*sessionState
+		uids     map[string]*uidState
+	}
+)
+
+// New returns a Limiter of the config
+func New(config Config) *Limiter {
+	if config.KickWindow <= 0 {
+		config.KickWindow = 10 * time.Second
+	}
+	if config.Response == nil {
+		config.Response = DefaultResponse
+	}
+	patterns := make([]string, 0, len(config.Routes))
+	for p := range config.Routes {
+		patterns = append(patterns, p)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+	return &Limiter{
+		config:   config,
+		patterns: patterns,
+		global:   NewBucket(config.Global),
+		sessions: map[int64]*sessionState{},
+		uids:     map[string]*uidState{},
+	}
+}
+
+// Config returns the config of the limiter
+func (l *Limiter) Config() Config {
+	return l.config
+}
+
+// Allow decides whether the message on route sent by the session is allowed
+func (l *Limiter) Allow(sid int64, uid, route string) Result {
+	return l.AllowAt(sid, uid, route, time.Now())
+}
+
+// AllowAt decides whether the message is allowed at the time now. Only the
+// violations of the route, session and UID limits count toward kicking the
+// session, the session is not kicked because of the global limit.
+func (l *Limiter) AllowAt(sid int64, uid, route string, now time.Time) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	s := l.session(sid, uid)
+	allowed, violated := l.allow(s, route, now)
+	if allowed {
+		return Allowed
+	}
+
+	if !violated || l.config.KickAfter <= 0 {
+		return Limited
+	}
+	if now.After(s.windowEnd) {
+		s.violations = 0
+		s.windowEnd = now.Add(l.config.KickWindow)
+	}
+	s.violations++
+	if s.violations >= l.config.KickAfter {
+		return Kick
+	}
+	return Limited
+}
+
+// allow takes a token from each bucket limiting the message only if all of
+// them have one, violated reports whether the message is denied by the
+// limits of the session rather than the global limit
+func (l *Limiter) allow(s *sessionState, route string, now time.Time) (allowed, violated bool) {
+	buckets := make([]*Bucket, 0, 4)
+	if b := s.routeBucket(l, route); b != nil {
+		buckets = append(buckets, b)
+	}
+	buckets = append(buckets, s.bucket)
+	if s.uid != "" {
+		buckets = append(buckets, l.uids[s.uid].bucket)
+	}
+	for _, b := range buckets {
+		if !b.availableAt(now) {
+			return false, true
+		}
+	}
+	if !l.global.availableAt(now) {
+		return false, false
+	}
+
+	for _, b := range buckets {
+		b.take()
+	}
+	l.global.take()
+	return true, false
+}
+
+// session returns the state of the session, the UID bucket is switched if
+// the session is bound to another UID
+func (l *Limiter) session(sid int64, uid string) *sessionState {
+	s, found := l.sessions[sid]
+	if !found {
+		s = &sessionState{bucket: NewBucket(l.config.Session), routes: map[string]*Bucket{}}
+		l.sessions[sid] = s
+	}
+	if s.uid != uid {
+		l.release(s.uid)
+		s.uid = uid
+		if uid != "" {
+			u, found := l.uids[uid]
+			if !found {
+				u = &uidState{bucket: NewBucket(l.config.UID)}
+				l.uids[uid] = u
+			}
+			u.refs++
+		}
+	}
+	return s
+}
+
+func (l *Limiter) release(uid string) {
+	if uid == "" {
+		return
+	}
+	if u, found := l.uids[uid]; found {
+		u.refs--
+		if u.refs <= 0 {
+			delete(l.uids, uid)
+		}
+	}
+}
+
+func (s *sessionState) routeBucket(l *Limiter, route string) *Bucket {
+	for _, p := range l.patterns {
+		if !pipeline.Match(p, route) {
+			continue
+		}
+		b, found := s.routes[p]
+		if !found {
+			b = NewBucket(l.config.Routes[p])
+			s.routes[p] = b
+		}
+		return b
+	}
+	return nil
+}
+
+// Remove releases the state of the closed session
+func (l *Limiter) Remove(sid int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if s, found := l.sessions[sid]; found {
+		l.release(s.uid)
+		delete(l.sessions, sid)
+	}
+}
@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucket(t *testing.T) {
+	b := NewBucket(Limit{Rate: 10, Burst: 2})
+	now := time.Now()
+	if !b.AllowAt(now) || !b.AllowAt(now) {
+		t.Fatal("burst should be allowed")
+	}
+	if b.AllowAt(now) {
+		t.Fatal("bucket should be empty")
+	}
+	if !b.AllowAt(now.Add(100 * time.Millisecond)) {
+		t.Fatal("a token should be refilled")
+	}
+	if !NewBucket(Limit{}).AllowAt(now) {
+		t.Fatal("zero rate should be unlimited")
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	l := New(Config{
+		Session:   Limit{Rate: 100, Burst: 100},
+		UID:       Limit{Rate: 1, Burst: 3},
+		Routes:    map[string]Limit{"Room.*": {Rate: 1, Burst: 1}},
+		KickAfter: 3,
+	})
+	now := time.Now()
+
+	if l.AllowAt(1, "", "Room.Join", now) != Allowed {
+		t.Fatal("first message should be allowed")
+	}
+	if l.AllowAt(1, "", "Room.Join", now) != Limited {
+		t.Fatal("route limit should be applied")
+	}
+	if l.AllowAt(2, "", "Room.Join", now) != Allowed {
+		t.Fatal("route limit is applied to each session")
+	}
+
+	// sessions of the same UID share the UID limit
+	for i := 0; i < 3; i++ {
+		if l.AllowAt(int64(3+i%2), "u1", "Lobby.Chat", now) != Allowed {
+			t.Fatalf("message %d should be allowed", i)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if l.AllowAt(4, "u1", "Lobby.Chat", now) != Limited {
+			t.Fatal("UID limit should be applied")
+		}
+	}
+	if l.AllowAt(4, "u1", "Lobby.Chat", now) != Kick {
+		t.Fatal("session should be kicked after repeated violations")
+	}
+
+	for sid := int64(1); sid <= 4; sid++ {
+		l.Remove(sid)
+	}
+	if len(l.uids) != 0 || len(l.sessions) != 0 {
+		t.Fatal("state should be released")
+	}
+}
+
+func TestLimiterAtomic(t *testing.T) {
+	l := New(Config{
+		Global:    Limit{Rate: 1, Burst: 1},
+		Session:   Limit{Rate: 1, Burst: 2},
+		KickAfter: 1,
+	})
+	now := time.Now()
+
+	if l.AllowAt(1, "", "Room.Join", now) != Allowed {
+		t.Fatal("first message should be allowed")
+	}
+	// the global limit does not kick the session
+	if l.AllowAt(1, "", "Room.Join", now) != Limited {
+		t.Fatal("global limit should be applied")
+	}
+	// the denied message takes no token from the session bucket
+	if tokens := l.sessions[1].bucket.tokens; tokens != 1 {
+		t.Fatalf("session bucket should keep 1 token, got %v", tokens)
+	}
+}
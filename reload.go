@@ -0,0 +1,175 @@
+package nano
+
+import (
+	"reflect"
+	"sort"
+	"sync/atomic"
+
+	"github.com/nano-kit/go-nano/cluster"
+	"github.com/nano-kit/go-nano/internal/env"
+	"github.com/nano-kit/go-nano/logging"
+)
+
+// setting is an option compared by Reload, keyed by the configuration key
+type setting struct {
+	key string
+	get func(opt *cluster.Options) interface{}
+}
+
+// reloadableSettings are applied to the running node by Reload
+var reloadableSettings = []setting{
+	{"heartbeat", func(opt *cluster.Options) interface{} { return opt.Heartbeat }},
+	{"readIdleTimeout", func(opt *cluster.Options) interface{} { return opt.ReadIdleTimeout }},
+	{"writeTimeout", func(opt *cluster.Options) interface{} { return opt.WriteTimeout }},
+	{"rateLimit", func(opt *cluster.Options) interface{} { return opt.RateLimit }},
+}
+
+// restartSettings take effect after the node is restarted, the settings
+// constructing components, such as the serializer and tracer, are not compared
+var restartSettings = []setting{
+	{"master", func(opt *cluster.Options) interface{} { return opt.IsMaster }},
+	{"registryAddr", func(opt *cluster.Options) interface{} { return opt.RegistryAddr }},
+	{"registerInterval", func(opt *cluster.Options) interface{} { return opt.RegisterInterval }},
+	{"gateAddr", func(opt *cluster.Options) interface{} { return opt.GateAddr }},
+	{"label", func(opt *cluster.Options) interface{} { return opt.Label }},
+	{"isWebsocket", func(opt *cluster.Options) interface{} { return opt.IsWebsocket }},
+	{"wsPath", func(opt *cluster.Options) interface{} { return opt.WSPath }},
+	{"wsCompression", func(opt *cluster.Options) interface{} { return opt.WSCompression }},
+	{"tls", func(opt *cluster.Options) interface{} { return [2]string{opt.TSLCertificate, opt.TSLKey} }},
+	{"compression", func(opt *cluster.Options) interface{} { return opt.CompressThreshold }},
+	{"maxPacketSize", func(opt *cluster.Options) interface{} { return opt.MaxPacketSize }},
+	{"maxMessageSize", func(opt *cluster.Options) interface{} { return opt.MaxMessageSize }},
+	{"admission", func(opt *cluster.Options) interface{} { return opt.AdmissionOptions }},
+	{"shutdown", func(opt *cluster.Options) interface{} { return opt.ShutdownOptions }},
+	{"sessionSync", func(opt *cluster.Options) interface{} { return opt.SyncKeys }},
+	{"bindPolicy", func(opt *cluster.Options) interface{} { return opt.BindPolicy }},
+	{"sessionFlushInterval", func(opt *cluster.Options) interface{} { return opt.SessionFlushInterval }},
+	{"monitorAddr", func(opt *cluster.Options) interface{} { return opt.MonitorAddr }},
+	{"monitorAuth", func(opt *cluster.Options) interface{} { return opt.MonitorAuth }},
+	{"monitorAdmin", func(opt *cluster.Options) interface{} { return opt.MonitorAdmin }},
+	{"gateID", func(opt *cluster.Options) interface{} { return opt.GateID }},
+	{"dictionary", func(opt *cluster.Options) interface{} { return opt.Dictionary }},
+}
+
+// WithConfigFile sets the configuration file reloaded on SIGHUP by Listen
+// and on POST /debug/nano/config/reload of the node monitor, which is served
+// only with WithMonitorAuth or WithMonitorAdmin. The file is not loaded by
+// this option, see LoadConfig.
+func WithConfigFile(path string) Option {
+	return func(opt *cluster.Options) {
+		opt.ConfigFile = path
+	}
+}
+
+// Reload applies the configuration to the running server. The debug mode, log
+// levels, heartbeat, connection timeouts and rate limits are applied live, and
+// they are reset to the settings the server is created with if missing from
+// the configuration. The other changed settings are reported to require
+// restart. The debug mode and the log levels are process-wide, so
+// ErrMultipleServers is returned if other servers are running in current
+// process.
+func (s *Server) Reload(c *Config) (*cluster.ReloadReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.node == nil {
+		return nil, ErrServerNotStarted
+	}
+	if atomic.LoadInt32(&runningServers) > 1 {
+		return nil, ErrMultipleServers
+	}
+
+	next := s.initial
+	if len(c.SessionSync) > 0 {
+		next.SyncKeys = nil // WithSessionSync appends the keys
+	}
+	for _, option := range c.nodeOptions() {
+		option(&next)
+	}
+
+	report := &cluster.ReloadReport{}
+	for _, setting := range reloadableSettings {
+		if !reflect.DeepEqual(setting.get(&s.opts), setting.get(&next)) {
+			report.Applied = append(report.Applied, setting.key)
+		}
+	}
+	for _, setting := range restartSettings {
+		if !reflect.DeepEqual(setting.get(&s.opts), setting.get(&next)) {
+			report.RestartRequired = append(report.RestartRequired, setting.key)
+		}
+	}
+	if debug := s.initialDebug || c.Debug; env.Debug != debug {
+		env.Debug = debug
+		report.Applied = append(report.Applied, "debug")
+	}
+	if reloadLogLevels(s.initialLevels, c) {
+		report.Applied = append(report.Applied, "logLevels")
+	}
+
+	s.node.Reload(cluster.Reloadable{
+		Heartbeat:       next.Heartbeat,
+		ReadIdleTimeout: next.ReadIdleTimeout,
+		WriteTimeout:    next.WriteTimeout,
+		RateLimit:       next.RateLimit,
+	})
+	s.opts.Heartbeat = next.Heartbeat
+	s.opts.ReadIdleTimeout = next.ReadIdleTimeout
+	s.opts.WriteTimeout = next.WriteTimeout
+	s.opts.RateLimit = next.RateLimit
+	return report, nil
+}
+
+// ReloadFile loads the configuration file and applies it, see Reload
+func (s *Server) ReloadFile(path string) (*cluster.ReloadReport, error) {
+	c, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	report, err := s.Reload(c)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("configuration reloaded", "path", path, "applied", report.Applied,
+		"restartRequired", report.RestartRequired)
+	return report, nil
+}
+
+// reloadLogLevels sets the log levels of the configuration over the initial
+// levels, the default level is debug in debug mode, and the levels of the
+// other packages are removed
+func reloadLogLevels(initial map[string]string, c *Config) bool {
+	target := make(map[string]string, len(initial)+len(c.LogLevels))
+	for pkg, name := range initial {
+		target[pkg] = name
+	}
+	if c.Debug {
+		target[""] = logging.LevelDebug.String()
+	}
+	for pkg, name := range c.LogLevels {
+		level, _ := logging.ParseLevel(name)
+		target[pkg] = level.String()
+	}
+
+	changed := false
+	levels := logging.Levels()
+	for _, pkg := range logging.Packages() {
+		if _, found := target[pkg]; !found {
+			logging.ResetLevel(pkg)
+			changed = true
+		}
+	}
+
+	pkgs := make([]string, 0, len(target))
+	for pkg := range target {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	for _, pkg := range pkgs {
+		if levels[pkg] != target[pkg] {
+			level, _ := logging.ParseLevel(target[pkg])
+			logging.SetLevel(pkg, level)
+			changed = true
+		}
+	}
+	return changed
+}
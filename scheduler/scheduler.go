@@ -35,8 +35,25 @@ type LocalScheduler interface {
 // Task is a function
 type Task func()
 
-// SystemTimedSched is the library level timed-scheduler
-var systemTimedSched *TimedSched = NewTimedSched(1)
+// Scheduler runs the tasks one by one in its goroutine, each node has its own
+// scheduler running the handlers
+type Scheduler struct {
+	ts *TimedSched
+}
+
+// New returns a started scheduler
+func New() *Scheduler {
+	return &Scheduler{ts: NewTimedSched(1)}
+}
+
+// systemScheduler is the library level scheduler used by the package level
+// functions and the node started by nano.Listen
+var systemScheduler = New()
+
+// Default returns the library level scheduler
+func Default() *Scheduler {
+	return systemScheduler
+}
 
 func try(f Task) Task {
 	return func() {
@@ -50,30 +67,92 @@ func try(f Task) Task {
 }
 
 // Close stops the scheduler
-func Close() {
-	systemTimedSched.Close()
-	log.Print("scheduler stopped")
+func (s *Scheduler) Close() {
+	s.ts.Close()
 }
 
 // Run add task to scheduler for immediate execution
-func Run(task Task) {
-	systemTimedSched.Run(try(task))
+func (s *Scheduler) Run(task Task) {
+	s.ts.Run(try(task))
+}
+
+// Schedule implements the LocalScheduler interface, the handlers of the
+// components scheduled by it run in the scheduler
+func (s *Scheduler) Schedule(task Task) {
+	s.Run(task)
+}
+
+// TryRun adds task to scheduler for immediate execution without blocking, it
+// reports false if the queue is full or the scheduler is closed
+func (s *Scheduler) TryRun(task Task) bool {
+	return s.ts.TryRun(try(task))
 }
 
 type repeatableTask struct {
 	Task
 	interval time.Duration
+	die      <-chan struct{}
+	ts       *TimedSched
 }
 
 func (r repeatableTask) run() {
+	select {
+	case <-r.die:
+		return
+	default:
+	}
 	now := time.Now()
 	r.Task()
-	systemTimedSched.Put(r.run, now.Add(r.interval))
+	r.ts.Put(r.run, now.Add(r.interval))
 }
 
 // Repeat runs the task repeatly at every interval
-func Repeat(task Task, interval time.Duration) {
-	r := repeatableTask{try(task), interval}
+func (s *Scheduler) Repeat(task Task, interval time.Duration) {
+	s.RepeatUntil(task, interval, nil)
+}
+
+// RepeatUntil runs the task repeatly at every interval until die is closed
+func (s *Scheduler) RepeatUntil(task Task, interval time.Duration, die <-chan struct{}) {
+	r := repeatableTask{try(task), interval, die, s.ts}
 	now := time.Now()
-	systemTimedSched.Put(r.run, now.Add(interval))
+	s.ts.Put(r.run, now.Add(interval))
+}
+
+// QueueLen returns the number of tasks waiting for immediate execution
+func (s *Scheduler) QueueLen() int {
+	return s.ts.QueueLen()
+}
+
+// Close stops the library level scheduler
+func Close() {
+	systemScheduler.Close()
+	log.Print("scheduler stopped")
+}
+
+// Run add task to the library level scheduler for immediate execution
+func Run(task Task) {
+	systemScheduler.Run(task)
+}
+
+// TryRun adds task to the library level scheduler for immediate execution
+// without blocking, it reports false if the queue is full or the scheduler is
+// closed
+func TryRun(task Task) bool {
+	return systemScheduler.TryRun(task)
+}
+
+// Repeat runs the task repeatly at every interval in the library level scheduler
+func Repeat(task Task, interval time.Duration) {
+	systemScheduler.Repeat(task, interval)
+}
+
+// RepeatUntil runs the task repeatly at every interval in the library level
+// scheduler until die is closed
+func RepeatUntil(task Task, interval time.Duration, die <-chan struct{}) {
+	systemScheduler.RepeatUntil(task, interval, die)
+}
+
+// QueueLen returns the number of tasks waiting in the library level scheduler
+func QueueLen() int {
+	return systemScheduler.QueueLen()
 }
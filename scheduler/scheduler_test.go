@@ -24,3 +24,66 @@ func TestRunAndRepeat(t *testing.T) {
 		t.Error()
 	}
 }
+
+func TestTryRun(t *testing.T) {
+	ts := NewTimedSched(1)
+	done := make(chan struct{})
+	if !ts.TryRun(func() { close(done) }) {
+		t.Fatal("task should be queued")
+	}
+	<-done
+	ts.Close()
+	if ts.TryRun(func() {}) {
+		t.Fatal("task should not be queued after the scheduler is closed")
+	}
+}
+
+func TestRepeatUntil(t *testing.T) {
+	die := make(chan struct{})
+	count := make(chan struct{}, 1)
+	RepeatUntil(func() {
+		select {
+		case count <- struct{}{}:
+		default:
+		}
+	}, time.Millisecond, die)
+	<-count
+	close(die)
+	time.Sleep(5 * time.Millisecond)
+	select {
+	case <-count:
+	default:
+	}
+	time.Sleep(5 * time.Millisecond)
+	select {
+	case <-count:
+		t.Fatal("task should not be repeated after die is closed")
+	default:
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	s := New()
+	done := make(chan struct{})
+	s.Run(func() { close(done) })
+	<-done
+
+	repeated := make(chan struct{}, 1)
+	die := make(chan struct{})
+	s.RepeatUntil(func() {
+		select {
+		case repeated <- struct{}{}:
+		default:
+		}
+	}, time.Millisecond, die)
+	<-repeated
+	close(die)
+
+	s.Close()
+	if s.TryRun(func() {}) {
+		t.Fatal("task should not be queued after the scheduler is closed")
+	}
+	if !TryRun(func() {}) {
+		t.Fatal("closing a scheduler should not close the library level scheduler")
+	}
+}
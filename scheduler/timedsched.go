@@ -183,6 +183,26 @@ func (ts *TimedSched) Run(f func()) {
 	ts.chRunnable <- f
 }
 
+// TryRun a function 'f' immediately if it can be queued without blocking
+func (ts *TimedSched) TryRun(f func()) bool {
+	select {
+	case <-ts.die:
+		return false
+	default:
+	}
+	select {
+	case ts.chRunnable <- f:
+		return true
+	default:
+		return false
+	}
+}
+
+// QueueLen returns the number of tasks waiting for immediate execution
+func (ts *TimedSched) QueueLen() int {
+	return len(ts.chRunnable)
+}
+
 // Close terminates this scheduler
 func (ts *TimedSched) Close() {
 	ts.dieOnce.Do(func() {
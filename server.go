@@ -0,0 +1,151 @@
+package nano
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nano-kit/go-nano/cluster"
+	"github.com/nano-kit/go-nano/internal/env"
+	"github.com/nano-kit/go-nano/logging"
+)
+
+// Server is a nano node which can be embedded into an application. Unlike
+// Listen, it does not block or handle signals. The heartbeat, serializer,
+// handshake validator, gRPC dial options, node monitor, metrics, route
+// dictionary, session ID generator and session lifecycle callbacks are
+// configured per server. Each server runs its handlers in its own scheduler
+// unless WithScheduler is set, and stopping a server closes its sessions, its
+// monitor and its scheduler, so a server can be stopped and started again in
+// one process, e.g. in tests.
+//
+// The metrics registered to cluster.Metrics, the debug mode and the log
+// levels are process-wide, so Reload is rejected while several servers are
+// running. The package level PushToUID, PushToUIDs and
+// FindSessionByUID use the node started by Listen, use the methods of
+// Server.Node instead.
+type Server struct {
+	opts cluster.Options
+
+	// the settings the server is created with, which are kept by Reload if
+	// missing from the configuration file
+	initial       cluster.Options
+	initialLevels map[string]string
+	initialDebug  bool
+
+	mu   sync.Mutex
+	node *cluster.Node
+}
+
+// runningServers is the number of the servers started in current process
+var runningServers int32
+
+// NewServer returns a server listening on the service address set by
+// WithServiceAddr
+func NewServer(opts ...Option) *Server {
+	opt := cluster.NewOptions()
+	for _, option := range opts {
+		option(&opt)
+	}
+
+	// Use listen address as gate address in non-cluster mode
+	if !opt.IsMaster && opt.RegistryAddr == "" && opt.GateAddr == "" {
+		opt.GateAddr = opt.ServiceAddr
+	}
+
+	// Set the retry interval to 3 secondes if doesn't set by user
+	if opt.RegisterInterval == 0 {
+		opt.RegisterInterval = time.Second * 3
+	}
+
+	return &Server{
+		opts:          opt,
+		initial:       opt,
+		initialLevels: logging.Levels(),
+		initialDebug:  env.Debug,
+	}
+}
+
+// Options returns the options of the server, which are updated by Reload
+func (s *Server) Options() cluster.Options {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.opts
+}
+
+// Node returns the node of the server, nil if the server is not started
+func (s *Server) Node() *cluster.Node {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.node
+}
+
+// Start starts the server without blocking. If ctx is done before the node
+// is started, the error of ctx is returned and the node is shut down once
+// the startup completes.
+func (s *Server) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.node != nil {
+		return ErrServerStarted
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	node := &cluster.Node{Options: s.opts}
+	if path := s.opts.ConfigFile; path != "" && node.Reloader == nil {
+		node.Reloader = func() (*cluster.ReloadReport, error) { return s.ReloadFile(path) }
+	}
+	done := make(chan error, 1)
+	go func() { done <- node.Startup() }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return err
+		}
+	case <-ctx.Done():
+		go func() {
+			if err := <-done; err == nil {
+				node.Shutdown()
+			}
+		}()
+		return ctx.Err()
+	}
+
+	s.node = node
+	atomic.AddInt32(&runningServers, 1)
+	return nil
+}
+
+// Stop shuts down the server gracefully, the client sessions are drained
+// until ctx is done, see cluster.Node.ShutdownContext
+func (s *Server) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	node := s.node
+	s.node = nil
+	s.mu.Unlock()
+
+	if node == nil {
+		return ErrServerNotStarted
+	}
+	atomic.AddInt32(&runningServers, -1)
+	return node.ShutdownContext(ctx)
+}
+
+// installDefaults makes the per-server settings the defaults of current
+// process, which are used out of the server, e.g. by Group
+func (s *Server) installDefaults() {
+	if s.opts.Heartbeat > 0 {
+		env.Heartbeat = s.opts.Heartbeat
+	}
+	if s.opts.Serializer != nil {
+		env.Serializer = s.opts.Serializer
+	}
+	if s.opts.HandshakeValidator != nil {
+		env.HandshakeValidator = s.opts.HandshakeValidator
+	}
+}
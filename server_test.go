@@ -0,0 +1,141 @@
+package nano
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/nano-kit/go-nano/cluster"
+	"github.com/nano-kit/go-nano/internal/codec"
+	"github.com/nano-kit/go-nano/internal/packet"
+	"github.com/nano-kit/go-nano/scheduler"
+)
+
+func freeAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func handshakeHeartbeat(t *testing.T, addr string) float64 {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	data, _ := codec.Encode(packet.Handshake, nil)
+	if _, err := conn.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 1024)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	packets, err := codec.NewDecoder().Decode(buf[:n])
+	if err != nil || len(packets) != 1 {
+		t.Fatalf("unexpected handshake response %v, %v", packets, err)
+	}
+	var resp struct {
+		Sys struct {
+			Heartbeat float64 `json:"heartbeat"`
+		} `json:"sys"`
+	}
+	if err := json.Unmarshal(packets[0].Data, &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.Sys.Heartbeat
+}
+
+func TestServers(t *testing.T) {
+	s1 := NewServer(WithServiceAddr(freeAddr(t)), WithHeartbeatInterval(5*time.Second))
+	s2 := NewServer(WithServiceAddr(freeAddr(t)), WithHeartbeatInterval(7*time.Second))
+
+	ctx := context.Background()
+	for _, s := range []*Server{s1, s2} {
+		if err := s.Start(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s1.Start(ctx); err != ErrServerStarted {
+		t.Fatalf("expect %v, got %v", ErrServerStarted, err)
+	}
+
+	if hb := handshakeHeartbeat(t, s1.Node().GateAddr); hb != 5 {
+		t.Fatalf("expect heartbeat 5, got %v", hb)
+	}
+	if hb := handshakeHeartbeat(t, s2.Node().GateAddr); hb != 7 {
+		t.Fatalf("expect heartbeat 7, got %v", hb)
+	}
+
+	// the servers are not the default node of the package level functions
+	if err := PushToUID("u1", "notice", nil); err != cluster.ErrNodeNotStarted {
+		t.Fatalf("expect %v, got %v", cluster.ErrNodeNotStarted, err)
+	}
+
+	// each server runs its handlers in its own scheduler
+	sched := s1.Node().Scheduler
+	if sched == nil || sched == s2.Node().Scheduler || sched == scheduler.Default() {
+		t.Fatal("expect the servers have their own schedulers")
+	}
+
+	for i, s := range []*Server{s1, s2} {
+		ctx, cancel := context.WithTimeout(ctx, time.Second)
+		if err := s.Stop(ctx); err != nil {
+			t.Fatal(err)
+		}
+		cancel()
+		// stopping a server does not affect the others
+		if i == 0 {
+			if hb := handshakeHeartbeat(t, s2.Node().GateAddr); hb != 7 {
+				t.Fatalf("expect heartbeat 7, got %v", hb)
+			}
+		}
+	}
+	if err := s1.Stop(ctx); err != ErrServerNotStarted {
+		t.Fatalf("expect %v, got %v", ErrServerNotStarted, err)
+	}
+	if sched.TryRun(func() {}) {
+		t.Fatal("expect the scheduler closed by Stop")
+	}
+	if !scheduler.TryRun(func() {}) {
+		t.Fatal("expect the library level scheduler not closed")
+	}
+}
+
+func TestServerMonitorRestart(t *testing.T) {
+	monitorAddr := freeAddr(t)
+	withMonitor := func(opt *cluster.Options) { opt.MonitorAddr = monitorAddr }
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		s := NewServer(WithServiceAddr(freeAddr(t)), withMonitor)
+		if err := s.Start(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		// the monitor address is in use by the running server
+		other := NewServer(WithServiceAddr(freeAddr(t)), withMonitor)
+		if err := other.Start(ctx); err == nil {
+			other.Stop(ctx)
+			t.Fatal("expect the monitor address in use")
+		}
+
+		stopCtx, cancel := context.WithTimeout(ctx, time.Second)
+		if err := s.Stop(stopCtx); err != nil {
+			t.Fatal(err)
+		}
+		cancel()
+		if conn, err := net.Dial("tcp", monitorAddr); err == nil {
+			conn.Close()
+			t.Fatal("the monitor should be shut down with the server")
+		}
+	}
+}
@@ -23,8 +23,6 @@ package service
 import (
 	"strconv"
 	"sync/atomic"
-
-	"github.com/nano-kit/go-nano/internal/env"
 )
 
 const (
@@ -33,8 +31,9 @@ const (
 	gateIDShift   = 32
 )
 
-// Connections is a global variable which is used by session.
-var Connections = newConnectionService()
+// Connections is the session ID generator used by session.New, whose gate ID
+// is 0. The nodes generate the session IDs with their own generators.
+var Connections = NewConnectionService(0)
 
 type SID int64
 
@@ -48,17 +47,21 @@ func (s SID) String() string {
 	return strconv.FormatInt(gate, 10) + "_" + str
 }
 
-type connectionService struct {
-	sid uint32
+// ConnectionService generates the session IDs of a gate, the IDs are unique
+// in the cluster if the gates have distinct gate IDs
+type ConnectionService struct {
+	sid    uint32
+	gateID uint16
 }
 
-func newConnectionService() *connectionService {
-	return &connectionService{sid: 0}
+// NewConnectionService returns the session ID generator of the gate
+func NewConnectionService(gateID uint16) *ConnectionService {
+	return &ConnectionService{gateID: gateID}
 }
 
 // SessionID returns the session id
-func (c *connectionService) SessionID() SID {
+func (c *ConnectionService) SessionID() SID {
 	s := SID(atomic.AddUint32(&c.sid, 1))
-	g := SID(env.GateID) << gateIDShift
+	g := SID(c.gateID) << gateIDShift
 	return g | s
 }
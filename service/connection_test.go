@@ -2,7 +2,6 @@ package service
 
 import (
 	"fmt"
-	"github.com/nano-kit/go-nano/internal/env"
 	"testing"
 )
 
@@ -30,7 +29,7 @@ func TestSID_String(t *testing.T) {
 }
 
 func TestConnectionService_SessionID(t *testing.T) {
-	s := newConnectionService()
+	s := NewConnectionService(0)
 	sid := s.SessionID()
 	if sid != 1 {
 		t.Errorf("got %q, want %q", sid, 1)
@@ -44,9 +43,8 @@ func TestConnectionService_SessionID(t *testing.T) {
 	if sid != 0 {
 		t.Errorf("got %q, want %q", sid, 0)
 	}
-	env.GateID = 1
+	s = NewConnectionService(1)
 	sid = s.SessionID()
-	env.GateID = 0
 	if sid != 0x100000001 {
 		t.Errorf("got %q, want %q", sid, "1_1")
 	}
@@ -0,0 +1,230 @@
+package session
+
+import (
+	"sync"
+	"time"
+)
+
+// CloseReason describes why a session was closed
+type CloseReason int32
+
+// Close reasons which are delivered to the OnClosing and OnClosed callbacks
+const (
+	CloseUnknown          CloseReason = iota // the reason is not recorded
+	CloseClientClosed                        // the client closed the connection
+	CloseReadError                           // failed to read or decode from the connection
+	CloseWriteError                          // failed to write to the connection
+	CloseHeartbeatTimeout                    // no heartbeat from the client
+	CloseKicked                              // closed by the server, e.g. Session.Close
+	CloseServerShutdown                      // the server is shutting down
+	CloseDecodeError                         // the client sent malformed packets
+	CloseProtocolError                       // the client violated the protocol, e.g. data before handshake
+	CloseAuthFailed                          // the token presented in handshake is invalid
+	CloseRateLimited                         // the client exceeded the rate limit repeatedly
+	CloseHandshakeTimeout                    // the client did not send handshake in time
+	CloseReadTimeout                         // the client sent nothing within the read idle timeout
+	CloseWriteTimeout                        // writing to the client did not complete in time
+)
+
+var closeReasonNames = map[CloseReason]string{
+	CloseUnknown:          "unknown",
+	CloseClientClosed:     "client closed",
+	CloseReadError:        "read error",
+	CloseWriteError:       "write error",
+	CloseHeartbeatTimeout: "heartbeat timeout",
+	CloseKicked:           "kicked",
+	CloseServerShutdown:   "server shutdown",
+	CloseDecodeError:      "decode error",
+	CloseProtocolError:    "protocol error",
+	CloseAuthFailed:       "auth failed",
+	CloseRateLimited:      "rate limited",
+	CloseHandshakeTimeout: "handshake timeout",
+	CloseReadTimeout:      "read timeout",
+	CloseWriteTimeout:     "write timeout",
+}
+
+func (r CloseReason) String() string {
+	if name, found := closeReasonNames[r]; found {
+		return name
+	}
+	return "unknown"
+}
+
+type (
+	// CloseHandler represents a callback that will be called when a session
+	// is closing or closed with the reason.
+	CloseHandler func(*Session, CloseReason)
+
+	idleHandler struct {
+		after   time.Duration
+		handler LifetimeHandler
+	}
+
+	// Lifecycle is the container of the session lifecycle callbacks of a node,
+	// the callbacks are called in the scheduler goroutine except OnBound, which
+	// is called in the goroutine calling Session.Bind. OnClosing and OnClosed
+	// are called in a new goroutine if the scheduler is busy or stopped. A nil
+	// Lifecycle has no callbacks.
+	Lifecycle struct {
+		mu          sync.RWMutex
+		onCreated   []LifetimeHandler
+		onHandshake []LifetimeHandler
+		onBound     []LifetimeHandler
+		onIdle      []idleHandler
+		onClosing   []CloseHandler
+		onClosed    []CloseHandler
+	}
+)
+
+// closeReasoner is implemented by the network entity which records the
+// reason of closing
+type closeReasoner interface {
+	CloseReason() CloseReason
+}
+
+// lifecycleProvider is implemented by the network entity which belongs to a
+// node with lifecycle callbacks
+type lifecycleProvider interface {
+	Lifecycle() *Lifecycle
+}
+
+// NewLifecycle returns an empty Lifecycle
+func NewLifecycle() *Lifecycle {
+	return &Lifecycle{}
+}
+
+// OnCreated sets the callback which will be called after a session is created
+func (lc *Lifecycle) OnCreated(h LifetimeHandler) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	lc.onCreated = append(lc.onCreated, h)
+}
+
+// OnHandshake sets the callback which will be called after the handshake of
+// a client session is completed
+func (lc *Lifecycle) OnHandshake(h LifetimeHandler) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	lc.onHandshake = append(lc.onHandshake, h)
+}
+
+// OnBound sets the callback which will be called after an UID is bound to a session
+func (lc *Lifecycle) OnBound(h LifetimeHandler) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	lc.onBound = append(lc.onBound, h)
+}
+
+// OnIdle sets the callback which will be called once the client session has
+// not sent any data for the duration, it will be called again after the
+// client becomes active and idle again
+func (lc *Lifecycle) OnIdle(after time.Duration, h LifetimeHandler) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	lc.onIdle = append(lc.onIdle, idleHandler{after: after, handler: h})
+}
+
+// OnClosing sets the callback which will be called before the session is
+// closed, messages pushed in the callback will still be delivered
+func (lc *Lifecycle) OnClosing(h CloseHandler) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	lc.onClosing = append(lc.onClosing, h)
+}
+
+// OnClosed sets the callback which will be called after the session is closed
+func (lc *Lifecycle) OnClosed(h CloseHandler) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	lc.onClosed = append(lc.onClosed, h)
+}
+
+// NotifyCreated calls the OnCreated callbacks
+func (lc *Lifecycle) NotifyCreated(s *Session) {
+	if lc != nil {
+		lc.notify(&lc.onCreated, s)
+	}
+}
+
+// NotifyHandshake calls the OnHandshake callbacks
+func (lc *Lifecycle) NotifyHandshake(s *Session) {
+	if lc != nil {
+		lc.notify(&lc.onHandshake, s)
+	}
+}
+
+// NotifyBound calls the OnBound callbacks
+func (lc *Lifecycle) NotifyBound(s *Session) {
+	if lc != nil {
+		lc.notify(&lc.onBound, s)
+	}
+}
+
+// NotifyIdle calls the OnIdle callbacks whose duration is between from and to,
+// the idle checker passes the idle time of last check and current check, so
+// that each callback is called once during an idle period
+func (lc *Lifecycle) NotifyIdle(s *Session, from, to time.Duration) {
+	if lc == nil {
+		return
+	}
+	lc.mu.RLock()
+	handlers := lc.onIdle
+	lc.mu.RUnlock()
+
+	for _, h := range handlers {
+		if h.after > from && h.after <= to {
+			h.handler(s)
+		}
+	}
+}
+
+// HasIdle decides whether there are OnIdle callbacks
+func (lc *Lifecycle) HasIdle() bool {
+	if lc == nil {
+		return false
+	}
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+
+	return len(lc.onIdle) > 0
+}
+
+// NotifyClosing calls the OnClosing callbacks
+func (lc *Lifecycle) NotifyClosing(s *Session, reason CloseReason) {
+	if lc != nil {
+		lc.notifyClose(&lc.onClosing, s, reason)
+	}
+}
+
+// NotifyClosed calls the OnClosed callbacks
+func (lc *Lifecycle) NotifyClosed(s *Session, reason CloseReason) {
+	if lc != nil {
+		lc.notifyClose(&lc.onClosed, s, reason)
+	}
+}
+
+func (lc *Lifecycle) notify(which *[]LifetimeHandler, s *Session) {
+	lc.mu.RLock()
+	handlers := *which
+	lc.mu.RUnlock()
+
+	for _, h := range handlers {
+		h(s)
+	}
+}
+
+func (lc *Lifecycle) notifyClose(which *[]CloseHandler, s *Session, reason CloseReason) {
+	lc.mu.RLock()
+	handlers := *which
+	lc.mu.RUnlock()
+
+	for _, h := range handlers {
+		h(s, reason)
+	}
+}
@@ -0,0 +1,26 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLifecycle(t *testing.T) {
+	var nilLifecycle *Lifecycle
+	nilLifecycle.NotifyCreated(nil)
+	nilLifecycle.NotifyClosed(nil, CloseKicked)
+
+	lc := NewLifecycle()
+	var events []string
+	lc.OnIdle(time.Minute, func(*Session) { events = append(events, "idle") })
+	lc.OnClosed(func(_ *Session, reason CloseReason) { events = append(events, reason.String()) })
+
+	s := New(nil)
+	lc.NotifyIdle(s, 0, time.Second)
+	lc.NotifyIdle(s, time.Second, time.Minute)
+	lc.NotifyIdle(s, time.Minute, time.Hour)
+	lc.NotifyClosed(s, CloseHeartbeatTimeout)
+	if len(events) != 2 || events[0] != "idle" || events[1] != "heartbeat timeout" {
+		t.Fatalf("unexpected events %v", events)
+	}
+}
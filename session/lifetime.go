@@ -12,7 +12,10 @@ type (
 	}
 )
 
-// Lifetime is the container of LifetimeHandlers
+// Lifetime is the container of LifetimeHandlers, which are called after the
+// sessions of the node started by nano.Listen are closed. Set the OnClosed
+// callback of the Lifecycle of the node for the servers created by
+// nano.NewServer.
 var Lifetime = &lifetime{}
 
 // OnClosed set the Callback which will be called
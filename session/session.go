@@ -27,6 +27,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/nano-kit/go-nano/logging"
 	"github.com/nano-kit/go-nano/service"
 )
 
@@ -41,11 +42,25 @@ type NetworkEntity interface {
 	RemoteAddr() net.Addr
 }
 
+// uidBinder is implemented by the network entity which validates and
+// records the UID binding, such as enforcing the single login policy
+type uidBinder interface {
+	BindUID(uid string) error
+}
+
+// uidUnbinder is implemented by the network entity which records the UID
+// binding and removes the record when the session is unbound
+type uidUnbinder interface {
+	UnbindUID(uid string)
+}
+
 var (
 	//ErrIllegalUID represents a invalid uid
 	ErrIllegalUID = errors.New("illegal uid")
 )
 
+var logger = logging.Get("session")
+
 // Session represents a client session which could storage temp data during low-level
 // keep connected, all data will be released when the low-level connection was broken.
 // Session instance related to the client will be passed to Handler method as the first
@@ -57,6 +72,8 @@ type Session struct {
 	lastTime     int64                  // last heartbeat time
 	entity       NetworkEntity          // low-level network entity
 	data         map[string]interface{} // session data store
+	onChange     []ChangeFunc           // data change callbacks
+	persist      *persistence           // where the data is persisted
 	router       *Router
 }
 
@@ -121,9 +138,18 @@ func (s *Session) ID() service.SID {
 
 // UID returns uid that bind to current session
 func (s *Session) UID() string {
+	s.RLock()
+	defer s.RUnlock()
+
 	return s.uid
 }
 
+// Logger returns the logger of package "session" with the fields sid and uid
+// of current session, the uid is the one bound at the time of calling
+func (s *Session) Logger() logging.Logger {
+	return logger.With("sid", s.ID(), "uid", s.UID())
+}
+
 // LastActivity returns last heartbeat time in readable format
 func (s *Session) LastActivity() string {
 	t := atomic.LoadInt64(&s.lastTime)
@@ -151,22 +177,68 @@ func (s *Session) LastMid() uint64 {
 	return s.entity.LastMid()
 }
 
-// Bind bind UID to current session
+// Bind bind UID to current session, the binding may be rejected by the
+// network entity according to the bind policy of the node. It may block on
+// the network to record the binding to a remote session registry, which is
+// bounded by a timeout. If a store is attached, the persisted values of the
+// UID are loaded, and the UID keeps bound even if the loading failed.
 func (s *Session) Bind(uid string) error {
 	if uid == "" {
 		return ErrIllegalUID
 	}
 
+	if b, ok := s.entity.(uidBinder); ok {
+		if err := b.BindUID(uid); err != nil {
+			return err
+		}
+	}
+
+	s.Lock()
 	s.uid = uid
+	p := s.persist
+	s.Unlock()
+
+	if lp, ok := s.entity.(lifecycleProvider); ok {
+		lp.Lifecycle().NotifyBound(s)
+	}
+
+	if p != nil {
+		return s.Load()
+	}
 	return nil
 }
 
+// Unbind removes the UID binding of current session, the session data is kept
+func (s *Session) Unbind() {
+	uid := s.UID()
+	if uid == "" {
+		return
+	}
+
+	if u, ok := s.entity.(uidUnbinder); ok {
+		u.UnbindUID(uid)
+	}
+
+	s.Lock()
+	s.uid = ""
+	s.Unlock()
+}
+
 // Close terminate current session, session related data will not be released,
 // all related data should be Clear explicitly in Session closed callback
 func (s *Session) Close() {
 	s.entity.Close()
 }
 
+// CloseReason returns why the session was closed, it is CloseUnknown before
+// the session is closed or if the network entity does not record the reason.
+func (s *Session) CloseReason() CloseReason {
+	if r, ok := s.entity.(closeReasoner); ok {
+		return r.CloseReason()
+	}
+	return CloseUnknown
+}
+
 // RemoteAddr returns the remote network address.
 func (s *Session) RemoteAddr() net.Addr {
 	return s.entity.RemoteAddr()
@@ -175,247 +247,136 @@ func (s *Session) RemoteAddr() net.Addr {
 // Remove delete data associated with the key from session storage
 func (s *Session) Remove(key string) {
 	s.Lock()
-	defer s.Unlock()
-
+	old, found := s.data[key]
 	delete(s.data, key)
+	listeners := s.onChange
+	s.Unlock()
+
+	if found {
+		s.notifyChange(key, old, nil, listeners)
+	}
 }
 
 // Set associates value with the key in session storage
 func (s *Session) Set(key string, value interface{}) {
 	s.Lock()
-	defer s.Unlock()
-
+	old := s.data[key]
 	s.data[key] = value
+	listeners := s.onChange
+	s.Unlock()
+
+	s.notifyChange(key, old, value, listeners)
 }
 
 // HasKey decides whether a key has associated value
 func (s *Session) HasKey(key string) bool {
-	s.RLock()
-	defer s.RUnlock()
-
-	_, has := s.data[key]
-	return has
+	return s.Has(key)
 }
 
 // Int returns the value associated with the key as a int.
 func (s *Session) Int(key string) int {
-	s.RLock()
-	defer s.RUnlock()
-
-	v, ok := s.data[key]
-	if !ok {
-		return 0
-	}
-
-	value, ok := v.(int)
-	if !ok {
-		return 0
+	if v, ok := s.Value(key).(int); ok {
+		return v
 	}
-	return value
+	return s.IntOr(key, 0)
 }
 
 // Int8 returns the value associated with the key as a int8.
 func (s *Session) Int8(key string) int8 {
-	s.RLock()
-	defer s.RUnlock()
-
-	v, ok := s.data[key]
-	if !ok {
-		return 0
+	if v, ok := s.Value(key).(int8); ok {
+		return v
 	}
-
-	value, ok := v.(int8)
-	if !ok {
-		return 0
-	}
-	return value
+	return s.Int8Or(key, 0)
 }
 
 // Int16 returns the value associated with the key as a int16.
 func (s *Session) Int16(key string) int16 {
-	s.RLock()
-	defer s.RUnlock()
-
-	v, ok := s.data[key]
-	if !ok {
-		return 0
-	}
-
-	value, ok := v.(int16)
-	if !ok {
-		return 0
+	if v, ok := s.Value(key).(int16); ok {
+		return v
 	}
-	return value
+	return s.Int16Or(key, 0)
 }
 
 // Int32 returns the value associated with the key as a int32.
 func (s *Session) Int32(key string) int32 {
-	s.RLock()
-	defer s.RUnlock()
-
-	v, ok := s.data[key]
-	if !ok {
-		return 0
+	if v, ok := s.Value(key).(int32); ok {
+		return v
 	}
-
-	value, ok := v.(int32)
-	if !ok {
-		return 0
-	}
-	return value
+	return s.Int32Or(key, 0)
 }
 
 // Int64 returns the value associated with the key as a int64.
 func (s *Session) Int64(key string) int64 {
-	s.RLock()
-	defer s.RUnlock()
-
-	v, ok := s.data[key]
-	if !ok {
-		return 0
-	}
-
-	value, ok := v.(int64)
-	if !ok {
-		return 0
+	if v, ok := s.Value(key).(int64); ok {
+		return v
 	}
-	return value
+	return s.Int64Or(key, 0)
 }
 
 // Uint returns the value associated with the key as a uint.
 func (s *Session) Uint(key string) uint {
-	s.RLock()
-	defer s.RUnlock()
-
-	v, ok := s.data[key]
-	if !ok {
-		return 0
+	if v, ok := s.Value(key).(uint); ok {
+		return v
 	}
-
-	value, ok := v.(uint)
-	if !ok {
-		return 0
-	}
-	return value
+	return s.UintOr(key, 0)
 }
 
 // Uint8 returns the value associated with the key as a uint8.
 func (s *Session) Uint8(key string) uint8 {
-	s.RLock()
-	defer s.RUnlock()
-
-	v, ok := s.data[key]
-	if !ok {
-		return 0
+	if v, ok := s.Value(key).(uint8); ok {
+		return v
 	}
-
-	value, ok := v.(uint8)
-	if !ok {
-		return 0
-	}
-	return value
+	return s.Uint8Or(key, 0)
 }
 
 // Uint16 returns the value associated with the key as a uint16.
 func (s *Session) Uint16(key string) uint16 {
-	s.RLock()
-	defer s.RUnlock()
-
-	v, ok := s.data[key]
-	if !ok {
-		return 0
-	}
-
-	value, ok := v.(uint16)
-	if !ok {
-		return 0
+	if v, ok := s.Value(key).(uint16); ok {
+		return v
 	}
-	return value
+	return s.Uint16Or(key, 0)
 }
 
 // Uint32 returns the value associated with the key as a uint32.
 func (s *Session) Uint32(key string) uint32 {
-	s.RLock()
-	defer s.RUnlock()
-
-	v, ok := s.data[key]
-	if !ok {
-		return 0
-	}
-
-	value, ok := v.(uint32)
-	if !ok {
-		return 0
+	if v, ok := s.Value(key).(uint32); ok {
+		return v
 	}
-	return value
+	return s.Uint32Or(key, 0)
 }
 
 // Uint64 returns the value associated with the key as a uint64.
 func (s *Session) Uint64(key string) uint64 {
-	s.RLock()
-	defer s.RUnlock()
-
-	v, ok := s.data[key]
-	if !ok {
-		return 0
-	}
-
-	value, ok := v.(uint64)
-	if !ok {
-		return 0
+	if v, ok := s.Value(key).(uint64); ok {
+		return v
 	}
-	return value
+	return s.Uint64Or(key, 0)
 }
 
 // Float32 returns the value associated with the key as a float32.
 func (s *Session) Float32(key string) float32 {
-	s.RLock()
-	defer s.RUnlock()
-
-	v, ok := s.data[key]
-	if !ok {
-		return 0
+	if v, ok := s.Value(key).(float32); ok {
+		return v
 	}
-
-	value, ok := v.(float32)
-	if !ok {
-		return 0
-	}
-	return value
+	return s.Float32Or(key, 0)
 }
 
 // Float64 returns the value associated with the key as a float64.
 func (s *Session) Float64(key string) float64 {
-	s.RLock()
-	defer s.RUnlock()
-
-	v, ok := s.data[key]
-	if !ok {
-		return 0
-	}
-
-	value, ok := v.(float64)
-	if !ok {
-		return 0
+	if v, ok := s.Value(key).(float64); ok {
+		return v
 	}
-	return value
+	return s.Float64Or(key, 0)
 }
 
 // String returns the value associated with the key as a string.
 func (s *Session) String(key string) string {
-	s.RLock()
-	defer s.RUnlock()
-
-	v, ok := s.data[key]
-	if !ok {
-		return ""
-	}
+	return s.StringOr(key, "")
+}
 
-	value, ok := v.(string)
-	if !ok {
-		return ""
-	}
-	return value
+// Bool returns the value associated with the key as a bool.
+func (s *Session) Bool(key string) bool {
+	return s.BoolOr(key, false)
 }
 
 // Value returns the value associated with the key as a interface{}.
@@ -426,20 +387,29 @@ func (s *Session) Value(key string) interface{} {
 	return s.data[key]
 }
 
-// State returns all session state
+// State returns a snapshot of all session state
 func (s *Session) State() map[string]interface{} {
 	s.RLock()
 	defer s.RUnlock()
 
-	return s.data
+	state := make(map[string]interface{}, len(s.data))
+	for k, v := range s.data {
+		state[k] = v
+	}
+	return state
 }
 
 // Restore session state after reconnect
 func (s *Session) Restore(data map[string]interface{}) {
+	state := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		state[k] = v
+	}
+
 	s.Lock()
 	defer s.Unlock()
 
-	s.data = data
+	s.data = state
 }
 
 // Clear releases all data related to current session
@@ -1,7 +1,11 @@
 package session
 
 import (
+	"log"
+	"os"
 	"testing"
+
+	"github.com/nano-kit/go-nano/logging"
 )
 
 func TestNewSession(t *testing.T) {
@@ -22,6 +26,19 @@ func TestSession_Bind(t *testing.T) {
 	}
 }
 
+func TestSession_Unbind(t *testing.T) {
+	s := New(nil)
+	s.Bind("100")
+	s.Set("level", 1)
+	s.Unbind()
+	if s.UID() != "" {
+		t.Fatalf("expect unbound, got %q", s.UID())
+	}
+	if s.Int("level") != 1 {
+		t.Fatal("session data should be kept")
+	}
+}
+
 func TestSession_HasKey(t *testing.T) {
 	s := New(nil)
 	key := "hello"
@@ -175,3 +192,20 @@ func TestSession_Restore(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestSession_Logger(t *testing.T) {
+	var fields []interface{}
+	logging.SetHandler(logging.HandlerFunc(func(_ logging.Level, pkg, _ string, kv []interface{}) {
+		if pkg == "session" {
+			fields = kv
+		}
+	}))
+	defer logging.SetHandler(logging.NewStdHandler(log.New(os.Stderr, "[nano] ", log.LstdFlags)))
+
+	s := New(nil)
+	s.Bind("u1")
+	s.Logger().Info("joined", "room", "r1")
+	if len(fields) != 6 || fields[1] != s.ID() || fields[3] != "u1" || fields[5] != "r1" {
+		t.Fatalf("unexpected fields %v", fields)
+	}
+}
@@ -0,0 +1,317 @@
+package session
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrStoreNotAttached represents the session has no store to persist data
+var ErrStoreNotAttached = errors.New("session: store not attached")
+
+// Store persists the serializable session values keyed by UID. Each value is
+// encoded as JSON, so a Redis implementation could store them as the fields
+// of a hash. Load returns an empty map if the UID has no persisted values.
+type Store interface {
+	Load(uid string) (map[string][]byte, error)
+	Save(uid string, data map[string][]byte) error
+	Delete(uid string) error
+}
+
+// MemoryStore is a Store keeps the values in memory, which is useful when
+// sessions reconnect to the same process
+type MemoryStore struct {
+	mu   sync.RWMutex
+	data map[string]map[string][]byte
+}
+
+// NewMemoryStore returns an in-memory Store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{data: map[string]map[string][]byte{}}
+}
+
+// Load implements the Store interface
+func (m *MemoryStore) Load(uid string) (map[string][]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return copyData(m.data[uid]), nil
+}
+
+// Save implements the Store interface
+func (m *MemoryStore) Save(uid string, data map[string][]byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.data[uid] = copyData(data)
+	return nil
+}
+
+// Delete implements the Store interface
+func (m *MemoryStore) Delete(uid string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.data, uid)
+	return nil
+}
+
+func copyData(data map[string][]byte) map[string][]byte {
+	result := make(map[string][]byte, len(data))
+	for k, v := range data {
+		result[k] = append([]byte(nil), v...)
+	}
+	return result
+}
+
+// FileStore is a Store keeps the values of each UID in a JSON file under the
+// directory, so that the values survive the process restart
+type FileStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// NewFileStore returns a Store which saves files in dir, the directory will be
+// created if it does not exist
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// path returns the file of the uid, the uid is hex encoded to be a safe file name
+func (f *FileStore) path(uid string) string {
+	return filepath.Join(f.dir, hex.EncodeToString([]byte(uid))+".json")
+}
+
+// Load implements the Store interface
+func (f *FileStore) Load(uid string) (map[string][]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	b, err := ioutil.ReadFile(f.path(uid))
+	if os.IsNotExist(err) {
+		return map[string][]byte{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, err
+	}
+	data := make(map[string][]byte, len(raw))
+	for k, v := range raw {
+		data[k] = v
+	}
+	return data, nil
+}
+
+// Save implements the Store interface, the file is replaced atomically
+func (f *FileStore) Save(uid string, data map[string][]byte) error {
+	raw := make(map[string]json.RawMessage, len(data))
+	for k, v := range data {
+		raw[k] = v
+	}
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	tmp, err := ioutil.TempFile(f.dir, ".session-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path(uid))
+}
+
+// Delete implements the Store interface
+func (f *FileStore) Delete(uid string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	err := os.Remove(f.path(uid))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// persistence records how the session values are persisted
+type persistence struct {
+	sync.Mutex                   // serialize the Load and Flush
+	store        Store           // where the values are persisted
+	keys         map[string]bool // persisted keys, nil means all keys
+	writeThrough bool            // save on every change
+	saving       int32           // a background save is pending
+	changed      map[string]bool // changed keys since the last flush, protected by the session lock
+}
+
+func (p *persistence) persisted(key string) bool {
+	return p.keys == nil || p.keys[key]
+}
+
+// saveLater flushes the session in a background goroutine, so the store I/O
+// never blocks the caller. The changes made before the pending save starts
+// are coalesced into it.
+func (p *persistence) saveLater(s *Session) {
+	if !atomic.CompareAndSwapInt32(&p.saving, 0, 1) {
+		return
+	}
+	go func() {
+		atomic.StoreInt32(&p.saving, 0)
+		if err := s.Flush(); err != nil {
+			s.Logger().Error("save session failed", "err", err)
+		}
+	}()
+}
+
+// AttachStore persists the values of the keys to store, all values will be
+// persisted if no keys specified. The values are loaded when an UID is bound
+// to the session. If writeThrough is true, the values are saved in background
+// whenever one of them is changed, otherwise they are saved by Flush.
+func (s *Session) AttachStore(store Store, writeThrough bool, keys ...string) {
+	p := &persistence{store: store, writeThrough: writeThrough, changed: map[string]bool{}}
+	if len(keys) > 0 {
+		p.keys = make(map[string]bool, len(keys))
+		for _, k := range keys {
+			p.keys[k] = true
+		}
+	}
+
+	s.Lock()
+	s.persist = p
+	s.Unlock()
+
+	s.OnChange(func(key string, _, _ interface{}) {
+		if !p.persisted(key) {
+			return
+		}
+		s.Lock()
+		p.changed[key] = true
+		s.Unlock()
+		if p.writeThrough {
+			p.saveLater(s)
+		}
+	})
+}
+
+// Load restores the persisted values of the bound UID. The persisted values
+// overwrite the values in the session, including the ones set before the UID
+// is bound; the values of the other keys set before are kept, and they are
+// saved by the next flush.
+func (s *Session) Load() error {
+	s.RLock()
+	p, uid := s.persist, s.uid
+	s.RUnlock()
+	if p == nil {
+		return ErrStoreNotAttached
+	}
+	if uid == "" {
+		return ErrIllegalUID
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	data, err := p.store.Load(uid)
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	for key, b := range data {
+		if !p.persisted(key) {
+			continue
+		}
+		if v, err := decodeValue(s.data[key], b); err == nil {
+			s.data[key] = v
+			delete(p.changed, key)
+		}
+	}
+	dirty := len(p.changed) > 0
+	s.Unlock()
+
+	if dirty && p.writeThrough {
+		p.saveLater(s)
+	}
+	return nil
+}
+
+// Flush saves the persisted values if they have been changed since the last
+// flush, nothing will be saved before an UID is bound
+func (s *Session) Flush() error {
+	s.RLock()
+	p := s.persist
+	s.RUnlock()
+	if p == nil {
+		return ErrStoreNotAttached
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	s.Lock()
+	uid := s.uid
+	if uid == "" || len(p.changed) == 0 {
+		s.Unlock()
+		return nil
+	}
+	data := make(map[string][]byte, len(s.data))
+	for key, v := range s.data {
+		if !p.persisted(key) {
+			continue
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			s.Unlock()
+			return err
+		}
+		data[key] = b
+	}
+	changed := p.changed
+	p.changed = map[string]bool{}
+	s.Unlock()
+
+	if err := p.store.Save(uid, data); err != nil {
+		s.Lock()
+		for key := range changed {
+			p.changed[key] = true
+		}
+		s.Unlock()
+		return err
+	}
+	return nil
+}
+
+// decodeValue decodes b to the type of the old value if there is one
+func decodeValue(old interface{}, b []byte) (interface{}, error) {
+	if old != nil {
+		ptr := reflect.New(reflect.TypeOf(old))
+		if err := json.Unmarshal(b, ptr.Interface()); err == nil {
+			return ptr.Elem().Interface(), nil
+		}
+	}
+	var v interface{}
+	err := json.Unmarshal(b, &v)
+	return v, err
+}
@@ -0,0 +1,134 @@
+package session
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+// waitSaved waits for the background save of the key
+func waitSaved(t *testing.T, store Store, uid, key string) map[string][]byte {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		data, err := store.Load(uid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, found := data[key]; found {
+			return data
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s is not saved", key)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	s := New(nil)
+	s.AttachStore(store, true, "level", "room")
+	s.Set("level", 10)
+	if data, _ := store.Load("u1"); len(data) != 0 {
+		t.Fatal("nothing should be saved before binding")
+	}
+
+	s.Bind("u1")
+	s.Set("room", "lobby")
+	s.Set("private", "not persisted")
+	if data := waitSaved(t, store, "u1", "room"); string(data["level"]) != "10" {
+		t.Fatalf("level set before binding should be saved, got %q", data["level"])
+	}
+
+	s2 := New(nil)
+	s2.AttachStore(store, true, "level", "room")
+	s2.Set("level", int64(0))
+	if err := s2.Bind("u1"); err != nil {
+		t.Fatal(err)
+	}
+	if s2.Value("level") != int64(10) {
+		t.Fatalf("level should keep int64 type, got %T", s2.Value("level"))
+	}
+	if s2.String("room") != "lobby" {
+		t.Fatalf("unexpected room %v", s2.Value("room"))
+	}
+	if s2.Has("private") {
+		t.Fatal("private should not be persisted")
+	}
+}
+
+func TestStoreSetBeforeBind(t *testing.T) {
+	store := NewMemoryStore()
+	store.Save("u1", map[string][]byte{"level": []byte("10")})
+
+	s := New(nil)
+	s.AttachStore(store, false)
+	s.Set("level", 1)
+	s.Set("room", "lobby")
+	if err := s.Bind("u1"); err != nil {
+		t.Fatal(err)
+	}
+	if s.Int("level") != 10 {
+		t.Fatalf("level should be overwritten by the persisted value, got %v", s.Value("level"))
+	}
+	if s.String("room") != "lobby" {
+		t.Fatalf("room should be kept, got %v", s.Value("room"))
+	}
+
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := store.Load("u1")
+	if string(data["level"]) != "10" || string(data["room"]) != `"lobby"` {
+		t.Fatalf("unexpected data %q", data)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nano-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(nil)
+	s.AttachStore(store, false)
+	s.Bind("user/1")
+	s.Set("score", 99)
+	if data, _ := store.Load("user/1"); len(data) != 0 {
+		t.Fatal("nothing should be saved before flush")
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := store.Load("user/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data["score"]) != "99" {
+		t.Fatalf("unexpected data %q", data["score"])
+	}
+	if err := store.Delete("user/1"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := store.Load("user/1"); len(data) != 0 {
+		t.Fatal("data should be deleted")
+	}
+}
+
+func TestSession_StateSnapshot(t *testing.T) {
+	s := New(nil)
+	s.Set("key", 1)
+	state := s.State()
+	state["key"] = 2
+	if s.Int("key") != 1 {
+		t.Fatal("State should return a copy")
+	}
+}
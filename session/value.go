@@ -0,0 +1,339 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+)
+
+var (
+	// ErrKeyNotFound represents the key has no associated value
+	ErrKeyNotFound = errors.New("session: key not found")
+	// ErrTypeMismatch represents the value can not be converted to the destination type
+	ErrTypeMismatch = errors.New("session: type mismatch")
+	// ErrValueOverflow represents the numeric value overflows the destination type
+	ErrValueOverflow = errors.New("session: value overflow")
+	// ErrInvalidDestination represents the destination of Get is not a non-nil pointer
+	ErrInvalidDestination = errors.New("session: destination must be a non-nil pointer")
+)
+
+// ChangeFunc is called after the value associated with key has been changed
+// by Set or Remove, new is nil when the key is removed
+type ChangeFunc func(key string, old, new interface{})
+
+// OnChange registers a callback which will be called after a value has been
+// changed, the callback is called outside of the session lock
+func (s *Session) OnChange(fn ChangeFunc) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.onChange = append(s.onChange, fn)
+}
+
+func (s *Session) notifyChange(key string, old, new interface{}, listeners []ChangeFunc) {
+	for _, fn := range listeners {
+		fn(key, old, new)
+	}
+}
+
+// Has decides whether a key has associated value
+func (s *Session) Has(key string) bool {
+	s.RLock()
+	defer s.RUnlock()
+
+	_, has := s.data[key]
+	return has
+}
+
+// Keys returns all keys in session storage in ascending order
+func (s *Session) Keys() []string {
+	s.RLock()
+	keys := make([]string, 0, len(s.data))
+	for k := range s.data {
+		keys = append(keys, k)
+	}
+	s.RUnlock()
+
+	sort.Strings(keys)
+	return keys
+}
+
+// Get stores the value associated with the key in the value pointed to by dst.
+// Numeric values are converted between the integer and float kinds as long as
+// the value fits the destination type.
+func (s *Session) Get(key string, dst interface{}) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return ErrInvalidDestination
+	}
+
+	v, err := s.convert(key, rv.Elem())
+	if err != nil && err != ErrKeyNotFound {
+		return fmt.Errorf("%w: key=%s, value=%T, destination=%s", err, key, v, rv.Elem().Type())
+	}
+	return err
+}
+
+// convert stores the value associated with the key in dst, the error is not
+// annotated to keep the accessors with defaults free of allocation
+func (s *Session) convert(key string, dst reflect.Value) (interface{}, error) {
+	s.RLock()
+	v, ok := s.data[key]
+	s.RUnlock()
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return v, assign(dst, v)
+}
+
+// assign stores v to dst with numeric conversion
+func assign(dst reflect.Value, v interface{}) error {
+	if v == nil {
+		switch dst.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		return ErrTypeMismatch
+	}
+
+	src := reflect.ValueOf(v)
+	if src.Type().AssignableTo(dst.Type()) {
+		dst.Set(src)
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		switch src.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			i = src.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			u := src.Uint()
+			if u > math.MaxInt64 {
+				return ErrValueOverflow
+			}
+			i = int64(u)
+		case reflect.Float32, reflect.Float64:
+			f := src.Float()
+			if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+				return ErrValueOverflow
+			}
+			i = int64(f)
+		default:
+			return ErrTypeMismatch
+		}
+		if dst.OverflowInt(i) {
+			return ErrValueOverflow
+		}
+		dst.SetInt(i)
+		return nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var u uint64
+		switch src.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			i := src.Int()
+			if i < 0 {
+				return ErrValueOverflow
+			}
+			u = uint64(i)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			u = src.Uint()
+		case reflect.Float32, reflect.Float64:
+			f := src.Float()
+			if f != math.Trunc(f) || f < 0 || f >= math.MaxUint64 {
+				return ErrValueOverflow
+			}
+			u = uint64(f)
+		default:
+			return ErrTypeMismatch
+		}
+		if dst.OverflowUint(u) {
+			return ErrValueOverflow
+		}
+		dst.SetUint(u)
+		return nil
+
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		switch src.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			f = float64(src.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			f = float64(src.Uint())
+		case reflect.Float32, reflect.Float64:
+			f = src.Float()
+		default:
+			return ErrTypeMismatch
+		}
+		if dst.OverflowFloat(f) {
+			return ErrValueOverflow
+		}
+		dst.SetFloat(f)
+		return nil
+
+	case reflect.String:
+		if src.Kind() == reflect.String {
+			dst.SetString(src.String())
+			return nil
+		}
+		return ErrTypeMismatch
+
+	case reflect.Bool:
+		if src.Kind() == reflect.Bool {
+			dst.SetBool(src.Bool())
+			return nil
+		}
+		return ErrTypeMismatch
+	}
+
+	if src.Type().ConvertibleTo(dst.Type()) && src.Kind() == dst.Kind() {
+		dst.Set(src.Convert(dst.Type()))
+		return nil
+	}
+	return ErrTypeMismatch
+}
+
+// IntOr returns the value associated with the key as a int, or def if the
+// key is absent or the value can not be converted.
+func (s *Session) IntOr(key string, def int) int {
+	var v int
+	if _, err := s.convert(key, reflect.ValueOf(&v).Elem()); err != nil {
+		return def
+	}
+	return v
+}
+
+// Int8Or returns the value associated with the key as a int8, or def if the
+// key is absent or the value can not be converted.
+func (s *Session) Int8Or(key string, def int8) int8 {
+	var v int8
+	if _, err := s.convert(key, reflect.ValueOf(&v).Elem()); err != nil {
+		return def
+	}
+	return v
+}
+
+// Int16Or returns the value associated with the key as a int16, or def if the
+// key is absent or the value can not be converted.
+func (s *Session) Int16Or(key string, def int16) int16 {
+	var v int16
+	if _, err := s.convert(key, reflect.ValueOf(&v).Elem()); err != nil {
+		return def
+	}
+	return v
+}
+
+// Int32Or returns the value associated with the key as a int32, or def if the
+// key is absent or the value can not be converted.
+func (s *Session) Int32Or(key string, def int32) int32 {
+	var v int32
+	if _, err := s.convert(key, reflect.ValueOf(&v).Elem()); err != nil {
+		return def
+	}
+	return v
+}
+
+// Int64Or returns the value associated with the key as a int64, or def if the
+// key is absent or the value can not be converted.
+func (s *Session) Int64Or(key string, def int64) int64 {
+	var v int64
+	if _, err := s.convert(key, reflect.ValueOf(&v).Elem()); err != nil {
+		return def
+	}
+	return v
+}
+
+// UintOr returns the value associated with the key as a uint, or def if the
+// key is absent or the value can not be converted.
+func (s *Session) UintOr(key string, def uint) uint {
+	var v uint
+	if _, err := s.convert(key, reflect.ValueOf(&v).Elem()); err != nil {
+		return def
+	}
+	return v
+}
+
+// Uint8Or returns the value associated with the key as a uint8, or def if the
+// key is absent or the value can not be converted.
+func (s *Session) Uint8Or(key string, def uint8) uint8 {
+	var v uint8
+	if _, err := s.convert(key, reflect.ValueOf(&v).Elem()); err != nil {
+		return def
+	}
+	return v
+}
+
+// Uint16Or returns the value associated with the key as a uint16, or def if the
+// key is absent or the value can not be converted.
+func (s *Session) Uint16Or(key string, def uint16) uint16 {
+	var v uint16
+	if _, err := s.convert(key, reflect.ValueOf(&v).Elem()); err != nil {
+		return def
+	}
+	return v
+}
+
+// Uint32Or returns the value associated with the key as a uint32, or def if the
+// key is absent or the value can not be converted.
+func (s *Session) Uint32Or(key string, def uint32) uint32 {
+	var v uint32
+	if _, err := s.convert(key, reflect.ValueOf(&v).Elem()); err != nil {
+		return def
+	}
+	return v
+}
+
+// Uint64Or returns the value associated with the key as a uint64, or def if the
+// key is absent or the value can not be converted.
+func (s *Session) Uint64Or(key string, def uint64) uint64 {
+	var v uint64
+	if _, err := s.convert(key, reflect.ValueOf(&v).Elem()); err != nil {
+		return def
+	}
+	return v
+}
+
+// Float32Or returns the value associated with the key as a float32, or def if
+// the key is absent or the value can not be converted.
+func (s *Session) Float32Or(key string, def float32) float32 {
+	var v float32
+	if _, err := s.convert(key, reflect.ValueOf(&v).Elem()); err != nil {
+		return def
+	}
+	return v
+}
+
+// Float64Or returns the value associated with the key as a float64, or def if
+// the key is absent or the value can not be converted.
+func (s *Session) Float64Or(key string, def float64) float64 {
+	var v float64
+	if _, err := s.convert(key, reflect.ValueOf(&v).Elem()); err != nil {
+		return def
+	}
+	return v
+}
+
+// StringOr returns the value associated with the key as a string, or def if
+// the key is absent or the value is not a string.
+func (s *Session) StringOr(key string, def string) string {
+	var v string
+	if _, err := s.convert(key, reflect.ValueOf(&v).Elem()); err != nil {
+		return def
+	}
+	return v
+}
+
+// BoolOr returns the value associated with the key as a bool, or def if the
+// key is absent or the value is not a bool.
+func (s *Session) BoolOr(key string, def bool) bool {
+	var v bool
+	if _, err := s.convert(key, reflect.ValueOf(&v).Elem()); err != nil {
+		return def
+	}
+	return v
+}
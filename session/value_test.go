@@ -0,0 +1,122 @@
+package session
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestSession_Get(t *testing.T) {
+	s := New(nil)
+	s.Set("int64", int64(42))
+	s.Set("big", int64(math.MaxInt64))
+	s.Set("negative", -1)
+	s.Set("float", 3.0)
+	s.Set("fraction", 3.5)
+	s.Set("name", "nano")
+
+	var i int
+	if err := s.Get("int64", &i); err != nil || i != 42 {
+		t.Fatalf("expect 42, got %d, %v", i, err)
+	}
+	if s.Int("int64") != 42 {
+		t.Fatalf("Int should convert int64, got %d", s.Int("int64"))
+	}
+	var i8 int8
+	if err := s.Get("big", &i8); !errors.Is(err, ErrValueOverflow) {
+		t.Fatalf("expect overflow, got %v", err)
+	}
+	var u uint
+	if err := s.Get("negative", &u); !errors.Is(err, ErrValueOverflow) {
+		t.Fatalf("expect overflow, got %v", err)
+	}
+	if err := s.Get("float", &i); err != nil || i != 3 {
+		t.Fatalf("expect 3, got %d, %v", i, err)
+	}
+	if err := s.Get("fraction", &i); !errors.Is(err, ErrValueOverflow) {
+		t.Fatalf("expect overflow, got %v", err)
+	}
+	if s.Float32("int64") != 42 {
+		t.Fatalf("Float32 should convert int64, got %v", s.Float32("int64"))
+	}
+	if err := s.Get("name", &i); !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("expect type mismatch, got %v", err)
+	}
+	if err := s.Get("absent", &i); err != ErrKeyNotFound {
+		t.Fatalf("expect key not found, got %v", err)
+	}
+	if err := s.Get("name", i); err != ErrInvalidDestination {
+		t.Fatalf("expect invalid destination, got %v", err)
+	}
+	var v interface{}
+	if err := s.Get("name", &v); err != nil || v != "nano" {
+		t.Fatalf("expect nano, got %v, %v", v, err)
+	}
+}
+
+func TestSession_Or(t *testing.T) {
+	s := New(nil)
+	s.Set("level", int32(7))
+	s.Set("name", "nano")
+
+	if s.IntOr("level", 1) != 7 {
+		t.Fail()
+	}
+	if s.IntOr("absent", 1) != 1 {
+		t.Fail()
+	}
+	if s.Uint8Or("name", 9) != 9 {
+		t.Fail()
+	}
+	if s.StringOr("level", "none") != "none" {
+		t.Fail()
+	}
+	if !s.BoolOr("absent", true) {
+		t.Fail()
+	}
+}
+
+func TestSession_AccessorAllocs(t *testing.T) {
+	s := New(nil)
+	s.Set("level", int8(7))
+	if n := testing.AllocsPerRun(100, func() { s.Int8("level") }); n != 0 {
+		t.Fatalf("expect no allocation, got %v", n)
+	}
+	if s.Int64("level") != 7 {
+		t.Fail()
+	}
+}
+
+func TestSession_Keys(t *testing.T) {
+	s := New(nil)
+	s.Set("b", 1)
+	s.Set("a", 2)
+	if !s.Has("a") || s.Has("c") {
+		t.Fail()
+	}
+	if keys := s.Keys(); !reflect.DeepEqual(keys, []string{"a", "b"}) {
+		t.Fatalf("unexpected keys %v", keys)
+	}
+}
+
+func TestSession_OnChange(t *testing.T) {
+	s := New(nil)
+	var changes [][3]interface{}
+	s.OnChange(func(key string, old, new interface{}) {
+		changes = append(changes, [3]interface{}{key, old, new})
+	})
+	s.Set("level", 1)
+	s.Set("level", 2)
+	s.Remove("level")
+	s.Remove("absent")
+
+	expect := [][3]interface{}{
+		{"level", nil, 1},
+		{"level", 1, 2},
+		{"level", 2, nil},
+	}
+	if !reflect.DeepEqual(changes, expect) {
+		t.Fatalf("unexpected changes %v", changes)
+	}
+}
@@ -0,0 +1,46 @@
+package trace
+
+import "sync"
+
+// MemoryExporter keeps the exported spans in memory, which is useful in tests
+type MemoryExporter struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+// NewMemoryExporter returns an empty MemoryExporter
+func NewMemoryExporter() *MemoryExporter {
+	return &MemoryExporter{}
+}
+
+// Export implements the Exporter interface
+func (m *MemoryExporter) Export(span *Span) {
+	m.mu.Lock()
+	m.spans = append(m.spans, span)
+	m.mu.Unlock()
+}
+
+// Spans returns the exported spans in the order of ending
+func (m *MemoryExporter) Spans() []*Span {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*Span(nil), m.spans...)
+}
+
+// Find returns the exported spans of the name
+func (m *MemoryExporter) Find(name string) []*Span {
+	var result []*Span
+	for _, s := range m.Spans() {
+		if s.Name == name {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// Reset removes the exported spans
+func (m *MemoryExporter) Reset() {
+	m.mu.Lock()
+	m.spans = nil
+	m.mu.Unlock()
+}
@@ -0,0 +1,222 @@
+// Package trace records the spans of the messages passing through the gate
+// and the backends. The trace context is propagated between nodes in the W3C
+// traceparent format, and the spans are exported through an Exporter.
+package trace
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HeaderName is the name of the gRPC metadata carrying the trace context
+const HeaderName = "traceparent"
+
+// ErrInvalidTraceparent indicates the traceparent is malformed
+var ErrInvalidTraceparent = errors.New("invalid traceparent")
+
+type (
+	// TraceID identifies a trace
+	TraceID [16]byte
+
+	// SpanID identifies a span in a trace
+	SpanID [8]byte
+
+	// SpanContext is the identity of a span propagated across the nodes
+	SpanContext struct {
+		TraceID TraceID
+		SpanID  SpanID
+		Sampled bool
+	}
+
+	// Span records a timed operation of a trace. A nil Span ignores all
+	// operations, which is returned by a nil Tracer.
+	Span struct {
+		Name       string
+		Context    SpanContext
+		Parent     SpanID // zero for the root span
+		Start      time.Time
+		End        time.Time
+		Attributes map[string]string
+		Error      string
+
+		mu     sync.Mutex
+		tracer *Tracer
+		ended  bool
+	}
+
+	// Exporter exports the ended spans, it is called in the goroutine ending
+	// the span and should not block
+	Exporter interface {
+		Export(span *Span)
+	}
+
+	// Tracer starts the spans and exports them when they end. A nil Tracer
+	// disables tracing.
+	Tracer struct {
+		exporter Exporter
+	}
+)
+
+// String returns the hex encoding of the trace ID
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// IsValid decides whether the trace ID is non-zero
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+// String returns the hex encoding of the span ID
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// IsValid decides whether the span ID is non-zero
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+// IsValid decides whether the span context can be propagated
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Traceparent encodes the span context in the W3C traceparent format, an
+// empty string is returned for the invalid span context
+func (sc SpanContext) Traceparent() string {
+	if !sc.IsValid() {
+		return ""
+	}
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// Parse decodes the span context in the W3C traceparent format
+func Parse(traceparent string) (SpanContext, error) {
+	var sc SpanContext
+	parts := strings.Split(traceparent, "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" ||
+		len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, ErrInvalidTraceparent
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return sc, ErrInvalidTraceparent
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, ErrInvalidTraceparent
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, ErrInvalidTraceparent
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return sc, ErrInvalidTraceparent
+	}
+	if !sc.IsValid() {
+		return sc, ErrInvalidTraceparent
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, nil
+}
+
+// New returns a Tracer exporting the spans to the exporter
+func New(exporter Exporter) *Tracer {
+	return &Tracer{exporter: exporter}
+}
+
+// Start starts a span, which is the child of parent if parent is valid, or
+// the root of a new trace otherwise. The span of the unsampled parent is not
+// exported but its context is still propagated.
+func (t *Tracer) Start(parent SpanContext, name string) *Span {
+	if t == nil {
+		return nil
+	}
+
+	s := &Span{Name: name, Start: time.Now(), tracer: t}
+	if parent.IsValid() {
+		s.Context.TraceID = parent.TraceID
+		s.Context.Sampled = parent.Sampled
+		s.Parent = parent.SpanID
+	} else {
+		rand.Read(s.Context.TraceID[:])
+		s.Context.Sampled = true
+	}
+	rand.Read(s.Context.SpanID[:])
+	return s
+}
+
+// StartFrom starts a span whose parent is encoded in traceparent, a new
+// trace is started if traceparent is empty or malformed
+func (t *Tracer) StartFrom(traceparent, name string) *Span {
+	parent, _ := Parse(traceparent)
+	return t.Start(parent, name)
+}
+
+// SpanContext returns the context of the span
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.Context
+}
+
+// Traceparent returns the context of the span in the W3C traceparent format
+func (s *Span) Traceparent() string {
+	return s.SpanContext().Traceparent()
+}
+
+// SetAttribute sets an attribute of the span
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Attributes == nil {
+		s.Attributes = map[string]string{}
+	}
+	s.Attributes[key] = value
+}
+
+// SetError records the error of the operation, nil is ignored
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.Error = err.Error()
+	s.mu.Unlock()
+}
+
+// Finish ends the span and exports it, the subsequent calls are ignored
+func (s *Span) Finish() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.End = time.Now()
+	s.mu.Unlock()
+
+	if s.Context.Sampled && s.tracer.exporter != nil {
+		s.tracer.exporter.Export(s)
+	}
+}
+
+// Duration returns the duration of the ended span
+func (s *Span) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
@@ -0,0 +1,120 @@
+package trace
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTraceparent(t *testing.T) {
+	const tp = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := Parse(tp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID.String() != "00f067aa0ba902b7" || !sc.Sampled {
+		t.Fatalf("unexpected span context %+v", sc)
+	}
+	if sc.Traceparent() != tp {
+		t.Fatalf("expect %s, got %s", tp, sc.Traceparent())
+	}
+
+	for _, bad := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473x-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		if _, err := Parse(bad); err != ErrInvalidTraceparent {
+			t.Fatalf("%q should be invalid, got %v", bad, err)
+		}
+	}
+	if (SpanContext{}).Traceparent() != "" {
+		t.Fatal("invalid span context should not be propagated")
+	}
+}
+
+func TestTracer(t *testing.T) {
+	exporter := NewMemoryExporter()
+	tracer := New(exporter)
+
+	root := tracer.Start(SpanContext{}, "gate")
+	child := tracer.StartFrom(root.Traceparent(), "handler")
+	child.SetAttribute("route", "Room.Join")
+	child.SetError(errors.New("room is full"))
+	child.Finish()
+	child.Finish()
+	root.Finish()
+
+	spans := exporter.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("expect 2 spans, got %d", len(spans))
+	}
+	if child.Context.TraceID != root.Context.TraceID || child.Parent != root.Context.SpanID {
+		t.Fatalf("the child should belong to the trace of root")
+	}
+	if child.Attributes["route"] != "Room.Join" || child.Error != "room is full" {
+		t.Fatalf("unexpected child span %+v", child)
+	}
+	if len(exporter.Find("gate")) != 1 {
+		t.Fatal("the root span should be found")
+	}
+
+	// the span of unsampled parent is not exported
+	exporter.Reset()
+	tracer.StartFrom("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", "handler").Finish()
+	if len(exporter.Spans()) != 0 {
+		t.Fatal("unsampled span should not be exported")
+	}
+
+	// nil tracer disables tracing
+	var disabled *Tracer
+	span := disabled.Start(SpanContext{}, "gate")
+	span.SetAttribute("route", "Room.Join")
+	span.Finish()
+	if span.Traceparent() != "" {
+		t.Fatal("nil tracer should not propagate trace context")
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	received := make(chan otlpRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var req otlpRequest
+		body, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Error(err)
+		}
+		received <- req
+	}))
+	defer server.Close()
+
+	exporter := NewOTLPExporter(server.URL, "gate")
+	tracer := New(exporter)
+	root := tracer.Start(SpanContext{}, "gate")
+	child := tracer.StartFrom(root.Traceparent(), "rpc")
+	child.SetError(errors.New("broken rpc"))
+	child.Finish()
+	root.Finish()
+	exporter.Close()
+
+	req := <-received
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 || req.ResourceSpans[0].Resource.Attributes[0].Value.StringValue != "gate" {
+		t.Fatalf("unexpected request %+v", req)
+	}
+	if spans[0].ParentSpanID != root.Context.SpanID.String() || spans[0].Status.Code != otlpStatusError {
+		t.Fatalf("unexpected span %+v", spans[0])
+	}
+	if spans[1].TraceID != root.Context.TraceID.String() || spans[1].ParentSpanID != "" {
+		t.Fatalf("unexpected span %+v", spans[1])
+	}
+}